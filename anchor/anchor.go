@@ -0,0 +1,163 @@
+package anchor
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"fmt"
+	"io"
+
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+	"github.com/tusharjoshi4531/block-chain.git/types"
+	"github.com/tusharjoshi4531/block-chain.git/util"
+)
+
+// MaxMetadataSize bounds the metadata stored with an anchor.
+const MaxMetadataSize = 256
+
+const anchorEncodingVersion uint8 = 1
+
+func init() {
+	core.RegisterTxType(core.TxTypeAnchor, core.TxTypeHandler{
+		Name: "Anchor",
+		Decode: func(payload []byte) (any, error) {
+			return decodeAnchorPayload(payload)
+		},
+		Validate: func(tx *core.Transaction, decoded any) error {
+			anchor := decoded.(*Anchor)
+			if !anchor.Submitter.Equal(tx.From) {
+				return fmt.Errorf("anchor is not signed by its submitter")
+			}
+			return nil
+		},
+	})
+}
+
+// Anchor records the hash of a document on chain; the block including it
+// proves the document existed by then. It names its submitter so that
+// anchors of the same document by different wallets have distinct
+// transaction hashes.
+type Anchor struct {
+	Hash      types.Hash
+	Metadata  string
+	Submitter *ecdsa.PublicKey
+}
+
+func NewAnchor(hash types.Hash, metadata string, submitter *ecdsa.PublicKey) *Anchor {
+	return &Anchor{
+		Hash:      hash,
+		Metadata:  metadata,
+		Submitter: submitter,
+	}
+}
+
+func (anchor *Anchor) Bytes() ([]byte, error) {
+	if len(anchor.Metadata) > MaxMetadataSize {
+		return nil, fmt.Errorf("anchor metadata of (%d) bytes exceeds (%d) bytes", len(anchor.Metadata), MaxMetadataSize)
+	}
+
+	buf := &bytes.Buffer{}
+	cw := util.NewCanonicalWriter(buf)
+	cw.WriteUint8(anchorEncodingVersion)
+	cw.WriteFixed(anchor.Hash[:])
+	cw.WriteString(anchor.Metadata)
+	cw.WriteBytes(crypto.PublicKeyBytes(anchor.Submitter))
+	if err := cw.Err(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// NewAnchorTransaction builds an anchor of hash signed by the submitter.
+func NewAnchorTransaction(hash types.Hash, metadata string, privKey *ecdsa.PrivateKey) (*core.Transaction, error) {
+	data, err := NewAnchor(hash, metadata, &privKey.PublicKey).Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := core.NewTypedTransaction(core.TxTypeAnchor, data)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Sign(privKey); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+func DecodeAnchor(tx *core.Transaction) (*Anchor, error) {
+	txType, decoded, err := core.DecodeTransaction(tx)
+	if err != nil {
+		return nil, err
+	}
+	if txType != core.TxTypeAnchor {
+		return nil, fmt.Errorf("transaction is not an anchor")
+	}
+	return decoded.(*Anchor), nil
+}
+
+func decodeAnchorPayload(payload []byte) (*Anchor, error) {
+	anchor := &Anchor{}
+	cr := util.NewCanonicalReader(bytes.NewReader(payload))
+	cr.ReadVersion(anchorEncodingVersion)
+	cr.ReadFixed(anchor.Hash[:])
+	anchor.Metadata = cr.ReadString()
+	submitterBytes := cr.ReadBytes()
+	cr.ReadEnd()
+	if err := cr.Err(); err != nil {
+		return nil, err
+	}
+	if len(anchor.Metadata) > MaxMetadataSize {
+		return nil, fmt.Errorf("anchor metadata of (%d) bytes exceeds (%d) bytes", len(anchor.Metadata), MaxMetadataSize)
+	}
+
+	submitter, err := crypto.PublicKeyFromBytes(submitterBytes)
+	if err != nil {
+		return nil, err
+	}
+	anchor.Submitter = submitter
+	return anchor, nil
+}
+
+func IsAnchorTransaction(tx *core.Transaction) bool {
+	envelope, err := core.DecodeEnvelope(tx.Data)
+	return err == nil && envelope.Type == core.TxTypeAnchor
+}
+
+// HashDocument is the hash anchored for the document read from r.
+func HashDocument(r io.Reader) (types.Hash, error) {
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, r); err != nil {
+		return types.Hash{}, err
+	}
+	return types.Hash(hasher.Sum(nil)), nil
+}
+
+// FindAnchorInChain returns the oldest block anchoring documentHash in the
+// chain ending at blockHash, the way HasTransactionInChain looks for a
+// transaction.
+func FindAnchorInChain(bc core.BlockChain, documentHash types.Hash, blockHash types.Hash) (*core.Block, error) {
+	block, err := bc.GetBlockWithHash(blockHash)
+	if err != nil {
+		return nil, err
+	}
+
+	var oldest *core.Block
+	for block.Header.Height > 0 {
+		for _, tx := range block.Transactions {
+			if anchor, err := DecodeAnchor(tx); err == nil && anchor.Hash == documentHash {
+				oldest = block
+				break
+			}
+		}
+		if block, err = bc.GetPrevBlock(block); err != nil {
+			return nil, err
+		}
+	}
+
+	if oldest == nil {
+		return nil, fmt.Errorf("document with hash (%s) is not anchored in the block chain", documentHash.String())
+	}
+	return oldest, nil
+}
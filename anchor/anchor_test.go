@@ -0,0 +1,99 @@
+package anchor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+	"github.com/tusharjoshi4531/block-chain.git/types"
+)
+
+func TestAnchorEncoding(t *testing.T) {
+	privKey := crypto.GeneratePrivateKey()
+	hash := types.Hash{1, 2, 3}
+
+	tx, err := NewAnchorTransaction(hash, "contract.pdf", privKey)
+	assert.Nil(t, err)
+	assert.True(t, IsAnchorTransaction(tx))
+	assert.Nil(t, core.ValidateTransaction(tx))
+
+	anchor, err := DecodeAnchor(tx)
+	assert.Nil(t, err)
+	assert.Equal(t, hash, anchor.Hash)
+	assert.Equal(t, "contract.pdf", anchor.Metadata)
+	assert.Equal(t, &privKey.PublicKey, anchor.Submitter)
+
+	// The same document anchored by another wallet is another transaction
+	other, err := NewAnchorTransaction(hash, "contract.pdf", crypto.GeneratePrivateKey())
+	assert.Nil(t, err)
+	assert.NotEqual(t, tx.Hash(), other.Hash())
+
+	// Anchors must be signed by their submitter
+	assert.Nil(t, other.Sign(privKey))
+	assert.NotNil(t, core.ValidateTransaction(other))
+
+	_, err = NewAnchorTransaction(hash, strings.Repeat("a", MaxMetadataSize+1), privKey)
+	assert.NotNil(t, err)
+	_, err = DecodeAnchor(core.NewTransaction([]byte("FOO")))
+	assert.NotNil(t, err)
+
+	envelope, err := core.DecodeEnvelope(tx.Data)
+	assert.Nil(t, err)
+	padded, err := core.NewTypedTransaction(core.TxTypeAnchor, append(envelope.Payload, 0))
+	assert.Nil(t, err)
+	_, err = DecodeAnchor(padded)
+	assert.NotNil(t, err)
+}
+
+func TestHashDocument(t *testing.T) {
+	document := []byte("document")
+	hash, err := HashDocument(bytes.NewReader(document))
+	assert.Nil(t, err)
+	assert.Equal(t, types.Hash(sha256.Sum256(document)), hash)
+}
+
+func TestFindAnchorInChain(t *testing.T) {
+	privKey := crypto.GeneratePrivateKey()
+	bc := core.NewDefaultBlockChain()
+	document := types.Hash{7}
+
+	addBlock := func(txs ...*core.Transaction) types.Hash {
+		prevHash, err := bc.GetHeighestBlock().Hash()
+		assert.Nil(t, err)
+		block := core.NewBlockWithHeaderInfo(bc.Height()+1, prevHash)
+		for _, tx := range txs {
+			block.AddTransaction(tx)
+		}
+		assert.Nil(t, bc.AddBlock(block))
+		blockHash, err := block.Hash()
+		assert.Nil(t, err)
+		return blockHash
+	}
+
+	first, err := NewAnchorTransaction(document, "v1", privKey)
+	assert.Nil(t, err)
+	second, err := NewAnchorTransaction(document, "v2", privKey)
+	assert.Nil(t, err)
+
+	emptyHash := addBlock()
+	anchoredHash := addBlock(first)
+	tipHash := addBlock(second)
+
+	// The oldest anchor proves when the document existed
+	block, err := FindAnchorInChain(bc, document, tipHash)
+	assert.Nil(t, err)
+	blockHash, err := block.Hash()
+	assert.Nil(t, err)
+	assert.Equal(t, anchoredHash, blockHash)
+
+	_, err = FindAnchorInChain(bc, document, emptyHash)
+	assert.NotNil(t, err)
+	_, err = FindAnchorInChain(bc, types.Hash{8}, tipHash)
+	assert.NotNil(t, err)
+	_, err = FindAnchorInChain(bc, document, types.Hash{9})
+	assert.NotNil(t, err)
+}
@@ -2,13 +2,13 @@ package bcnetwork
 
 import (
 	"bytes"
-	"encoding/gob"
 	"fmt"
 	"sort"
 	"time"
 
 	"github.com/tusharjoshi4531/block-chain.git/core"
 	"github.com/tusharjoshi4531/block-chain.git/network"
+	"github.com/tusharjoshi4531/block-chain.git/types"
 	"github.com/tusharjoshi4531/block-chain.git/util"
 )
 
@@ -18,14 +18,12 @@ type BlockChainTransportSender interface {
 	SendHashChain(to string) error
 	SendBlocks(to string, blocks []*core.Block) error
 	SendBlocksWithHashChain(to string, blocks []*core.Block) error
-	SendWalletId(to string, walletId string) error
 	BroadcastTransaction(*core.Transaction) error
 	BroadcastHashChain() error
-	BroadcastWalletId(walletId string) error
 }
 
 type BlockChainTransportProcessor interface {
-	AddWallet(walletId string) error
+	SetBlockVerifier(verify func(*core.Block) error)
 	ProcessMessage(*BCPayload, string) error
 }
 
@@ -38,14 +36,39 @@ type DefaultBlockChainTransport struct {
 	network.Transport
 	blockChain      core.BlockChain
 	transactionPool core.TransactionPool
+	blockVerifier   func(*core.Block) error
+	genesisHash     types.Hash
 }
 
 func NewDefaultBlockChainTransport(transport network.Transport, blockChain core.BlockChain, transactionPool core.TransactionPool) *DefaultBlockChainTransport {
+	genesisHash, err := blockChain.GetGenesis().Hash()
+	if err != nil {
+		panic(err)
+	}
+
 	return &DefaultBlockChainTransport{
 		Transport:       transport,
 		blockChain:      blockChain,
 		transactionPool: transactionPool,
+		genesisHash:     genesisHash,
+	}
+}
+
+// newMessage stamps the payload with the local genesis hash so peers on a
+// different chain drop it.
+func (tr *DefaultBlockChainTransport) newMessage(payload *BCPayload) (*network.Message, error) {
+	payload.Genesis = tr.genesisHash
+	payloadBytes, err := payload.Bytes()
+	if err != nil {
+		return nil, err
 	}
+	return network.NewMessage(tr.Address(), payloadBytes), nil
+}
+
+// SetBlockVerifier installs a check run on every block received from peers
+// before it is added to the chain.
+func (tr *DefaultBlockChainTransport) SetBlockVerifier(verify func(*core.Block) error) {
+	tr.blockVerifier = verify
 }
 
 func (tr *DefaultBlockChainTransport) SendTransaction(to string, transaction *core.Transaction) error {
@@ -53,12 +76,10 @@ func (tr *DefaultBlockChainTransport) SendTransaction(to string, transaction *co
 	if err != nil {
 		return err
 	}
-	payloadBytes, err := payload.Bytes()
+	msg, err := tr.newMessage(payload)
 	if err != nil {
 		return err
 	}
-
-	msg := network.NewMessage(tr.Address(), payloadBytes)
 	return tr.SendMessageTo(to, msg)
 }
 
@@ -71,11 +92,10 @@ func (tr *DefaultBlockChainTransport) SendHashChain(to string) error {
 	if err != nil {
 		return err
 	}
-	payloadBytes, err := payload.Bytes()
+	msg, err := tr.newMessage(payload)
 	if err != nil {
 		return err
 	}
-	msg := network.NewMessage(tr.Address(), payloadBytes)
 	return tr.SendMessageTo(to, msg)
 }
 
@@ -84,11 +104,10 @@ func (tr *DefaultBlockChainTransport) SendBlocks(to string, blocks []*core.Block
 	if err != nil {
 		return err
 	}
-	payloadBytes, err := payload.Bytes()
+	msg, err := tr.newMessage(payload)
 	if err != nil {
 		return err
 	}
-	msg := network.NewMessage(tr.Address(), payloadBytes)
 	return tr.SendMessageTo(to, msg)
 }
 
@@ -97,24 +116,10 @@ func (tr *DefaultBlockChainTransport) SendBlocksWithHashChain(to string, blocks
 	if err != nil {
 		return err
 	}
-	payloadBytes, err := payload.Bytes()
+	msg, err := tr.newMessage(payload)
 	if err != nil {
 		return err
 	}
-	msg := network.NewMessage(tr.Address(), payloadBytes)
-	return tr.SendMessageTo(to, msg)
-}
-
-func (tr *DefaultBlockChainTransport) SendWalletId(to, walletId string) error {
-	payload, err := NewBCWalletId(walletId)
-	if err != nil {
-		return err
-	}
-	payloadBytes, err := payload.Bytes()
-	if err != nil {
-		return err
-	}
-	msg := network.NewMessage(tr.Address(), payloadBytes)
 	return tr.SendMessageTo(to, msg)
 }
 
@@ -123,12 +128,10 @@ func (tr *DefaultBlockChainTransport) BroadcastTransaction(transaction *core.Tra
 	if err != nil {
 		return err
 	}
-	payloadBytes, err := payload.Bytes()
+	msg, err := tr.newMessage(payload)
 	if err != nil {
 		return err
 	}
-
-	msg := network.NewMessage(tr.Address(), payloadBytes)
 	return tr.BroadCastMessage(msg)
 }
 
@@ -137,35 +140,23 @@ func (tr *DefaultBlockChainTransport) BroadcastHashChain() error {
 	if err != nil {
 		return err
 	}
-	payloadBytes, err := payload.Bytes()
+	msg, err := tr.newMessage(payload)
 	if err != nil {
 		return err
 	}
-	msg := network.NewMessage(tr.Address(), payloadBytes)
 	return tr.BroadCastMessage(msg)
 }
 
-func (tr *DefaultBlockChainTransport) BroadcastWalletId(walletId string) error {
-	payload, err := NewBCWalletId(walletId)
-	if err != nil {
-		return err
-	}
-	payloadBytes, err := payload.Bytes()
-	if err != nil {
-		return err
-	}
-	msg := network.NewMessage(tr.Address(), payloadBytes)
-	return tr.BroadCastMessage(msg)
-}
-
-func (tr *DefaultBlockChainTransport) AddWallet(walletId string) error {
-	if err := tr.blockChain.AddWallet(walletId); err != nil {
-		return err
+func (tr *DefaultBlockChainTransport) ProcessMessage(payload *BCPayload, from string) error {
+	if payload.Genesis != tr.genesisHash {
+		return fmt.Errorf(
+			"refusing message from (%s); genesis (%s) does not match local genesis (%s)",
+			from,
+			payload.Genesis.String(),
+			tr.genesisHash.String(),
+		)
 	}
-	return tr.BroadcastWalletId(walletId)
-}
 
-func (tr *DefaultBlockChainTransport) ProcessMessage(payload *BCPayload, from string) error {
 	switch payload.MsgType {
 	case MessageTransaction:
 		return tr.handleTransactionMessage(payload.Payload)
@@ -175,8 +166,6 @@ func (tr *DefaultBlockChainTransport) ProcessMessage(payload *BCPayload, from st
 		return tr.handleHashChainMessage(payload.Payload, from)
 	case MessageBlocksWithHashChain:
 		return tr.handleBlocksWithHashChainMessage(payload.Payload, from)
-	case MessageWalletId:
-		return tr.handleWalletId(payload.Payload)
 	default:
 		return fmt.Errorf("incorrect message type (%d)", payload.MsgType)
 	}
@@ -225,15 +214,6 @@ func (tr *DefaultBlockChainTransport) handleBlocksWithHashChainMessage(payload [
 	return tr.SendBlocks(from, extraBlocks)
 }
 
-func (tr *DefaultBlockChainTransport) handleWalletId(payload []byte) error {
-	walletId, err := decodeWalletIdFromBytes(payload)
-	if err != nil {
-		return err
-	}
-
-	return tr.blockChain.AddWallet(walletId)
-}
-
 func (tr *DefaultBlockChainTransport) decodeTransactionFromBytes(payload []byte) (*core.Transaction, error) {
 	transaction := core.NewTransaction([]byte{})
 	err := transaction.Decode(bytes.NewBuffer(payload))
@@ -267,15 +247,6 @@ func (tr *DefaultBlockChainTransport) decodeBlocksAndHashchainFromBytes(payload
 	return blocks, hashChain, err
 }
 
-func decodeWalletIdFromBytes(payload []byte) (string, error) {
-	buf := bytes.NewBuffer(payload)
-	walletId := ""
-	if err := gob.NewDecoder(buf).Decode(&walletId); err != nil {
-		return "", err
-	}
-	return walletId, nil
-}
-
 func (tr *DefaultBlockChainTransport) addTransaction(transaction *core.Transaction) error {
 	transaction.SetFirstSeen(time.Now().UnixNano())
 	err := tr.transactionPool.AddTransaction(transaction)
@@ -288,6 +259,11 @@ func (tr *DefaultBlockChainTransport) addBlocks(blocks []*core.Block) error {
 	})
 
 	for _, block := range blocks {
+		if tr.blockVerifier != nil {
+			if err := tr.blockVerifier(block); err != nil {
+				return err
+			}
+		}
 		if err := tr.blockChain.AddBlock(block); err != nil {
 			return err
 		}
@@ -3,7 +3,6 @@ package bcnetwork
 import (
 	"bytes"
 	"crypto/ecdsa"
-	"encoding/gob"
 	"fmt"
 	"sort"
 	"strconv"
@@ -479,9 +478,9 @@ func TestBlockchainSyncManual(t *testing.T) {
 		assert.Nil(t, recPayload.Decode(bytes.NewBuffer(recMsg.Payload)))
 		assert.Equal(t, recPayload.MsgType, MessageBlocks)
 
-		len := 0
-		buf := bytes.NewBuffer(recPayload.Payload)
-		assert.Nil(t, gob.NewDecoder(buf).Decode(&len))
+		cr := util.NewCanonicalReader(bytes.NewBuffer(recPayload.Payload))
+		assert.Equal(t, uint32(numDivergeBlocks), cr.ReadUint32())
+		assert.Nil(t, cr.Err())
 
 		assert.Nil(t, tr.ProcessMessage(recPayload, otr.Address()))
 	}
@@ -522,6 +521,62 @@ func TestEncodeBlocksWithHashChain(t *testing.T) {
 
 }
 
+func TestBlockVerifier(t *testing.T) {
+	privKey := crypto.GeneratePrivateKey()
+	bc := core.NewDefaultBlockChain()
+	tr := NewLocalBlockChainTransport("A", bc, core.NewDefaultTransactionPool())
+	tr.SetBlockVerifier(func(block *core.Block) error { return block.Verify() })
+
+	prevHash, err := bc.GetGenesis().Hash()
+	assert.Nil(t, err)
+
+	unsigned := core.NewBlockWithHeaderInfo(1, prevHash)
+	payload, err := NewBCBlocks([]*core.Block{unsigned})
+	assert.Nil(t, err)
+	payload.Genesis = prevHash
+	assert.NotNil(t, tr.ProcessMessage(payload, "B"))
+	assert.Equal(t, uint32(0), bc.Height())
+
+	signed := core.NewBlockWithHeaderInfo(1, prevHash)
+	assert.Nil(t, signed.Sign(privKey))
+	payload, err = NewBCBlocks([]*core.Block{signed})
+	assert.Nil(t, err)
+	payload.Genesis = prevHash
+	assert.Nil(t, tr.ProcessMessage(payload, "B"))
+	assert.Equal(t, uint32(1), bc.Height())
+}
+
+func TestGenesisMismatch(t *testing.T) {
+	privKey := crypto.GeneratePrivateKey()
+	bcA := core.NewDefaultBlockChain()
+	bcB := core.NewDefaultBlockChainWithGenesis(core.NewGenesisBlock(0, core.NewSeal(1)))
+
+	ta := NewLocalBlockChainTransport("A", bcA, core.NewDefaultTransactionPool())
+	tb := NewLocalBlockChainTransport("B", bcB, core.NewDefaultTransactionPool())
+	tc := NewLocalBlockChainTransport("C", core.NewDefaultBlockChain(), core.NewDefaultTransactionPool())
+	ta.Connect(tb)
+	ta.Connect(tc)
+
+	tx := core.NewTransaction([]byte("hello"))
+	assert.Nil(t, tx.Sign(privKey))
+	assert.Nil(t, ta.BroadcastTransaction(tx))
+
+	receive := func(tr *LocalBlockChainTransport) error {
+		recMsg := <-tr.ReadChan()
+		recPayload := &BCPayload{}
+		assert.Nil(t, recPayload.Decode(bytes.NewBuffer(recMsg.Payload)))
+		return tr.ProcessMessage(recPayload, recMsg.From)
+	}
+
+	// Same genesis
+	assert.Nil(t, receive(tc))
+	assert.True(t, tc.transactionPool.HasTransaction(tx.Hash()))
+
+	// Different genesis
+	assert.NotNil(t, receive(tb))
+	assert.Equal(t, 0, tb.transactionPool.Len())
+}
+
 func TestBlockChainSyncProt(t *testing.T) {
 	numTx := 100
 	blockSz := 5
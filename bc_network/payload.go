@@ -6,6 +6,7 @@ import (
 	"io"
 
 	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/types"
 	"github.com/tusharjoshi4531/block-chain.git/util"
 )
 
@@ -14,7 +15,6 @@ const (
 	MessageHashChain
 	MessageBlocks
 	MessageBlocksWithHashChain
-	MessageWalletId
 	// MessageTXSync
 )
 
@@ -28,15 +28,16 @@ func MsgTypeToString(msgType int) string {
 		return "Blocks"
 	case MessageBlocksWithHashChain:
 		return "HashChainWithBlocks"
-	case MessageWalletId:
-		return "WalletId"
 	default:
 		return "Invalid"
 	}
 }
 
+// BCPayload is a message between peers. Genesis is the hash of the
+// sender's genesis block; peers only accept payloads from their own chain.
 type BCPayload struct {
 	MsgType int
+	Genesis types.Hash
 	Payload []byte
 }
 
@@ -88,18 +89,6 @@ func NewBCBlocksWithHashChain(blocks []*core.Block, hashChain *core.HashChain) (
 	}, nil
 }
 
-func NewBCWalletId(walletId string) (*BCPayload, error) {
-	buf := &bytes.Buffer{}
-	if err := gob.NewEncoder(buf).Encode(walletId); err != nil {
-		return nil, err
-	}
-
-	return &BCPayload{
-		MsgType: MessageWalletId,
-		Payload: buf.Bytes(),
-	}, nil
-}
-
 func (payload *BCPayload) Encode(w io.Writer) error {
 	return gob.NewEncoder(w).Encode(payload)
 }
@@ -0,0 +1,226 @@
+package bft
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+	"github.com/tusharjoshi4531/block-chain.git/network"
+	"github.com/tusharjoshi4531/block-chain.git/prot"
+	"github.com/tusharjoshi4531/block-chain.git/types"
+)
+
+type testNetwork struct {
+	keys       []*ecdsa.PrivateKey
+	set        *ValidatorSet
+	chains     []*core.DefaultBlockChain
+	transports []*network.LocalTransport
+	nodes      []*Node
+	finalized  []*core.Subscription
+}
+
+func newTestNetwork(numValidators int, online int) *testNetwork {
+	net := &testNetwork{}
+	publicKeys := make([]*ecdsa.PublicKey, 0, numValidators)
+	for i := 0; i < numValidators; i++ {
+		privKey := crypto.GeneratePrivateKey()
+		net.keys = append(net.keys, privKey)
+		publicKeys = append(publicKeys, &privKey.PublicKey)
+	}
+	net.set = NewValidatorSet(publicKeys...)
+
+	config := Config{
+		TimeoutPropose:   300 * time.Millisecond,
+		TimeoutPrevote:   200 * time.Millisecond,
+		TimeoutPrecommit: 200 * time.Millisecond,
+	}
+
+	for i := 0; i < online; i++ {
+		bc := core.NewDefaultBlockChain()
+		miner := prot.NewSimpleMiner(bc, core.NewDefaultTransactionPool(), net.keys[i])
+		propose := func() (*core.Block, error) {
+			return miner.MineBlock(10, "")
+		}
+
+		transport := network.NewLocalTransport(fmt.Sprintf("V%d", i))
+		net.chains = append(net.chains, bc)
+		net.transports = append(net.transports, transport)
+		net.nodes = append(net.nodes, NewNode(transport, bc, net.set, net.keys[i], propose, config))
+		net.finalized = append(net.finalized, bc.Subscribe(64, core.EventBlockFinalized))
+	}
+
+	for i := range net.transports {
+		for j := range net.transports {
+			if i != j {
+				net.transports[i].Connect(net.transports[j])
+			}
+		}
+	}
+	return net
+}
+
+func (net *testNetwork) run(t *testing.T, height uint32) {
+	for _, node := range net.nodes {
+		node.Start()
+	}
+	defer func() {
+		for _, node := range net.nodes {
+			node.Stop()
+		}
+	}()
+
+	for i, sub := range net.finalized {
+		for {
+			select {
+			case event := <-sub.Events():
+				if event.Block.Header.Height < height {
+					continue
+				}
+			case <-time.After(20 * time.Second):
+				t.Fatalf("validator (%d) did not finalize height (%d)", i, height)
+			}
+			break
+		}
+	}
+}
+
+func TestFinalizesSameBlocks(t *testing.T) {
+	net := newTestNetwork(4, 4)
+	net.run(t, 3)
+
+	for height := uint32(1); height <= 3; height++ {
+		expected, err := net.chains[0].GetBlockAtHeight(height)
+		assert.Nil(t, err)
+		expectedHash, err := expected.Hash()
+		assert.Nil(t, err)
+		assert.Nil(t, VerifyCertificate(expected, net.set))
+
+		for _, bc := range net.chains[1:] {
+			block, err := bc.GetBlockAtHeight(height)
+			assert.Nil(t, err)
+			hash, err := block.Hash()
+			assert.Nil(t, err)
+			assert.Equal(t, expectedHash, hash)
+			assert.Nil(t, VerifyCertificate(block, net.set))
+		}
+	}
+
+	for _, bc := range net.chains {
+		assert.True(t, bc.GetFinalizedBlock().Header.Height >= 3)
+	}
+}
+
+func TestLivenessWithOfflineValidator(t *testing.T) {
+	net := newTestNetwork(4, 3)
+	net.run(t, 4)
+
+	for _, bc := range net.chains {
+		assert.True(t, bc.GetFinalizedBlock().Header.Height >= 4)
+		assert.Nil(t, VerifyCertificate(bc.GetFinalizedBlock(), net.set))
+	}
+}
+
+func TestVerifyCertificate(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 4)
+	publicKeys := make([]*ecdsa.PublicKey, 4)
+	for i := range keys {
+		keys[i] = crypto.GeneratePrivateKey()
+		publicKeys[i] = &keys[i].PublicKey
+	}
+	set := NewValidatorSet(publicKeys...)
+
+	block := core.NewBlock()
+	block.Header.Height = 1
+	assert.Nil(t, block.Sign(keys[0]))
+	blockHash, err := block.Hash()
+	assert.Nil(t, err)
+
+	precommit := func(privKey *ecdsa.PrivateKey, hash types.Hash) *Vote {
+		vote, err := NewVote(VotePrecommit, 1, 0, hash, privKey)
+		assert.Nil(t, err)
+		return vote
+	}
+
+	// Missing certificate
+	assert.NotNil(t, VerifyCertificate(block, set))
+
+	// Quorum of precommits
+	block.Certificate = newCertificate(1, 0, []*Vote{
+		precommit(keys[0], blockHash),
+		precommit(keys[1], blockHash),
+		precommit(keys[2], blockHash),
+	})
+	assert.Nil(t, VerifyCertificate(block, set))
+
+	// Duplicate signer does not count twice
+	block.Certificate = newCertificate(1, 0, []*Vote{
+		precommit(keys[0], blockHash),
+		precommit(keys[0], blockHash),
+		precommit(keys[1], blockHash),
+	})
+	assert.NotNil(t, VerifyCertificate(block, set))
+
+	// Signer outside the set
+	block.Certificate = newCertificate(1, 0, []*Vote{
+		precommit(keys[0], blockHash),
+		precommit(keys[1], blockHash),
+		precommit(crypto.GeneratePrivateKey(), blockHash),
+	})
+	assert.NotNil(t, VerifyCertificate(block, set))
+
+	// Precommits for another block
+	otherHash := types.Hash{1}
+	block.Certificate = newCertificate(1, 0, []*Vote{
+		precommit(keys[0], otherHash),
+		precommit(keys[1], otherHash),
+		precommit(keys[2], otherHash),
+	})
+	assert.NotNil(t, VerifyCertificate(block, set))
+
+	// Signature does not cover the round
+	block.Certificate = newCertificate(1, 0, []*Vote{
+		precommit(keys[0], blockHash),
+		precommit(keys[1], blockHash),
+		precommit(keys[2], blockHash),
+	})
+	block.Certificate.Round = 1
+	assert.NotNil(t, VerifyCertificate(block, set))
+}
+
+func TestMessageEncoding(t *testing.T) {
+	privKey := crypto.GeneratePrivateKey()
+
+	block := core.NewBlock()
+	block.Header.Height = 1
+	assert.Nil(t, block.Sign(privKey))
+
+	proposal, err := NewProposal(1, 2, block, privKey)
+	assert.Nil(t, err)
+	payload, err := encodeMessage(messageProposal, proposal)
+	assert.Nil(t, err)
+
+	decoded, err := decodeMessage(payload)
+	assert.Nil(t, err)
+	decodedProposal, ok := decoded.(*Proposal)
+	assert.True(t, ok)
+	assert.Nil(t, decodedProposal.Verify())
+	assert.Equal(t, uint32(2), decodedProposal.Round)
+
+	vote, err := NewVote(VotePrevote, 1, 2, types.Hash{}, privKey)
+	assert.Nil(t, err)
+	payload, err = encodeMessage(messageVote, vote)
+	assert.Nil(t, err)
+
+	decoded, err = decodeMessage(payload)
+	assert.Nil(t, err)
+	decodedVote, ok := decoded.(*Vote)
+	assert.True(t, ok)
+	assert.Nil(t, decodedVote.Verify())
+
+	decodedVote.Round = 3
+	assert.NotNil(t, decodedVote.Verify())
+}
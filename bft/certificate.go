@@ -0,0 +1,64 @@
+package bft
+
+import (
+	"fmt"
+
+	"github.com/tusharjoshi4531/block-chain.git/core"
+)
+
+func newCertificate(height, round uint32, votes []*Vote) *core.CommitCertificate {
+	cert := &core.CommitCertificate{
+		Height:    height,
+		Round:     round,
+		BlockHash: votes[0].BlockHash,
+		Votes:     make([]core.CommitVote, 0, len(votes)),
+	}
+	for _, vote := range votes {
+		cert.Votes = append(cert.Votes, core.CommitVote{
+			Validator: vote.Validator,
+			Signature: vote.Signature,
+		})
+	}
+	return cert
+}
+
+// VerifyCertificate checks that the certificate of the block holds
+// precommits for it from a quorum of distinct validators of the set.
+func VerifyCertificate(block *core.Block, set *ValidatorSet) error {
+	cert := block.Certificate
+	if cert == nil {
+		return fmt.Errorf("block has no commit certificate")
+	}
+
+	blockHash, err := block.Hash()
+	if err != nil {
+		return err
+	}
+	if cert.BlockHash != blockHash || cert.Height != block.Header.Height {
+		return fmt.Errorf("commit certificate is for a different block")
+	}
+
+	signers := make(map[string]struct{})
+	for _, commitVote := range cert.Votes {
+		vote := &Vote{
+			Type:      VotePrecommit,
+			Height:    cert.Height,
+			Round:     cert.Round,
+			BlockHash: cert.BlockHash,
+			Validator: commitVote.Validator,
+			Signature: commitVote.Signature,
+		}
+		if !set.Contains(vote.Validator) {
+			return fmt.Errorf("commit certificate has a vote from outside the validator set")
+		}
+		if err := vote.Verify(); err != nil {
+			return err
+		}
+		signers[validatorId(vote.Validator)] = struct{}{}
+	}
+
+	if len(signers) < set.Quorum() {
+		return fmt.Errorf("commit certificate has (%d) distinct precommits; quorum is (%d)", len(signers), set.Quorum())
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+package bft
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"fmt"
+	"io"
+
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+	"github.com/tusharjoshi4531/block-chain.git/types"
+	"github.com/tusharjoshi4531/block-chain.git/util"
+)
+
+type VoteType uint8
+
+const (
+	VotePrevote VoteType = iota + 1
+	VotePrecommit
+)
+
+const (
+	messageProposal uint8 = iota + 1
+	messageVote
+)
+
+const messageEncodingVersion uint8 = 1
+
+// signHash is the digest validators sign for a proposal or vote. A zero
+// block hash stands for a vote for no block.
+func signHash(kind uint8, voteType VoteType, height, round uint32, blockHash types.Hash) types.Hash {
+	buf := &bytes.Buffer{}
+	cw := util.NewCanonicalWriter(buf)
+	cw.WriteFixed([]byte("bft"))
+	cw.WriteUint8(messageEncodingVersion)
+	cw.WriteUint8(kind)
+	cw.WriteUint8(uint8(voteType))
+	cw.WriteUint32(height)
+	cw.WriteUint32(round)
+	cw.WriteFixed(blockHash[:])
+	return sha256.Sum256(buf.Bytes())
+}
+
+type Proposal struct {
+	Height    uint32
+	Round     uint32
+	Block     *core.Block
+	Proposer  *ecdsa.PublicKey
+	Signature *crypto.Signature
+}
+
+func NewProposal(height, round uint32, block *core.Block, privKey *ecdsa.PrivateKey) (*Proposal, error) {
+	proposal := &Proposal{
+		Height:   height,
+		Round:    round,
+		Block:    block,
+		Proposer: &privKey.PublicKey,
+	}
+
+	hash, err := proposal.signHash()
+	if err != nil {
+		return nil, err
+	}
+	if proposal.Signature, err = crypto.SignBytes(privKey, hash[:]); err != nil {
+		return nil, err
+	}
+	return proposal, nil
+}
+
+func (proposal *Proposal) signHash() (types.Hash, error) {
+	blockHash, err := proposal.Block.Hash()
+	if err != nil {
+		return types.Hash{}, err
+	}
+	return signHash(messageProposal, 0, proposal.Height, proposal.Round, blockHash), nil
+}
+
+func (proposal *Proposal) Verify() error {
+	hash, err := proposal.signHash()
+	if err != nil {
+		return err
+	}
+	if !proposal.Signature.Verify(proposal.Proposer, hash[:]) {
+		return fmt.Errorf("incorrect sign in proposal")
+	}
+	return nil
+}
+
+func (proposal *Proposal) Encode(w io.Writer) error {
+	cw := util.NewCanonicalWriter(w)
+	cw.WriteUint32(proposal.Height)
+	cw.WriteUint32(proposal.Round)
+	if err := cw.Err(); err != nil {
+		return err
+	}
+	if err := proposal.Block.Encode(w); err != nil {
+		return err
+	}
+	if err := crypto.SerializePublicKey(proposal.Proposer).Encode(w); err != nil {
+		return err
+	}
+	return proposal.Signature.Encode(w)
+}
+
+func (proposal *Proposal) Decode(r io.Reader) error {
+	cr := util.NewCanonicalReader(r)
+	proposal.Height = cr.ReadUint32()
+	proposal.Round = cr.ReadUint32()
+	if err := cr.Err(); err != nil {
+		return err
+	}
+
+	proposal.Block = core.NewBlock()
+	if err := proposal.Block.Decode(r); err != nil {
+		return err
+	}
+	proposer := &crypto.SerializablePublicKey{}
+	if err := proposer.Decode(r); err != nil {
+		return err
+	}
+	proposal.Proposer = crypto.DecodePublicKey(proposer)
+	proposal.Signature = crypto.NewNilSignature()
+	return proposal.Signature.Decode(r)
+}
+
+type Vote struct {
+	Type      VoteType
+	Height    uint32
+	Round     uint32
+	BlockHash types.Hash
+	Validator *ecdsa.PublicKey
+	Signature *crypto.Signature
+}
+
+func NewVote(voteType VoteType, height, round uint32, blockHash types.Hash, privKey *ecdsa.PrivateKey) (*Vote, error) {
+	vote := &Vote{
+		Type:      voteType,
+		Height:    height,
+		Round:     round,
+		BlockHash: blockHash,
+		Validator: &privKey.PublicKey,
+	}
+
+	hash := vote.signHash()
+	sig, err := crypto.SignBytes(privKey, hash[:])
+	if err != nil {
+		return nil, err
+	}
+	vote.Signature = sig
+	return vote, nil
+}
+
+func (vote *Vote) signHash() types.Hash {
+	return signHash(messageVote, vote.Type, vote.Height, vote.Round, vote.BlockHash)
+}
+
+func (vote *Vote) Verify() error {
+	if vote.Type != VotePrevote && vote.Type != VotePrecommit {
+		return fmt.Errorf("invalid vote type (%d)", vote.Type)
+	}
+	hash := vote.signHash()
+	if !vote.Signature.Verify(vote.Validator, hash[:]) {
+		return fmt.Errorf("incorrect sign in vote")
+	}
+	return nil
+}
+
+func (vote *Vote) Encode(w io.Writer) error {
+	cw := util.NewCanonicalWriter(w)
+	cw.WriteUint8(uint8(vote.Type))
+	cw.WriteUint32(vote.Height)
+	cw.WriteUint32(vote.Round)
+	cw.WriteFixed(vote.BlockHash[:])
+	if err := cw.Err(); err != nil {
+		return err
+	}
+	if err := crypto.SerializePublicKey(vote.Validator).Encode(w); err != nil {
+		return err
+	}
+	return vote.Signature.Encode(w)
+}
+
+func (vote *Vote) Decode(r io.Reader) error {
+	cr := util.NewCanonicalReader(r)
+	vote.Type = VoteType(cr.ReadUint8())
+	vote.Height = cr.ReadUint32()
+	vote.Round = cr.ReadUint32()
+	cr.ReadFixed(vote.BlockHash[:])
+	if err := cr.Err(); err != nil {
+		return err
+	}
+
+	validator := &crypto.SerializablePublicKey{}
+	if err := validator.Decode(r); err != nil {
+		return err
+	}
+	vote.Validator = crypto.DecodePublicKey(validator)
+	vote.Signature = crypto.NewNilSignature()
+	return vote.Signature.Decode(r)
+}
+
+func encodeMessage(kind uint8, msg util.Encoder) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	cw := util.NewCanonicalWriter(buf)
+	cw.WriteUint8(messageEncodingVersion)
+	cw.WriteUint8(kind)
+	if err := cw.Err(); err != nil {
+		return nil, err
+	}
+	if err := msg.Encode(buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeMessage returns either a *Proposal or a *Vote.
+func decodeMessage(payload []byte) (any, error) {
+	r := bytes.NewReader(payload)
+	cr := util.NewCanonicalReader(r)
+	cr.ReadVersion(messageEncodingVersion)
+	kind := cr.ReadUint8()
+	if err := cr.Err(); err != nil {
+		return nil, err
+	}
+
+	var msg util.Decoder
+	switch kind {
+	case messageProposal:
+		msg = &Proposal{}
+	case messageVote:
+		msg = &Vote{}
+	default:
+		return nil, fmt.Errorf("unknown bft message kind (%d)", kind)
+	}
+	if err := msg.Decode(r); err != nil {
+		return nil, err
+	}
+	cr.ReadEnd()
+	if err := cr.Err(); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
@@ -0,0 +1,506 @@
+package bft
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/network"
+	"github.com/tusharjoshi4531/block-chain.git/types"
+	"github.com/tusharjoshi4531/block-chain.git/util"
+)
+
+var errBlockNotOnTip = fmt.Errorf("block does not extend the current tip")
+
+type Step uint8
+
+const (
+	StepPropose Step = iota
+	StepPrevote
+	StepPrecommit
+)
+
+type Config struct {
+	TimeoutPropose   time.Duration
+	TimeoutPrevote   time.Duration
+	TimeoutPrecommit time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		TimeoutPropose:   3 * time.Second,
+		TimeoutPrevote:   time.Second,
+		TimeoutPrecommit: time.Second,
+	}
+}
+
+// ProposeFunc builds a signed block on top of the current tip.
+type ProposeFunc func() (*core.Block, error)
+
+type timeoutInfo struct {
+	height uint32
+	round  uint32
+	step   Step
+}
+
+type roundVotes struct {
+	prevotes   map[string]*Vote
+	precommits map[string]*Vote
+}
+
+func newRoundVotes() *roundVotes {
+	return &roundVotes{
+		prevotes:   make(map[string]*Vote),
+		precommits: make(map[string]*Vote),
+	}
+}
+
+// Node runs a Tendermint style round protocol over a transport. In every
+// round the scheduled validator proposes a block, validators prevote for it
+// and precommit once more than two thirds prevoted the same block. A block
+// with more than two thirds of precommits is added to the chain with its
+// commit certificate and finalized, so the chain never reorganizes below
+// it.
+type Node struct {
+	transport  network.Transport
+	blockChain core.BlockChain
+	validators *ValidatorSet
+	privateKey *ecdsa.PrivateKey
+	propose    ProposeFunc
+	validate   func(*core.Block) error
+	config     Config
+
+	height      uint32
+	round       uint32
+	step        Step
+	proposals   map[uint32]*Proposal
+	votes       map[uint32]*roundVotes
+	lockedBlock *core.Block
+	future      []any
+	queue       []any
+
+	timeouts chan timeoutInfo
+	done     chan struct{}
+	stopped  chan struct{}
+	mu       sync.RWMutex
+}
+
+func NewNode(
+	transport network.Transport,
+	blockChain core.BlockChain,
+	validators *ValidatorSet,
+	privKey *ecdsa.PrivateKey,
+	propose ProposeFunc,
+	config Config,
+) *Node {
+	return &Node{
+		transport:  transport,
+		blockChain: blockChain,
+		validators: validators,
+		privateKey: privKey,
+		propose:    propose,
+		config:     config,
+		timeouts:   make(chan timeoutInfo, 16),
+	}
+}
+
+// SetBlockValidator installs an extra check proposals must pass before this
+// node prevotes for them.
+func (node *Node) SetBlockValidator(validate func(*core.Block) error) {
+	node.validate = validate
+}
+
+func (node *Node) Start() {
+	node.done = make(chan struct{})
+	node.stopped = make(chan struct{})
+	go node.run()
+}
+
+func (node *Node) Stop() {
+	close(node.done)
+	<-node.stopped
+}
+
+// Height returns the height the node is currently deciding.
+func (node *Node) Height() uint32 {
+	node.mu.RLock()
+	defer node.mu.RUnlock()
+	return node.height
+}
+
+func (node *Node) run() {
+	defer close(node.stopped)
+
+	node.startHeight(node.blockChain.Height() + 1)
+	node.drainQueue()
+
+	for {
+		select {
+		case <-node.done:
+			return
+		case msg := <-node.transport.ReadChan():
+			message, err := decodeMessage(msg.Payload)
+			if err != nil {
+				log.Printf("bft: dropping message from (%s): %s", msg.From, err)
+				continue
+			}
+			node.handleMessage(message)
+		case timeout := <-node.timeouts:
+			node.handleTimeout(timeout)
+		}
+		node.drainQueue()
+	}
+}
+
+// drainQueue handles the node's own messages and replayed future messages.
+// Queuing them instead of handling them in place keeps state transitions
+// from nesting.
+func (node *Node) drainQueue() {
+	for len(node.queue) > 0 {
+		message := node.queue[0]
+		node.queue = node.queue[1:]
+		node.handleMessage(message)
+	}
+}
+
+func (node *Node) startHeight(height uint32) {
+	node.mu.Lock()
+	node.height = height
+	node.mu.Unlock()
+
+	node.proposals = make(map[uint32]*Proposal)
+	node.votes = make(map[uint32]*roundVotes)
+	node.lockedBlock = nil
+
+	future := node.future
+	node.future = nil
+	for _, message := range future {
+		node.queue = append(node.queue, message)
+	}
+
+	node.enterRound(0)
+}
+
+func (node *Node) enterRound(round uint32) {
+	node.round = round
+	node.step = StepPropose
+	node.scheduleTimeout(StepPropose, node.config.TimeoutPropose)
+
+	proposer := node.validators.Proposer(node.height, round)
+	if validatorId(proposer) != validatorId(&node.privateKey.PublicKey) {
+		node.tryPrevote()
+		return
+	}
+
+	block := node.lockedBlock
+	if block == nil {
+		var err error
+		if block, err = node.propose(); err != nil {
+			log.Printf("bft: could not propose block at height (%d): %s", node.height, err)
+			return
+		}
+	}
+
+	proposal, err := NewProposal(node.height, round, block, node.privateKey)
+	if err != nil {
+		log.Printf("bft: could not sign proposal: %s", err)
+		return
+	}
+	node.broadcast(messageProposal, proposal)
+}
+
+func (node *Node) handleMessage(message any) {
+	switch msg := message.(type) {
+	case *Proposal:
+		node.handleProposal(msg)
+	case *Vote:
+		node.handleVote(msg)
+	}
+}
+
+func (node *Node) handleProposal(proposal *Proposal) {
+	if proposal.Height < node.height {
+		return
+	}
+	if proposal.Height > node.height {
+		node.future = append(node.future, proposal)
+		return
+	}
+	if _, ok := node.proposals[proposal.Round]; ok {
+		return
+	}
+
+	expected := node.validators.Proposer(proposal.Height, proposal.Round)
+	if validatorId(proposal.Proposer) != validatorId(expected) {
+		return
+	}
+	if err := proposal.Verify(); err != nil {
+		return
+	}
+	if err := node.checkBlock(proposal.Block); err != nil {
+		log.Printf("bft: rejecting proposal at height (%d) round (%d): %s", proposal.Height, proposal.Round, err)
+		return
+	}
+
+	node.proposals[proposal.Round] = proposal
+	node.tryPrevote()
+	node.checkPrecommits(proposal.Round)
+}
+
+func (node *Node) checkBlock(block *core.Block) error {
+	tipHash, err := node.blockChain.GetHeighestBlock().Hash()
+	if err != nil {
+		return err
+	}
+	if block.Header.Height != node.height || block.Header.PrevBlockHash != tipHash {
+		return errBlockNotOnTip
+	}
+	if err := block.Verify(); err != nil {
+		return err
+	}
+	if node.validate != nil {
+		return node.validate(block)
+	}
+	return nil
+}
+
+// tryPrevote prevotes for the proposal of the current round, or for nothing
+// when the node is locked on a different block.
+func (node *Node) tryPrevote() {
+	proposal, ok := node.proposals[node.round]
+	if !ok || node.step != StepPropose {
+		return
+	}
+
+	blockHash, err := proposal.Block.Hash()
+	if err != nil {
+		return
+	}
+	if node.lockedBlock != nil {
+		lockedHash, err := node.lockedBlock.Hash()
+		if err != nil || lockedHash != blockHash {
+			blockHash = types.Hash{}
+		}
+	}
+	node.castVote(VotePrevote, blockHash)
+}
+
+func (node *Node) castVote(voteType VoteType, blockHash types.Hash) {
+	vote, err := NewVote(voteType, node.height, node.round, blockHash, node.privateKey)
+	if err != nil {
+		log.Printf("bft: could not sign vote: %s", err)
+		return
+	}
+
+	if voteType == VotePrevote {
+		node.step = StepPrevote
+		node.scheduleTimeout(StepPrevote, node.config.TimeoutPrevote)
+	} else {
+		node.step = StepPrecommit
+		node.scheduleTimeout(StepPrecommit, node.config.TimeoutPrecommit)
+	}
+	node.broadcast(messageVote, vote)
+}
+
+func (node *Node) handleVote(vote *Vote) {
+	if vote.Height < node.height {
+		return
+	}
+	if vote.Height > node.height {
+		node.future = append(node.future, vote)
+		return
+	}
+	if !node.validators.Contains(vote.Validator) {
+		return
+	}
+	if err := vote.Verify(); err != nil {
+		return
+	}
+
+	votes, ok := node.votes[vote.Round]
+	if !ok {
+		votes = newRoundVotes()
+		node.votes[vote.Round] = votes
+	}
+
+	id := validatorId(vote.Validator)
+	if vote.Type == VotePrevote {
+		if _, ok := votes.prevotes[id]; ok {
+			return
+		}
+		votes.prevotes[id] = vote
+	} else {
+		if _, ok := votes.precommits[id]; ok {
+			return
+		}
+		votes.precommits[id] = vote
+	}
+
+	node.checkRoundSkip(vote.Round)
+	if vote.Type == VotePrevote {
+		node.checkPrevotes(vote.Round)
+	} else {
+		node.checkPrecommits(vote.Round)
+	}
+}
+
+// checkPrevotes locks on and precommits a block once a quorum prevoted for
+// it, and precommits nothing once a quorum prevoted nothing.
+func (node *Node) checkPrevotes(round uint32) {
+	if round != node.round || node.step == StepPrecommit {
+		return
+	}
+
+	blockHash, ok := node.quorumHash(node.votes[round].prevotes)
+	if !ok {
+		return
+	}
+	if blockHash.IsZero() {
+		node.castVote(VotePrecommit, blockHash)
+		return
+	}
+
+	block := node.findBlock(round, blockHash)
+	if block == nil {
+		return
+	}
+	node.lockedBlock = block
+	node.castVote(VotePrecommit, blockHash)
+}
+
+// checkPrecommits commits a block once a quorum precommitted it in any
+// round, and moves to the next round once a quorum precommitted nothing in
+// the current one.
+func (node *Node) checkPrecommits(round uint32) {
+	votes, ok := node.votes[round]
+	if !ok {
+		return
+	}
+
+	blockHash, ok := node.quorumHash(votes.precommits)
+	if !ok {
+		return
+	}
+	if blockHash.IsZero() {
+		if round == node.round {
+			node.enterRound(round + 1)
+		}
+		return
+	}
+
+	block := node.findBlock(round, blockHash)
+	if block == nil {
+		return
+	}
+	node.commit(block, round, blockHash)
+}
+
+// checkRoundSkip jumps to a later round once more than a third of the
+// validators are seen voting in it.
+func (node *Node) checkRoundSkip(round uint32) {
+	if round <= node.round {
+		return
+	}
+
+	votes := node.votes[round]
+	voters := make(map[string]struct{})
+	for id := range votes.prevotes {
+		voters[id] = struct{}{}
+	}
+	for id := range votes.precommits {
+		voters[id] = struct{}{}
+	}
+	if len(voters)*3 > node.validators.Size() {
+		node.enterRound(round)
+	}
+}
+
+func (node *Node) quorumHash(votes map[string]*Vote) (types.Hash, bool) {
+	counts := make(map[types.Hash]int)
+	for _, vote := range votes {
+		counts[vote.BlockHash]++
+		if counts[vote.BlockHash] >= node.validators.Quorum() {
+			return vote.BlockHash, true
+		}
+	}
+	return types.Hash{}, false
+}
+
+func (node *Node) findBlock(round uint32, blockHash types.Hash) *core.Block {
+	candidates := make([]*core.Block, 0, 2)
+	if proposal, ok := node.proposals[round]; ok {
+		candidates = append(candidates, proposal.Block)
+	}
+	if node.lockedBlock != nil {
+		candidates = append(candidates, node.lockedBlock)
+	}
+
+	for _, block := range candidates {
+		if hash, err := block.Hash(); err == nil && hash == blockHash {
+			return block
+		}
+	}
+	return nil
+}
+
+func (node *Node) commit(block *core.Block, round uint32, blockHash types.Hash) {
+	precommits := make([]*Vote, 0, node.validators.Size())
+	for _, vote := range node.votes[round].precommits {
+		if vote.BlockHash == blockHash {
+			precommits = append(precommits, vote)
+		}
+	}
+	block.Certificate = newCertificate(node.height, round, precommits)
+
+	if err := node.blockChain.AddBlock(block); err != nil {
+		log.Printf("bft: could not add committed block (%s): %s", blockHash.String(), err)
+		return
+	}
+	if err := node.blockChain.Finalize(blockHash); err != nil {
+		log.Printf("bft: could not finalize block (%s): %s", blockHash.String(), err)
+		return
+	}
+
+	node.startHeight(node.height + 1)
+}
+
+func (node *Node) handleTimeout(timeout timeoutInfo) {
+	if timeout.height != node.height || timeout.round != node.round || timeout.step != node.step {
+		return
+	}
+
+	switch timeout.step {
+	case StepPropose:
+		node.castVote(VotePrevote, types.Hash{})
+	case StepPrevote:
+		node.castVote(VotePrecommit, types.Hash{})
+	case StepPrecommit:
+		node.enterRound(node.round + 1)
+	}
+}
+
+func (node *Node) scheduleTimeout(step Step, duration time.Duration) {
+	timeout := timeoutInfo{height: node.height, round: node.round, step: step}
+	done := node.done
+	time.AfterFunc(duration, func() {
+		select {
+		case node.timeouts <- timeout:
+		case <-done:
+		}
+	})
+}
+
+func (node *Node) broadcast(kind uint8, message util.Encoder) {
+	payload, err := encodeMessage(kind, message)
+	if err != nil {
+		log.Printf("bft: could not encode message: %s", err)
+		return
+	}
+	if err := node.transport.BroadCastMessage(network.NewMessage(node.transport.Address(), payload)); err != nil {
+		log.Printf("bft: could not broadcast message: %s", err)
+	}
+	node.queue = append(node.queue, message)
+}
@@ -0,0 +1,54 @@
+package bft
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+)
+
+// ValidatorSet is the fixed, ordered set of keys taking part in consensus.
+type ValidatorSet struct {
+	validators []*ecdsa.PublicKey
+	index      map[string]int
+}
+
+func NewValidatorSet(validators ...*ecdsa.PublicKey) *ValidatorSet {
+	set := &ValidatorSet{
+		validators: make([]*ecdsa.PublicKey, 0, len(validators)),
+		index:      make(map[string]int),
+	}
+	for _, validator := range validators {
+		id := validatorId(validator)
+		if _, ok := set.index[id]; ok {
+			continue
+		}
+		set.index[id] = len(set.validators)
+		set.validators = append(set.validators, validator)
+	}
+	return set
+}
+
+func (set *ValidatorSet) Size() int {
+	return len(set.validators)
+}
+
+// Quorum is the smallest number of validators that is more than two thirds
+// of the set.
+func (set *ValidatorSet) Quorum() int {
+	return len(set.validators)*2/3 + 1
+}
+
+func (set *ValidatorSet) Contains(key *ecdsa.PublicKey) bool {
+	_, ok := set.index[validatorId(key)]
+	return ok
+}
+
+// Proposer rotates through the set by height and round.
+func (set *ValidatorSet) Proposer(height, round uint32) *ecdsa.PublicKey {
+	return set.validators[(int(height)+int(round))%len(set.validators)]
+}
+
+func validatorId(key *ecdsa.PublicKey) string {
+	return hex.EncodeToString(crypto.PublicKeyBytes(key))
+}
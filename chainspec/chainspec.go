@@ -0,0 +1,272 @@
+package chainspec
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+	"github.com/tusharjoshi4531/block-chain.git/currency"
+	"github.com/tusharjoshi4531/block-chain.git/types"
+	"github.com/tusharjoshi4531/block-chain.git/util"
+)
+
+const (
+	EnginePow = "pow"
+	EnginePoa = "poa"
+	EnginePos = "pos"
+)
+
+const (
+	LedgerAccount = "account"
+	LedgerUTXO    = "utxo"
+)
+
+// specEncodingVersion is hashed in front of the spec so that changes to the
+// hashed fields change every genesis.
+const specEncodingVersion uint8 = 2
+
+// Spec describes a chain: its genesis state, consensus engine, reward
+// schedule and ledger model. An empty Ledger keeps account balances. Its hash is sealed into the genesis block, so nodes started
+// from different specs do not share a genesis and refuse each other's
+// messages.
+type Spec struct {
+	Name      string         `json:"name"`
+	NetworkId uint32         `json:"networkId"`
+	Genesis   Genesis        `json:"genesis"`
+	Consensus Consensus      `json:"consensus"`
+	Reward    RewardSchedule `json:"reward"`
+	Ledger    string         `json:"ledger,omitempty"`
+	Chain     ChainRules     `json:"chain,omitempty"`
+}
+
+type Genesis struct {
+	Timestamp int64 `json:"timestamp"`
+	// InitialBalance is credited to wallets registered after genesis.
+	InitialBalance float64            `json:"initialBalance"`
+	Allocations    map[string]float64 `json:"allocations,omitempty"`
+	Stakes         []StakeAllocation  `json:"stakes,omitempty"`
+}
+
+// StakeAllocation locks part of a genesis allocation as validator stake.
+type StakeAllocation struct {
+	Wallet    string  `json:"wallet"`
+	Validator string  `json:"validator"`
+	Amount    float64 `json:"amount"`
+}
+
+type Consensus struct {
+	Engine string `json:"engine"`
+	// Difficulty is the number of leading zero hex digits of a pow block
+	// hash.
+	Difficulty uint8 `json:"difficulty,omitempty"`
+	// Authorities are the hex encoded public keys of the initial poa
+	// signers.
+	Authorities []string `json:"authorities,omitempty"`
+	MinStake    float64  `json:"minStake,omitempty"`
+}
+
+// ChainRules bound how far the node lets a competing fork rewind its chain.
+// They are local policy rather than consensus and are left out of the spec
+// hash; checkpoints name blocks whose hashes already depend on the genesis.
+type ChainRules struct {
+	// MaxReorgDepth is the deepest fork the node switches to. Nil keeps
+	// currency.DefaultMaxReorgDepth and 0 disables the limit.
+	MaxReorgDepth *uint32 `json:"maxReorgDepth,omitempty"`
+	// Checkpoints pin the hex encoded hash of the main chain block at each
+	// height.
+	Checkpoints map[uint32]string `json:"checkpoints,omitempty"`
+}
+
+type RewardSchedule struct {
+	InitialReward   float64 `json:"initialReward"`
+	HalvingInterval uint16  `json:"halvingInterval"`
+}
+
+// Default is the spec nodes use when no chain spec file is given.
+func Default() *Spec {
+	return &Spec{
+		Name:      "default",
+		NetworkId: 1,
+		Genesis: Genesis{
+			InitialBalance: 10000,
+			Allocations:    map[string]float64{},
+		},
+		Consensus: Consensus{
+			Engine:     EnginePow,
+			Difficulty: 1,
+		},
+		Reward: RewardSchedule{
+			InitialReward:   100,
+			HalvingInterval: 10,
+		},
+	}
+}
+
+func Load(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+func Parse(data []byte) (*Spec, error) {
+	spec := &Spec{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(spec); err != nil {
+		return nil, err
+	}
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+func (spec *Spec) Validate() error {
+	for wallet, amount := range spec.Genesis.Allocations {
+		if currency.IsSystemWallet(wallet) {
+			return fmt.Errorf("genesis allocation to system wallet (%s)", wallet)
+		}
+		if amount < 0 {
+			return fmt.Errorf("genesis allocation to (%s) is negative", wallet)
+		}
+	}
+	for _, stake := range spec.Genesis.Stakes {
+		if _, ok := spec.Genesis.Allocations[stake.Wallet]; !ok {
+			return fmt.Errorf("genesis stake from (%s) has no allocation", stake.Wallet)
+		}
+		if stake.Amount <= 0 {
+			return fmt.Errorf("genesis stake from (%s) must be positive", stake.Wallet)
+		}
+	}
+
+	switch spec.Consensus.Engine {
+	case EnginePow:
+		if spec.Consensus.Difficulty > 64 {
+			return fmt.Errorf("difficulty (%d) exceeds the hash length", spec.Consensus.Difficulty)
+		}
+	case EnginePoa:
+		if len(spec.Consensus.Authorities) == 0 {
+			return fmt.Errorf("poa needs at least one authority")
+		}
+		if _, err := spec.AuthorityKeys(); err != nil {
+			return err
+		}
+	case EnginePos:
+		if len(spec.Genesis.Stakes) == 0 {
+			return fmt.Errorf("pos needs at least one genesis stake")
+		}
+	default:
+		return fmt.Errorf("unknown consensus engine (%s)", spec.Consensus.Engine)
+	}
+
+	if spec.Reward.HalvingInterval == 0 {
+		return fmt.Errorf("reward halving interval must be positive")
+	}
+
+	switch spec.Ledger {
+	case "", LedgerAccount, LedgerUTXO:
+	default:
+		return fmt.Errorf("unknown ledger model (%s)", spec.Ledger)
+	}
+
+	if _, err := spec.CheckpointHashes(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (spec *Spec) AuthorityKeys() ([]*ecdsa.PublicKey, error) {
+	keys := make([]*ecdsa.PublicKey, 0, len(spec.Consensus.Authorities))
+	for _, authority := range spec.Consensus.Authorities {
+		keyBytes, err := hex.DecodeString(authority)
+		if err != nil {
+			return nil, fmt.Errorf("invalid authority (%s): %s", authority, err)
+		}
+		key, err := crypto.PublicKeyFromBytes(keyBytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid authority (%s): %s", authority, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (spec *Spec) CheckpointHashes() (map[uint32]types.Hash, error) {
+	hashes := make(map[uint32]types.Hash, len(spec.Chain.Checkpoints))
+	for height, checkpoint := range spec.Chain.Checkpoints {
+		hash, err := types.HashFromString(checkpoint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid checkpoint at height (%d): %s", height, err)
+		}
+		hashes[height] = hash
+	}
+	return hashes, nil
+}
+
+// Hash is the sha256 of the canonical encoding of every field of the spec
+// except the chain rules, with allocations sorted by wallet.
+func (spec *Spec) Hash() (types.Hash, error) {
+	buf := &bytes.Buffer{}
+	cw := util.NewCanonicalWriter(buf)
+	cw.WriteUint8(specEncodingVersion)
+	cw.WriteString(spec.Name)
+	cw.WriteUint32(spec.NetworkId)
+
+	cw.WriteInt64(spec.Genesis.Timestamp)
+	cw.WriteFloat64(spec.Genesis.InitialBalance)
+	wallets := make([]string, 0, len(spec.Genesis.Allocations))
+	for wallet := range spec.Genesis.Allocations {
+		wallets = append(wallets, wallet)
+	}
+	sort.Strings(wallets)
+	cw.WriteUint32(uint32(len(wallets)))
+	for _, wallet := range wallets {
+		cw.WriteString(wallet)
+		cw.WriteFloat64(spec.Genesis.Allocations[wallet])
+	}
+	cw.WriteUint32(uint32(len(spec.Genesis.Stakes)))
+	for _, stake := range spec.Genesis.Stakes {
+		cw.WriteString(stake.Wallet)
+		cw.WriteString(stake.Validator)
+		cw.WriteFloat64(stake.Amount)
+	}
+
+	cw.WriteString(spec.Consensus.Engine)
+	cw.WriteUint8(spec.Consensus.Difficulty)
+	cw.WriteUint32(uint32(len(spec.Consensus.Authorities)))
+	for _, authority := range spec.Consensus.Authorities {
+		cw.WriteString(authority)
+	}
+	cw.WriteFloat64(spec.Consensus.MinStake)
+
+	cw.WriteFloat64(spec.Reward.InitialReward)
+	cw.WriteUint16(spec.Reward.HalvingInterval)
+	cw.WriteString(spec.Ledger)
+
+	if err := cw.Err(); err != nil {
+		return types.Hash{}, err
+	}
+	return sha256.Sum256(buf.Bytes()), nil
+}
+
+// GenesisBlock is the empty genesis of the spec with the spec hash as the
+// extra data of its seal.
+func (spec *Spec) GenesisBlock() (*core.Block, error) {
+	specHash, err := spec.Hash()
+	if err != nil {
+		return nil, err
+	}
+
+	seal := core.Seal{}
+	copy(seal.Extra[:], specHash[:])
+	return core.NewGenesisBlock(spec.Genesis.Timestamp, seal), nil
+}
@@ -0,0 +1,258 @@
+package chainspec
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+	"github.com/tusharjoshi4531/block-chain.git/currency"
+	"github.com/tusharjoshi4531/block-chain.git/poa"
+	"github.com/tusharjoshi4531/block-chain.git/pos"
+	"github.com/tusharjoshi4531/block-chain.git/pow"
+	"github.com/tusharjoshi4531/block-chain.git/types"
+)
+
+const testSpec = `{
+	"name": "testnet",
+	"networkId": 7,
+	"genesis": {
+		"timestamp": 1700000000,
+		"initialBalance": 50,
+		"allocations": {"alice": 1000, "bob": 500},
+		"stakes": [{"wallet": "alice", "validator": "v1", "amount": 400}]
+	},
+	"consensus": {"engine": "pos", "minStake": 100},
+	"reward": {"initialReward": 20, "halvingInterval": 5}
+}`
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spec.json")
+	assert.Nil(t, os.WriteFile(path, []byte(testSpec), 0o600))
+
+	spec, err := Load(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "testnet", spec.Name)
+	assert.Equal(t, uint32(7), spec.NetworkId)
+	assert.Equal(t, float64(1000), spec.Genesis.Allocations["alice"])
+	assert.Equal(t, EnginePos, spec.Consensus.Engine)
+	assert.Equal(t, uint16(5), spec.Reward.HalvingInterval)
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.json"))
+	assert.NotNil(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	invalid := []string{
+		`{"consensus": {"engine": "pow"}, "reward": {"halvingInterval": 0}}`,
+		`{"consensus": {"engine": "unknown"}, "reward": {"halvingInterval": 1}}`,
+		`{"consensus": {"engine": "pow", "difficulty": 65}, "reward": {"halvingInterval": 1}}`,
+		`{"consensus": {"engine": "poa"}, "reward": {"halvingInterval": 1}}`,
+		`{"consensus": {"engine": "poa", "authorities": ["zz"]}, "reward": {"halvingInterval": 1}}`,
+		`{"consensus": {"engine": "pos"}, "reward": {"halvingInterval": 1}}`,
+		`{"genesis": {"allocations": {"::": 10}}, "consensus": {"engine": "pow"}, "reward": {"halvingInterval": 1}}`,
+		`{"genesis": {"stakes": [{"wallet": "x", "validator": "v", "amount": 1}]}, "consensus": {"engine": "pos"}, "reward": {"halvingInterval": 1}}`,
+		`{"consensus": {"engine": "pow"}, "reward": {"halvingInterval": 1}, "unknown": true}`,
+		`{"consensus": {"engine": "pow"}, "reward": {"halvingInterval": 1}, "ledger": "unknown"}`,
+		`{"consensus": {"engine": "pow"}, "reward": {"halvingInterval": 1}, "chain": {"checkpoints": {"5": "zz"}}}`,
+		`{"consensus": {"engine": "pow"}, "reward": {"halvingInterval": 1}, "chain": {"checkpoints": {"5": "00"}}}`,
+	}
+	for _, data := range invalid {
+		_, err := Parse([]byte(data))
+		assert.NotNil(t, err, data)
+	}
+
+	key := crypto.GeneratePrivateKey()
+	poaSpec := fmt.Sprintf(
+		`{"consensus": {"engine": "poa", "authorities": [%q]}, "reward": {"halvingInterval": 1}}`,
+		hex.EncodeToString(crypto.PublicKeyBytes(&key.PublicKey)),
+	)
+	spec, err := Parse([]byte(poaSpec))
+	assert.Nil(t, err)
+	keys, err := spec.AuthorityKeys()
+	assert.Nil(t, err)
+	assert.True(t, key.PublicKey.Equal(keys[0]))
+}
+
+func TestGenesisBlock(t *testing.T) {
+	spec, err := Parse([]byte(testSpec))
+	assert.Nil(t, err)
+
+	specHash, err := spec.Hash()
+	assert.Nil(t, err)
+	genesis, err := spec.GenesisBlock()
+	assert.Nil(t, err)
+	assert.Equal(t, specHash[:], genesis.Header.Seal.Extra[:])
+	assert.Equal(t, int64(1700000000), genesis.Header.Timestamp)
+
+	// Same spec, same genesis
+	other, err := Parse([]byte(testSpec))
+	assert.Nil(t, err)
+	otherGenesis, err := other.GenesisBlock()
+	assert.Nil(t, err)
+	assert.Equal(t, genesis.Header, otherGenesis.Header)
+
+	// Any change to the spec changes the genesis
+	genesisHash, err := genesis.Hash()
+	assert.Nil(t, err)
+	changes := []func(spec *Spec){
+		func(spec *Spec) { spec.NetworkId++ },
+		func(spec *Spec) { spec.Genesis.Allocations["bob"]++ },
+		func(spec *Spec) { spec.Reward.InitialReward++ },
+		func(spec *Spec) { spec.Consensus.MinStake++ },
+		func(spec *Spec) { spec.Ledger = LedgerUTXO },
+	}
+	for _, change := range changes {
+		changed, err := Parse([]byte(testSpec))
+		assert.Nil(t, err)
+		change(changed)
+
+		changedGenesis, err := changed.GenesisBlock()
+		assert.Nil(t, err)
+		changedHash, err := changedGenesis.Hash()
+		assert.Nil(t, err)
+		assert.NotEqual(t, genesisHash, changedHash)
+	}
+}
+
+func TestNewBlockChain(t *testing.T) {
+	spec, err := Parse([]byte(testSpec))
+	assert.Nil(t, err)
+
+	ledger := currency.NewMemoryLedgerState()
+	bc, err := spec.NewBlockChain(ledger, currency.DefaultChainConfig())
+	assert.Nil(t, err)
+
+	genesis, err := spec.GenesisBlock()
+	assert.Nil(t, err)
+	genesisHash, err := genesis.Hash()
+	assert.Nil(t, err)
+	bcGenesisHash, err := bc.GetGenesis().Hash()
+	assert.Nil(t, err)
+	assert.Equal(t, genesisHash, bcGenesisHash)
+
+	balance, err := ledger.GetBalance("alice")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(600), balance)
+	balance, err = ledger.GetBalance("bob")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(500), balance)
+	assert.Equal(t, float64(400), ledger.GetStake("v1"))
+
+	privKey := crypto.GeneratePrivateKey()
+	registration, err := currency.NewRegistrationTransaction("carol", spec.Genesis.InitialBalance).ToCoreTransaction()
+	assert.Nil(t, err)
+	assert.Nil(t, registration.Sign(privKey))
+
+	// The chain enforces the spec's reward schedule
+	block := core.NewBlockWithHeaderInfo(1, genesisHash)
+	block.AddTransaction(registration)
+	assert.NotNil(t, bc.AddBlock(block))
+
+	coinbase, err := spec.NewRewarder(privKey).GenerateReward("bob", block)
+	assert.Nil(t, err)
+	block.PrependTransaction(coinbase)
+	assert.Nil(t, bc.AddBlock(block))
+
+	balance, err = ledger.GetBalance("carol")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(50), balance)
+	balance, err = ledger.GetBalance("bob")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(520), balance)
+}
+
+func TestChainConfig(t *testing.T) {
+	spec, err := Parse([]byte(testSpec))
+	assert.Nil(t, err)
+	config, err := spec.ChainConfig()
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(currency.DefaultMaxReorgDepth), config.MaxReorgDepth)
+	assert.Empty(t, config.Checkpoints)
+	specHash, err := spec.Hash()
+	assert.Nil(t, err)
+
+	checkpoint := types.Hash{1, 2, 3}
+	depth := uint32(0)
+	spec.Chain = ChainRules{
+		MaxReorgDepth: &depth,
+		Checkpoints:   map[uint32]string{10: checkpoint.String()},
+	}
+	config, err = spec.ChainConfig()
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(0), config.MaxReorgDepth)
+	assert.Equal(t, map[uint32]types.Hash{10: checkpoint}, config.Checkpoints)
+
+	// Chain rules are node policy and keep the genesis
+	changedHash, err := spec.Hash()
+	assert.Nil(t, err)
+	assert.Equal(t, specHash, changedHash)
+
+	// A checkpoint the chain does not match rejects the block
+	ledger := spec.NewLedger()
+	spec.Chain.Checkpoints = map[uint32]string{1: checkpoint.String()}
+	config, err = spec.ChainConfig()
+	assert.Nil(t, err)
+	bc, err := spec.NewBlockChain(ledger, config)
+	assert.Nil(t, err)
+	genesisHash, err := bc.GetGenesis().Hash()
+	assert.Nil(t, err)
+	block := core.NewBlockWithHeaderInfo(1, genesisHash)
+	coinbase, err := spec.NewRewarder(crypto.GeneratePrivateKey()).GenerateReward("bob", block)
+	assert.Nil(t, err)
+	block.PrependTransaction(coinbase)
+	assert.NotNil(t, bc.AddBlock(block))
+}
+
+func TestNewLedger(t *testing.T) {
+	spec, err := Parse([]byte(testSpec))
+	assert.Nil(t, err)
+	_, ok := spec.NewLedger().(*currency.MemoryLedgerState)
+	assert.True(t, ok)
+
+	spec.Ledger = LedgerUTXO
+	ledger, ok := spec.NewLedger().(*currency.UTXOLedgerState)
+	assert.True(t, ok)
+
+	_, err = spec.NewBlockChain(ledger, currency.DefaultChainConfig())
+	assert.Nil(t, err)
+	balance, err := ledger.GetBalance("alice")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(600), balance)
+	assert.Equal(t, float64(400), ledger.GetStake("v1"))
+	assert.Equal(t, 1, len(ledger.GetUTXOs("alice")))
+}
+
+func TestNewEngine(t *testing.T) {
+	privKey := crypto.GeneratePrivateKey()
+
+	newEngine := func(spec *Spec) any {
+		ledger := currency.NewMemoryLedgerState()
+		bc, err := spec.NewBlockChain(ledger, currency.DefaultChainConfig())
+		assert.Nil(t, err)
+		engine, err := spec.NewEngine(ledger, bc, nil, privKey)
+		assert.Nil(t, err)
+		return engine
+	}
+
+	_, ok := newEngine(Default()).(*pow.PowEngine)
+	assert.True(t, ok)
+
+	spec, err := Parse([]byte(testSpec))
+	assert.Nil(t, err)
+	_, ok = newEngine(spec).(*pos.PosEngine)
+	assert.True(t, ok)
+
+	spec = Default()
+	spec.Consensus = Consensus{
+		Engine:      EnginePoa,
+		Authorities: []string{hex.EncodeToString(crypto.PublicKeyBytes(&privKey.PublicKey))},
+	}
+	assert.Nil(t, spec.Validate())
+	_, ok = newEngine(spec).(*poa.PoaEngine)
+	assert.True(t, ok)
+}
@@ -0,0 +1,96 @@
+package chainspec
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/currency"
+	"github.com/tusharjoshi4531/block-chain.git/poa"
+	"github.com/tusharjoshi4531/block-chain.git/pos"
+	"github.com/tusharjoshi4531/block-chain.git/pow"
+	"github.com/tusharjoshi4531/block-chain.git/prot"
+)
+
+// NewLedger builds an empty ledger of the spec's ledger model.
+func (spec *Spec) NewLedger() currency.LedgerState {
+	if spec.Ledger == LedgerUTXO {
+		return currency.NewUTXOLedgerState()
+	}
+	return currency.NewMemoryLedgerState()
+}
+
+// ChainConfig is the default chain config with the spec's reorg depth and
+// checkpoints.
+func (spec *Spec) ChainConfig() (currency.ChainConfig, error) {
+	config := currency.DefaultChainConfig()
+	if spec.Chain.MaxReorgDepth != nil {
+		config.MaxReorgDepth = *spec.Chain.MaxReorgDepth
+	}
+	checkpoints, err := spec.CheckpointHashes()
+	if err != nil {
+		return currency.ChainConfig{}, err
+	}
+	config.Checkpoints = checkpoints
+	return config, nil
+}
+
+// NewBlockChain builds a ledger chain on the spec's genesis that enforces the
+// spec's reward schedule, and credits the genesis allocations and stakes to
+// the ledger.
+func (spec *Spec) NewBlockChain(ledger currency.LedgerState, config currency.ChainConfig) (*currency.BlockChain, error) {
+	genesis, err := spec.GenesisBlock()
+	if err != nil {
+		return nil, err
+	}
+	config.Genesis = genesis
+	schedule := spec.RewardSchedule()
+	config.Reward = &schedule
+
+	for wallet, amount := range spec.Genesis.Allocations {
+		if err := ledger.AddWallet(wallet, amount); err != nil {
+			return nil, err
+		}
+	}
+	for _, stake := range spec.Genesis.Stakes {
+		stakeTx := currency.NewStakeTransaction(stake.Wallet, stake.Validator, stake.Amount)
+		if err := ledger.CommitTransaciton(stakeTx); err != nil {
+			return nil, fmt.Errorf("genesis stake from (%s): %s", stake.Wallet, err)
+		}
+	}
+
+	return currency.NewBlockChainWithConfig(ledger, spec.Genesis.InitialBalance, config), nil
+}
+
+func (spec *Spec) RewardSchedule() currency.RewardSchedule {
+	return currency.NewRewardSchedule(spec.Reward.InitialReward, spec.Reward.HalvingInterval)
+}
+
+func (spec *Spec) NewRewarder(privKey *ecdsa.PrivateKey) *currency.Rewarder {
+	return currency.NewRewarder(privKey, spec.Reward.InitialReward, spec.Reward.HalvingInterval)
+}
+
+// NewEngine builds the consensus engine named by the spec.
+func (spec *Spec) NewEngine(
+	ledger currency.LedgerState,
+	bc core.BlockChain,
+	txPool core.TransactionPool,
+	privKey *ecdsa.PrivateKey,
+) (prot.Engine, error) {
+	rewarder := spec.NewRewarder(privKey)
+
+	switch spec.Consensus.Engine {
+	case EnginePow:
+		return pow.NewPowEngine(spec.Consensus.Difficulty, bc, txPool, privKey, rewarder), nil
+	case EnginePoa:
+		authorities, err := spec.AuthorityKeys()
+		if err != nil {
+			return nil, err
+		}
+		return poa.NewPoaEngine(authorities, bc, txPool, privKey, rewarder), nil
+	case EnginePos:
+		return pos.NewPosEngine(ledger, bc, txPool, privKey, rewarder, spec.Consensus.MinStake), nil
+	default:
+		return nil, fmt.Errorf("unknown consensus engine (%s)", spec.Consensus.Engine)
+	}
+}
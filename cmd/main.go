@@ -1,41 +1,89 @@
 package main
 
 import (
+	"crypto/ecdsa"
+	"flag"
 	"fmt"
 	"log"
 	"os"
 
 	bcnetwork "github.com/tusharjoshi4531/block-chain.git/bc_network"
+	"github.com/tusharjoshi4531/block-chain.git/chainspec"
 	"github.com/tusharjoshi4531/block-chain.git/core"
 	"github.com/tusharjoshi4531/block-chain.git/crypto"
-	"github.com/tusharjoshi4531/block-chain.git/currency"
+	"github.com/tusharjoshi4531/block-chain.git/indexer"
+	"github.com/tusharjoshi4531/block-chain.git/keystore"
 	"github.com/tusharjoshi4531/block-chain.git/network"
+	"github.com/tusharjoshi4531/block-chain.git/rpc"
 	"github.com/tusharjoshi4531/block-chain.git/shell"
 	"github.com/tusharjoshi4531/block-chain.git/tcp"
 )
 
+// passphraseEnv names the environment variable holding the keystore
+// passphrase.
+const passphraseEnv = "BC_KEYSTORE_PASSPHRASE"
+
 func main() {
-	fmt.Println(os.Args)
-	addr, peers := parseArgs(os.Args)
+	rpcAddr := flag.String("rpc", "", "listen address of the JSON-RPC server (disabled when empty)")
+	explorer := flag.Bool("explorer", false, "index transactions and serve explorer endpoints on the rpc server")
+	specPath := flag.String("chainspec", "", "path of the JSON chain spec (built-in default when empty)")
+	keystoreDir := flag.String("keystore", "", "directory of the encrypted keystore holding the node key (fresh key on every start when empty); the passphrase is read from "+passphraseEnv)
+	keyName := flag.String("key", "node", "name of the node key in the keystore; created on first start")
+	importPath := flag.String("import-key", "", "store the encrypted key file at this path in the keystore under -key and exit")
+	exportPath := flag.String("export-key", "", "write the encrypted key file stored under -key to this path and exit")
+	flag.Parse()
+
+	if *importPath != "" || *exportPath != "" {
+		if err := transferKey(*keystoreDir, *keyName, *importPath, *exportPath); err != nil {
+			log.Fatalf("Couldn't transfer key (%s), ERROR: (%s)", *keyName, err.Error())
+		}
+		return
+	}
+
+	addr, peers := parseArgs(flag.Args())
 
 	fmt.Println(addr)
 
-	ledger := currency.NewMemoryLedgerState()
-	bc := currency.NewBlockChain(ledger, 10000)
+	spec := chainspec.Default()
+	if *specPath != "" {
+		var err error
+		if spec, err = chainspec.Load(*specPath); err != nil {
+			log.Fatalf("Couldn't load chain spec (%s), ERROR: (%s)", *specPath, err.Error())
+		}
+	}
+
+	chainConfig, err := spec.ChainConfig()
+	if err != nil {
+		log.Fatalf("Couldn't read chain rules from spec, ERROR: (%s)", err.Error())
+	}
+	ledger := spec.NewLedger()
+	bc, err := spec.NewBlockChain(ledger, chainConfig)
+	if err != nil {
+		log.Fatalf("Couldn't build chain from spec, ERROR: (%s)", err.Error())
+	}
 	txPool := core.NewDefaultTransactionPool()
-	privKey := crypto.GeneratePrivateKey()
+	privKey, err := loadNodeKey(*keystoreDir, *keyName)
+	if err != nil {
+		log.Fatalf("Couldn't load node key (%s), ERROR: (%s)", *keyName, err.Error())
+	}
 	bcTransport := bcnetwork.NewDefaultBlockChainTransport(
 		network.NewDefaultTransport(addr),
 		bc,
 		txPool,
 	)
 
-	server := tcp.NewTcpServer(
+	engine, err := spec.NewEngine(ledger, bc, txPool, privKey)
+	if err != nil {
+		log.Fatalf("Couldn't build consensus engine, ERROR: (%s)", err.Error())
+	}
+
+	server := tcp.NewTcpServerWithEngine(
 		ledger,
 		bc,
 		txPool,
 		privKey,
 		bcTransport,
+		engine,
 	)
 
 	for _, peer := range peers {
@@ -44,16 +92,77 @@ func main() {
 		}
 	}
 
+	if *rpcAddr != "" {
+		rpcServer := rpc.NewServer(*rpcAddr, server)
+		if *explorer {
+			ix := indexer.NewIndexer(bc)
+			if err := ix.Start(); err != nil {
+				log.Fatalf("Couldn't start indexer, ERROR: (%s)", err.Error())
+			}
+			defer ix.Stop()
+			rpcServer.EnableExplorer(ix)
+		}
+		if err := rpcServer.Listen(); err != nil {
+			log.Fatalf("Couldn't start rpc server at (%s), ERROR: (%s)", *rpcAddr, err.Error())
+		}
+		defer rpcServer.Close()
+	}
+
 	sh := shell.NewShellInterface(server)
 
 	sh.Run()
 }
 
+func loadNodeKey(keystoreDir string, keyName string) (*ecdsa.PrivateKey, error) {
+	if keystoreDir == "" {
+		return crypto.GeneratePrivateKey(), nil
+	}
+	passphrase := os.Getenv(passphraseEnv)
+	if passphrase == "" {
+		return nil, fmt.Errorf("keystore passphrase is not set in (%s)", passphraseEnv)
+	}
+	ks, err := keystore.NewKeystore(keystoreDir)
+	if err != nil {
+		return nil, err
+	}
+	return ks.LoadOrCreateKey(keyName, passphrase)
+}
+
+// transferKey imports and exports key files as they are stored, still
+// encrypted, so it needs no passphrase.
+func transferKey(keystoreDir string, keyName string, importPath string, exportPath string) error {
+	if keystoreDir == "" {
+		return fmt.Errorf("key import and export need a keystore directory")
+	}
+	ks, err := keystore.NewKeystore(keystoreDir)
+	if err != nil {
+		return err
+	}
+
+	if importPath != "" {
+		data, err := os.ReadFile(importPath)
+		if err != nil {
+			return err
+		}
+		if err := ks.Import(keyName, data); err != nil {
+			return err
+		}
+	}
+	if exportPath != "" {
+		data, err := ks.Export(keyName)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(exportPath, data, 0o600)
+	}
+	return nil
+}
+
 func parseArgs(args []string) (string, []string) {
 	if len(args) < 1 {
 		panic("port not defined")
 	}
-	addr := args[1]
-	peers := args[2:]
+	addr := args[0]
+	peers := args[1:]
 	return addr, peers
 }
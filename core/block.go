@@ -6,6 +6,7 @@ import (
 	"crypto/sha256"
 	"fmt"
 	"io"
+	"sync/atomic"
 	"time"
 
 	"github.com/tusharjoshi4531/block-chain.git/crypto"
@@ -13,7 +14,26 @@ import (
 	"github.com/tusharjoshi4531/block-chain.git/util"
 )
 
-type Nonce interface{}
+// EncodingVersion is written at the start of every canonically encoded
+// header, block and transaction.
+const EncodingVersion uint8 = 1
+
+const SealExtraSize = 32
+
+// MaxBlockTimeDrift is how far past the local clock a block may be
+// stamped.
+const MaxBlockTimeDrift = 2 * time.Minute
+
+// Seal is the fixed-size consensus field of a header. Core only hashes and
+// encodes it; consensus engines decide what Nonce and Extra mean.
+type Seal struct {
+	Nonce uint64
+	Extra [SealExtraSize]byte
+}
+
+func NewSeal(nonce uint64) Seal {
+	return Seal{Nonce: nonce}
+}
 
 type BlockHeader struct {
 	Version       uint32
@@ -21,11 +41,22 @@ type BlockHeader struct {
 	PrevBlockHash types.Hash
 	Timestamp     int64
 	Height        uint32
-	Nonce         Nonce
+	Seal          Seal
 }
 
+// Encode writes the canonical header: encoding version, Version, DataHash,
+// PrevBlockHash, Timestamp, Height and the seal.
 func (header *BlockHeader) Encode(w io.Writer) error {
-	return util.EncoderGobEncodables(w, header)
+	cw := util.NewCanonicalWriter(w)
+	cw.WriteUint8(EncodingVersion)
+	cw.WriteUint32(header.Version)
+	cw.WriteFixed(header.DataHash[:])
+	cw.WriteFixed(header.PrevBlockHash[:])
+	cw.WriteInt64(header.Timestamp)
+	cw.WriteUint32(header.Height)
+	cw.WriteUint64(header.Seal.Nonce)
+	cw.WriteFixed(header.Seal.Extra[:])
+	return cw.Err()
 }
 
 func (header *BlockHeader) Bytes() ([]byte, error) {
@@ -37,7 +68,16 @@ func (header *BlockHeader) Bytes() ([]byte, error) {
 }
 
 func (header *BlockHeader) Decode(r io.Reader) error {
-	return util.DecodeGobDecodable(r, header)
+	cr := util.NewCanonicalReader(r)
+	cr.ReadVersion(EncodingVersion)
+	header.Version = cr.ReadUint32()
+	cr.ReadFixed(header.DataHash[:])
+	cr.ReadFixed(header.PrevBlockHash[:])
+	header.Timestamp = cr.ReadInt64()
+	header.Height = cr.ReadUint32()
+	header.Seal.Nonce = cr.ReadUint64()
+	cr.ReadFixed(header.Seal.Extra[:])
+	return cr.Err()
 }
 
 func (header *BlockHeader) Hash() (types.Hash, error) {
@@ -53,8 +93,13 @@ type Block struct {
 	Transactions []*Transaction
 	Validator    *ecdsa.PublicKey
 	Signature    *crypto.Signature
+	Certificate  *CommitCertificate
 
-	hash types.Hash
+	// hash caches Hash so that event subscribers in other goroutines can
+	// read it; it is cleared whenever the header changes through SetSeal or
+	// Decode. The first Hash also fills in DataHash, so blocks are hashed
+	// before they are shared, as AddBlock does.
+	hash atomic.Pointer[types.Hash]
 }
 
 func NewBlock() *Block {
@@ -65,7 +110,7 @@ func NewBlock() *Block {
 			PrevBlockHash: types.Hash{},
 			Timestamp:     time.Now().UnixNano(),
 			Height:        0,
-			Nonce:         0,
+			Seal:          Seal{},
 		},
 		Transactions: []*Transaction{},
 		Validator:    &ecdsa.PublicKey{},
@@ -80,14 +125,34 @@ func NewBlockWithHeaderInfo(height uint32, prevBlockHash types.Hash) *Block {
 	return block
 }
 
-func (block *Block) SetNonce(nonce Nonce) {
-	block.Header.Nonce = nonce
-	block.hash = types.Hash{}
+// NextBlockTimestamp is the timestamp of a new block on parent: the local
+// time, or just after the parent when the parent is stamped later.
+func NextBlockTimestamp(parent *Block) int64 {
+	return max(time.Now().UnixNano(), parent.Header.Timestamp+1)
+}
+
+// CheckBlockTimestamp requires a block to be stamped after its parent and at
+// most MaxBlockTimeDrift past now. Time locks and lock time scripts read
+// the timestamp, so producers must not be free to pick it.
+func CheckBlockTimestamp(block, parent *Block, now time.Time) error {
+	timestamp := block.Header.Timestamp
+	if timestamp <= parent.Header.Timestamp {
+		return fmt.Errorf("block at height (%d) is stamped (%d), not after its parent (%d)", block.Header.Height, timestamp, parent.Header.Timestamp)
+	}
+	if limit := now.Add(MaxBlockTimeDrift).UnixNano(); timestamp > limit {
+		return fmt.Errorf("block at height (%d) is stamped (%d), more than (%s) in the future", block.Header.Height, timestamp, MaxBlockTimeDrift)
+	}
+	return nil
+}
+
+func (block *Block) SetSeal(seal Seal) {
+	block.Header.Seal = seal
+	block.hash.Store(nil)
 }
 
 func (block *Block) Hash() (types.Hash, error) {
-	if !block.hash.IsZero() {
-		return block.hash, nil
+	if hash := block.hash.Load(); hash != nil {
+		return *hash, nil
 	}
 
 	// Hash data
@@ -103,8 +168,8 @@ func (block *Block) Hash() (types.Hash, error) {
 		return types.Hash{}, err
 	}
 
-	block.hash = hash
-	return block.hash, nil
+	block.hash.Store(&hash)
+	return hash, nil
 }
 
 func (block *Block) EncodeData(w io.Writer) error {
@@ -134,16 +199,21 @@ func (block *Block) AddTransaction(transaction *Transaction) {
 	block.Transactions = append(block.Transactions, transaction)
 }
 
-func (block *Block) Sign(privateKey *ecdsa.PrivateKey) error {
-	// Hash block
-	block.Hash()
+// PrependTransaction puts transaction ahead of the block's other
+// transactions, where the coinbase goes.
+func (block *Block) PrependTransaction(transaction *Transaction) {
+	block.Transactions = append([]*Transaction{transaction}, block.Transactions...)
+}
 
-	headerBytes, err := block.Header.Bytes()
+// Sign signs the block hash, which commits to the header and through
+// DataHash to the transactions.
+func (block *Block) Sign(privateKey *ecdsa.PrivateKey) error {
+	hash, err := block.Hash()
 	if err != nil {
 		return err
 	}
 
-	sig, err := crypto.SignBytes(privateKey, headerBytes)
+	sig, err := crypto.SignBytes(privateKey, hash[:])
 	if err != nil {
 		return err
 	}
@@ -154,24 +224,40 @@ func (block *Block) Sign(privateKey *ecdsa.PrivateKey) error {
 	return nil
 }
 
+// Verify checks that DataHash matches the transactions and that the
+// signature covers a freshly computed header hash, so that edits made after
+// hashing are not hidden by the cached hash.
 func (block *Block) Verify() error {
-	if block.Signature == nil {
+	if block.Signature == nil || block.Signature.IsNil() {
 		return fmt.Errorf("block has no signature")
 	}
 
-	headerBytes, err := block.Header.Bytes()
+	dataHash, err := block.DataHash()
+	if err != nil {
+		return err
+	}
+	if dataHash != block.Header.DataHash {
+		return fmt.Errorf("block data hash (%s) does not match its transactions (%s)", block.Header.DataHash.String(), dataHash.String())
+	}
+
+	hash, err := block.Header.Hash()
 	if err != nil {
 		return err
 	}
 
-	if !block.Signature.Verify(block.Validator, headerBytes) {
-		return fmt.Errorf("incorrect sign in transaction")
+	if !block.Signature.Verify(block.Validator, hash[:]) {
+		return fmt.Errorf("incorrect sign in block")
 	}
 
 	return nil
 }
 
 func (block *Block) Encode(w io.Writer) error {
+	cw := util.NewCanonicalWriter(w)
+	cw.WriteUint8(EncodingVersion)
+	if err := cw.Err(); err != nil {
+		return err
+	}
 	if err := block.Header.Encode(w); err != nil {
 		return err
 	}
@@ -185,6 +271,13 @@ func (block *Block) Encode(w io.Writer) error {
 		return err
 	}
 
+	cw.WriteBool(block.Certificate != nil)
+	if err := cw.Err(); err != nil {
+		return err
+	}
+	if block.Certificate != nil {
+		return block.Certificate.Encode(w)
+	}
 	return nil
 }
 
@@ -193,6 +286,11 @@ func (block *Block) Bytes() ([]byte, error) {
 }
 
 func (block *Block) Decode(r io.Reader) error {
+	cr := util.NewCanonicalReader(r)
+	cr.ReadVersion(EncodingVersion)
+	if err := cr.Err(); err != nil {
+		return err
+	}
 	if err := block.Header.Decode(r); err != nil {
 		return err
 	}
@@ -209,6 +307,20 @@ func (block *Block) Decode(r io.Reader) error {
 	if err := block.Signature.Decode(r); err != nil {
 		return err
 	}
+
+	hasCertificate := cr.ReadBool()
+	if err := cr.Err(); err != nil {
+		return err
+	}
+	block.Certificate = nil
+	if hasCertificate {
+		block.Certificate = &CommitCertificate{}
+		if err := block.Certificate.Decode(r); err != nil {
+			return err
+		}
+	}
+
+	block.hash.Store(nil)
 	return nil
 }
 
@@ -2,43 +2,84 @@ package core
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/tusharjoshi4531/block-chain.git/types"
+	"github.com/tusharjoshi4531/block-chain.git/util"
 )
 
 type BlockChain interface {
-	AddWallet(string) error
 	AddBlock(*Block) error
 	GetHeighestBlock() *Block
 	GetGenesis() *Block
 	GetPrevBlock(*Block) (*Block, error)
 	GetBlockWithHash(types.Hash) (*Block, error)
+	GetBlockAtHeight(height uint32) (*Block, error)
 	HasTransactionInChain(transactionHash types.Hash, blockHash types.Hash) error
 	GetTransactionsInChain(blockHash types.Hash) ([]*Transaction, error)
 	Height() uint32
+	Subscribe(bufferSize int, eventTypes ...EventType) *Subscription
+	Finalize(blockHash types.Hash) error
+	GetFinalizedBlock() *Block
 
 	GetBlockHashes() []types.Hash
 }
 
+// TransactionFilter is implemented by chains that keep state on top of
+// their blocks, such as a ledger, and can tell which transactions of a new
+// block would apply on its parent.
+type TransactionFilter interface {
+	FilterTransactions(block *Block) []*Transaction
+}
+
+// FilterTransactions drops the transactions of block that blockChain would
+// refuse, so that miners do not build blocks the chain rejects.
+func FilterTransactions(blockChain BlockChain, block *Block) {
+	if filter, ok := blockChain.(TransactionFilter); ok {
+		block.Transactions = filter.FilterTransactions(block)
+	}
+}
+
+// TipChangeHook is called with the blocks leaving the main chain (tip
+// first) and the blocks joining it (ancestor first) whenever the tip moves.
+// If it fails the new block is dropped and the tip stays where it was, so the
+// hook must leave its own state unchanged on error.
+type TipChangeHook func(disconnected, connected []*Block) error
+
 type DefaultBlockChain struct {
 	height         uint32
 	blocks         map[types.Hash]*Block
 	blocksAtHeight map[uint32][]*Block
 	genesis        *Block
 	heighestBlock  *Block
+	finalizedBlock *Block
+	tipChangeHook  TipChangeHook
+	events         *EventFeed
 }
 
 func NewDefaultBlockChain() *DefaultBlockChain {
+	return NewDefaultBlockChainWithGenesis(NewGenesisBlock(0, Seal{}))
+}
+
+// NewGenesisBlock builds an empty block at height 0. Chains built from
+// different specs put distinct seals in their genesis so their hashes never
+// match.
+func NewGenesisBlock(timestamp int64, seal Seal) *Block {
+	genesisBlock := NewBlock()
+	genesisBlock.Header.Height = 0
+	genesisBlock.Header.Timestamp = timestamp
+	genesisBlock.Header.Seal = seal
+	return genesisBlock
+}
+
+func NewDefaultBlockChainWithGenesis(genesisBlock *Block) *DefaultBlockChain {
 	chain := &DefaultBlockChain{
 		height:         0,
 		blocks:         make(map[types.Hash]*Block),
 		blocksAtHeight: make(map[uint32][]*Block),
 		heighestBlock:  nil,
+		events:         NewEventFeed(),
 	}
-	// TODO: Add genesis block
-	genesisBlock := NewBlock()
-	genesisBlock.Header.Height = 0
-	genesisBlock.Header.Timestamp = 0
 	hash, err := genesisBlock.Hash()
 	if err != nil {
 		panic(err)
@@ -50,10 +91,6 @@ func NewDefaultBlockChain() *DefaultBlockChain {
 	return chain
 }
 
-func (blockChain *DefaultBlockChain) AddWallet(wallet string) error {
-	return nil
-}
-
 func (blockChain *DefaultBlockChain) GetGenesis() *Block {
 	return blockChain.genesis
 }
@@ -75,11 +112,210 @@ func (blockChain *DefaultBlockChain) AddBlock(block *Block) error {
 		return fmt.Errorf("block (%s) has incorrect height; Required = (%d); Founc = (%d)", blockHash.String(), prevHeight+1, blockHeight)
 	}
 
+	if err := CheckBlockTimestamp(block, prevBlock, time.Now()); err != nil {
+		return err
+	}
+
+	if err := blockChain.checkFinality(block); err != nil {
+		return err
+	}
+
+	prevTip := blockChain.heighestBlock
+	prevChainHeight := blockChain.height
 	blockChain.addBlockWithoutValidation(blockHash, block)
 
+	if blockChain.heighestBlock != prevTip {
+		if err := blockChain.changeTip(prevTip, block); err != nil {
+			// Keep the tip where the ledger is
+			blockChain.removeBlock(blockHash, block)
+			blockChain.height = prevChainHeight
+			blockChain.heighestBlock = prevTip
+			return err
+		}
+	}
+	return nil
+}
+
+// Finalize marks a main chain block as final. Blocks that do not descend
+// from the finalized block are rejected afterwards, so the chain can no
+// longer reorganize below it.
+func (blockChain *DefaultBlockChain) Finalize(blockHash types.Hash) error {
+	block, err := blockChain.GetBlockWithHash(blockHash)
+	if err != nil {
+		return err
+	}
+
+	mainBlock, err := blockChain.GetBlockAtHeight(block.Header.Height)
+	if err != nil {
+		return err
+	}
+	if mainBlock != block {
+		return fmt.Errorf("block (%s) is not in the main chain", blockHash.String())
+	}
+
+	if finalized := blockChain.finalizedBlock; finalized != nil {
+		if finalized == block {
+			return nil
+		}
+		if finalized.Header.Height > block.Header.Height {
+			return fmt.Errorf("block (%s) is below the finalized height (%d)", blockHash.String(), finalized.Header.Height)
+		}
+	}
+
+	blockChain.finalizedBlock = block
+	blockChain.events.Publish(Event{Type: EventBlockFinalized, Block: block})
 	return nil
 }
 
+// GetFinalizedBlock returns the latest finalized block, or the genesis block
+// when nothing has been finalized.
+func (blockChain *DefaultBlockChain) GetFinalizedBlock() *Block {
+	if blockChain.finalizedBlock == nil {
+		return blockChain.genesis
+	}
+	return blockChain.finalizedBlock
+}
+
+func (blockChain *DefaultBlockChain) checkFinality(block *Block) error {
+	finalized := blockChain.finalizedBlock
+	if finalized == nil {
+		return nil
+	}
+
+	finalizedHash, err := finalized.Hash()
+	if err != nil {
+		return err
+	}
+	if block.Header.Height <= finalized.Header.Height {
+		return fmt.Errorf("block at height (%d) is at or below the finalized block (%s)", block.Header.Height, finalizedHash.String())
+	}
+
+	ancestor := block
+	for ancestor.Header.Height > finalized.Header.Height {
+		if ancestor, err = blockChain.GetPrevBlock(ancestor); err != nil {
+			return err
+		}
+	}
+	if ancestor != finalized {
+		return fmt.Errorf("block at height (%d) does not descend from the finalized block (%s)", block.Header.Height, finalizedHash.String())
+	}
+	return nil
+}
+
+func (blockChain *DefaultBlockChain) SetTipChangeHook(hook TipChangeHook) {
+	blockChain.tipChangeHook = hook
+}
+
+func (blockChain *DefaultBlockChain) Events() *EventFeed {
+	return blockChain.events
+}
+
+func (blockChain *DefaultBlockChain) Subscribe(bufferSize int, eventTypes ...EventType) *Subscription {
+	return blockChain.events.Subscribe(bufferSize, eventTypes...)
+}
+
+func (blockChain *DefaultBlockChain) changeTip(prevTip, newTip *Block) error {
+	prevHash, err := prevTip.Hash()
+	if err != nil {
+		return err
+	}
+	newHash, err := newTip.Hash()
+	if err != nil {
+		return err
+	}
+
+	disconnected, connected, err := blockChain.ReorgPath(prevHash, newHash)
+	if err != nil {
+		return err
+	}
+
+	if blockChain.tipChangeHook != nil {
+		if err := blockChain.tipChangeHook(disconnected, connected); err != nil {
+			return err
+		}
+	}
+
+	for _, block := range disconnected {
+		blockChain.events.Publish(Event{Type: EventBlockDisconnected, Block: block})
+	}
+	for _, block := range connected {
+		blockChain.events.Publish(Event{Type: EventBlockConnected, Block: block})
+		for _, transaction := range block.Transactions {
+			blockChain.events.Publish(Event{Type: EventTransactionConfirmed, Block: block, Transaction: transaction})
+		}
+	}
+	blockChain.events.Publish(Event{
+		Type:       EventTipChanged,
+		Block:      newTip,
+		Ancestor:   connected[0].Header.PrevBlockHash,
+		ReorgDepth: uint32(len(disconnected)),
+	})
+
+	return nil
+}
+
+// CommonAncestor returns the most recent block shared by the branches ending
+// at the two given hashes.
+func (blockChain *DefaultBlockChain) CommonAncestor(hashA, hashB types.Hash) (*Block, error) {
+	blockA, err := blockChain.GetBlockWithHash(hashA)
+	if err != nil {
+		return nil, err
+	}
+	blockB, err := blockChain.GetBlockWithHash(hashB)
+	if err != nil {
+		return nil, err
+	}
+
+	for blockA != blockB {
+		if blockA.Header.Height >= blockB.Header.Height {
+			if blockA, err = blockChain.GetPrevBlock(blockA); err != nil {
+				return nil, err
+			}
+		} else {
+			if blockB, err = blockChain.GetPrevBlock(blockB); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return blockA, nil
+}
+
+// ReorgPath returns the blocks to disconnect when moving the tip from
+// fromHash to toHash (newest first) and the blocks to connect (oldest first).
+func (blockChain *DefaultBlockChain) ReorgPath(fromHash, toHash types.Hash) ([]*Block, []*Block, error) {
+	ancestor, err := blockChain.CommonAncestor(fromHash, toHash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	disconnected, err := blockChain.pathToAncestor(fromHash, ancestor)
+	if err != nil {
+		return nil, nil, err
+	}
+	connected, err := blockChain.pathToAncestor(toHash, ancestor)
+	if err != nil {
+		return nil, nil, err
+	}
+	util.RevereseSlice(connected)
+
+	return disconnected, connected, nil
+}
+
+func (blockChain *DefaultBlockChain) pathToAncestor(hash types.Hash, ancestor *Block) ([]*Block, error) {
+	path := make([]*Block, 0)
+	currBlock, err := blockChain.GetBlockWithHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	for currBlock != ancestor {
+		path = append(path, currBlock)
+		if currBlock, err = blockChain.GetPrevBlock(currBlock); err != nil {
+			return nil, err
+		}
+	}
+	return path, nil
+}
+
 func (blockChain *DefaultBlockChain) GetHeighestBlock() *Block {
 	if blockChain.heighestBlock == nil {
 		panic("No heighest block exists in blockchain")
@@ -99,6 +335,23 @@ func (blockChain *DefaultBlockChain) GetBlockWithHash(hash types.Hash) (*Block,
 	return block, nil
 }
 
+// GetBlockAtHeight returns the block at the given height on the main chain.
+func (blockChain *DefaultBlockChain) GetBlockAtHeight(height uint32) (*Block, error) {
+	if height > blockChain.height {
+		return nil, fmt.Errorf("height (%d) is above the chain height (%d)", height, blockChain.height)
+	}
+
+	currBlock := blockChain.GetHeighestBlock()
+	for currBlock.Header.Height > height {
+		prevBlock, err := blockChain.GetPrevBlock(currBlock)
+		if err != nil {
+			return nil, err
+		}
+		currBlock = prevBlock
+	}
+	return currBlock, nil
+}
+
 func (blockChain *DefaultBlockChain) Height() uint32 {
 	return blockChain.height
 }
@@ -162,6 +415,20 @@ func (blockChain *DefaultBlockChain) addBlockWithoutValidation(blockHash types.H
 	}
 }
 
+// removeBlock drops a block that never became part of the main chain.
+func (blockChain *DefaultBlockChain) removeBlock(blockHash types.Hash, block *Block) {
+	delete(blockChain.blocks, blockHash)
+
+	blockHeight := block.Header.Height
+	siblings := blockChain.blocksAtHeight[blockHeight]
+	for i, sibling := range siblings {
+		if sibling == block {
+			blockChain.blocksAtHeight[blockHeight] = append(siblings[:i:i], siblings[i+1:]...)
+			break
+		}
+	}
+}
+
 func (blockChain *DefaultBlockChain) GetBlockHashes() []types.Hash {
 	chain := make([]types.Hash, 0, len(blockChain.blocks))
 	for hash := range blockChain.blocks {
@@ -188,5 +455,7 @@ func (blockChain *DefaultBlockChain) Copy() *DefaultBlockChain {
 		blocksAtHeight: newBlocksAtHeight,
 		genesis:        blockChain.genesis,
 		heighestBlock:  blockChain.heighestBlock,
+		finalizedBlock: blockChain.finalizedBlock,
+		events:         NewEventFeed(),
 	}
 }
@@ -1,8 +1,10 @@
 package core
 
 import (
+	"fmt"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/tusharjoshi4531/block-chain.git/crypto"
@@ -74,6 +76,21 @@ func TestLongestChain(t *testing.T) {
 	heighestBlock := bc.GetHeighestBlock()
 
 	assert.Equal(t, currBlock, heighestBlock)
+
+	block, err := bc.GetBlockAtHeight(uint32(lenBlocks))
+	assert.Nil(t, err)
+	assert.Equal(t, heighestBlock, block)
+
+	block, err = bc.GetBlockAtHeight(0)
+	assert.Nil(t, err)
+	assert.Equal(t, bc.GetGenesis(), block)
+
+	block, err = bc.GetBlockAtHeight(1)
+	assert.Nil(t, err)
+	assert.Equal(t, block.Header.PrevBlockHash, *bc.GetGenesis().hash.Load())
+
+	_, err = bc.GetBlockAtHeight(uint32(lenBlocks + 1))
+	assert.NotNil(t, err)
 }
 
 func TestHasTransaction(t *testing.T) {
@@ -115,6 +132,194 @@ func TestHasTransaction(t *testing.T) {
 	}
 }
 
+func TestChainEvents(t *testing.T) {
+	bc := NewDefaultBlockChain()
+	sub := bc.Subscribe(64)
+
+	genesisHash, err := bc.GetGenesis().Hash()
+	assert.Nil(t, err)
+
+	tx1 := newSignedTransaction(t, []byte("Foo"))
+	blockA := newSignedBlock(t, 1, genesisHash, []*Transaction{tx1})
+	assert.Nil(t, bc.AddBlock(blockA))
+
+	event := <-sub.Events()
+	assert.Equal(t, EventBlockConnected, event.Type)
+	assert.Equal(t, blockA, event.Block)
+	event = <-sub.Events()
+	assert.Equal(t, EventTransactionConfirmed, event.Type)
+	assert.Equal(t, tx1, event.Transaction)
+	event = <-sub.Events()
+	assert.Equal(t, EventTipChanged, event.Type)
+	assert.Equal(t, uint32(0), event.ReorgDepth)
+
+	// Fork at the same height takes over the tip
+	blockB1 := newSignedBlock(t, 1, genesisHash, []*Transaction{})
+	assert.Nil(t, bc.AddBlock(blockB1))
+	event = <-sub.Events()
+	assert.Equal(t, EventBlockDisconnected, event.Type)
+	assert.Equal(t, blockA, event.Block)
+	event = <-sub.Events()
+	assert.Equal(t, EventBlockConnected, event.Type)
+	assert.Equal(t, blockB1, event.Block)
+	event = <-sub.Events()
+	assert.Equal(t, EventTipChanged, event.Type)
+	assert.Equal(t, uint32(1), event.ReorgDepth)
+	assert.Equal(t, genesisHash, event.Ancestor)
+
+	blockB1Hash, err := blockB1.Hash()
+	assert.Nil(t, err)
+	blockB2 := newSignedBlock(t, 2, blockB1Hash, []*Transaction{})
+	assert.Nil(t, bc.AddBlock(blockB2))
+	event = <-sub.Events()
+	assert.Equal(t, EventBlockConnected, event.Type)
+	assert.Equal(t, blockB2, event.Block)
+	event = <-sub.Events()
+	assert.Equal(t, EventTipChanged, event.Type)
+
+	sub.Unsubscribe()
+	_, ok := <-sub.Events()
+	assert.False(t, ok)
+}
+
+func TestReorgPath(t *testing.T) {
+	bc := NewDefaultBlockChain()
+	sub := bc.Subscribe(64, EventBlockDisconnected, EventTipChanged)
+	defer sub.Unsubscribe()
+
+	genesisHash, err := bc.GetGenesis().Hash()
+	assert.Nil(t, err)
+
+	blockA := newSignedBlock(t, 1, genesisHash, []*Transaction{newSignedTransaction(t, []byte("A"))})
+	assert.Nil(t, bc.AddBlock(blockA))
+	blockAHash, err := blockA.Hash()
+	assert.Nil(t, err)
+
+	blockB1 := newSignedBlock(t, 1, genesisHash, []*Transaction{newSignedTransaction(t, []byte("B"))})
+	blockB1Hash, err := blockB1.Hash()
+	assert.Nil(t, err)
+	blockB2 := newSignedBlock(t, 2, blockB1Hash, []*Transaction{})
+	blockB2Hash, err := blockB2.Hash()
+	assert.Nil(t, err)
+	bc.addBlockWithoutValidation(blockB1Hash, blockB1)
+	bc.addBlockWithoutValidation(blockB2Hash, blockB2)
+
+	ancestor, err := bc.CommonAncestor(blockAHash, blockB2Hash)
+	assert.Nil(t, err)
+	assert.Equal(t, bc.GetGenesis(), ancestor)
+
+	disconnected, connected, err := bc.ReorgPath(blockAHash, blockB2Hash)
+	assert.Nil(t, err)
+	assert.Equal(t, []*Block{blockA}, disconnected)
+	assert.Equal(t, []*Block{blockB1, blockB2}, connected)
+}
+
+func TestTipChangeHookFailure(t *testing.T) {
+	bc := NewDefaultBlockChain()
+	genesisHash, err := bc.GetGenesis().Hash()
+	assert.Nil(t, err)
+
+	blockA := newSignedBlock(t, 1, genesisHash, []*Transaction{})
+	assert.Nil(t, bc.AddBlock(blockA))
+	blockAHash, err := blockA.Hash()
+	assert.Nil(t, err)
+
+	bc.SetTipChangeHook(func(disconnected, connected []*Block) error {
+		return fmt.Errorf("ledger refused block")
+	})
+	blockB := newSignedBlock(t, 2, blockAHash, []*Transaction{})
+	blockBHash, err := blockB.Hash()
+	assert.Nil(t, err)
+	assert.NotNil(t, bc.AddBlock(blockB))
+
+	// The tip stays where the hook left the state
+	assert.Equal(t, blockA, bc.GetHeighestBlock())
+	assert.Equal(t, uint32(1), bc.Height())
+	_, err = bc.GetBlockWithHash(blockBHash)
+	assert.NotNil(t, err)
+
+	bc.SetTipChangeHook(nil)
+	assert.Nil(t, bc.AddBlock(blockB))
+	assert.Equal(t, blockB, bc.GetHeighestBlock())
+}
+
+func TestBlockTimestamp(t *testing.T) {
+	bc := NewDefaultBlockChain()
+	genesisHash, err := bc.GetGenesis().Hash()
+	assert.Nil(t, err)
+
+	stamped := func(prevHash types.Hash, height uint32, timestamp int64) *Block {
+		block := NewBlockWithHeaderInfo(height, prevHash)
+		block.Header.Timestamp = timestamp
+		assert.Nil(t, block.Sign(crypto.GeneratePrivateKey()))
+		return block
+	}
+
+	// Blocks too far ahead of the local clock are refused
+	future := time.Now().Add(MaxBlockTimeDrift + time.Minute).UnixNano()
+	assert.NotNil(t, bc.AddBlock(stamped(genesisHash, 1, future)))
+
+	nearFuture := time.Now().Add(MaxBlockTimeDrift / 2).UnixNano()
+	blockA := stamped(genesisHash, 1, nearFuture)
+	assert.Nil(t, bc.AddBlock(blockA))
+	blockAHash, err := blockA.Hash()
+	assert.Nil(t, err)
+
+	// Children must be stamped after their parent
+	assert.NotNil(t, bc.AddBlock(stamped(blockAHash, 2, nearFuture)))
+	assert.NotNil(t, bc.AddBlock(stamped(blockAHash, 2, time.Now().UnixNano())))
+
+	next := NextBlockTimestamp(blockA)
+	assert.Equal(t, nearFuture+1, next)
+	assert.Nil(t, bc.AddBlock(stamped(blockAHash, 2, next)))
+	assert.Equal(t, uint32(2), bc.Height())
+}
+
+func TestFinalize(t *testing.T) {
+	bc := NewDefaultBlockChain()
+	sub := bc.Subscribe(8, EventBlockFinalized)
+	defer sub.Unsubscribe()
+
+	genesisHash, err := bc.GetGenesis().Hash()
+	assert.Nil(t, err)
+	assert.Equal(t, bc.GetGenesis(), bc.GetFinalizedBlock())
+
+	blockA1 := newSignedBlock(t, 1, genesisHash, []*Transaction{})
+	assert.Nil(t, bc.AddBlock(blockA1))
+	blockA1Hash, err := blockA1.Hash()
+	assert.Nil(t, err)
+	blockA2 := newSignedBlock(t, 2, blockA1Hash, []*Transaction{})
+	assert.Nil(t, bc.AddBlock(blockA2))
+	blockA2Hash, err := blockA2.Hash()
+	assert.Nil(t, err)
+
+	// Side chain blocks cannot be finalized
+	blockB1 := newSignedBlock(t, 1, genesisHash, []*Transaction{newSignedTransaction(t, []byte("B"))})
+	assert.Nil(t, bc.AddBlock(blockB1))
+	blockB1Hash, err := blockB1.Hash()
+	assert.Nil(t, err)
+	assert.NotNil(t, bc.Finalize(blockB1Hash))
+
+	assert.Nil(t, bc.Finalize(blockA1Hash))
+	event := <-sub.Events()
+	assert.Equal(t, blockA1, event.Block)
+	assert.Equal(t, blockA1, bc.GetFinalizedBlock())
+
+	// Forks at or below the finalized block are refused
+	assert.NotNil(t, bc.AddBlock(newSignedBlock(t, 1, genesisHash, []*Transaction{})))
+	blockB2 := newSignedBlock(t, 2, blockB1Hash, []*Transaction{})
+	assert.NotNil(t, bc.AddBlock(blockB2))
+	assert.Equal(t, blockA2, bc.GetHeighestBlock())
+
+	// Descendants are still accepted
+	assert.Nil(t, bc.AddBlock(newSignedBlock(t, 2, blockA1Hash, []*Transaction{newSignedTransaction(t, []byte("C"))})))
+	assert.Nil(t, bc.AddBlock(newSignedBlock(t, 3, blockA2Hash, []*Transaction{})))
+
+	assert.Nil(t, bc.Finalize(blockA2Hash))
+	assert.NotNil(t, bc.Finalize(blockA1Hash))
+	assert.Equal(t, blockA2, bc.GetFinalizedBlock())
+}
+
 func TestBlockChainNetwork(t *testing.T) {
 	ta := network.NewLocalTransport("A")
 	tb := network.NewLocalTransport("B")
@@ -2,6 +2,7 @@ package core
 
 import (
 	"bytes"
+	"encoding/hex"
 	"strconv"
 	"testing"
 
@@ -45,6 +46,35 @@ func TestSignBlock(t *testing.T) {
 	block.Validator = &otherPrivKey.PublicKey
 
 	assert.NotNil(t, block.Verify())
+
+	// Header edits after signing invalidate the signature
+	assert.Nil(t, block.Sign(privateKey))
+	block.Header.Timestamp++
+	assert.NotNil(t, block.Verify())
+
+	unsigned := NewBlock()
+	assert.NotNil(t, unsigned.Verify())
+}
+
+func TestVerifyTamperedTransactions(t *testing.T) {
+	block := NewBlock()
+	block.AddTransaction(newSignedTransaction(t, []byte("FOOO")))
+
+	privateKey := crypto.GeneratePrivateKey()
+	assert.Nil(t, block.Sign(privateKey))
+
+	blockBytes, err := block.Bytes()
+	assert.Nil(t, err)
+
+	// A relayer swaps the transactions but keeps the signed header
+	relayed := NewBlock()
+	assert.Nil(t, relayed.Decode(bytes.NewBuffer(blockBytes)))
+	assert.Nil(t, relayed.Verify())
+	relayed.Transactions = []*Transaction{newSignedTransaction(t, []byte("EVIL"))}
+	assert.NotNil(t, relayed.Verify())
+
+	relayed.Transactions = nil
+	assert.NotNil(t, relayed.Verify())
 }
 
 func TestEncodeDecodeBlock(t *testing.T) {
@@ -71,6 +101,24 @@ func TestEncodeDecodeBlock(t *testing.T) {
 	assert.Equal(t, block.Validator, block2.Validator)
 	assert.Equal(t, block.Signature, block2.Signature)
 	assert.Equal(t, block.Transactions, block2.Transactions)
+	assert.Nil(t, block2.Certificate)
+
+	blockHash, err := block.Hash()
+	assert.Nil(t, err)
+	sig, err := crypto.SignBytes(privateKey, blockHash[:])
+	assert.Nil(t, err)
+	block.Certificate = &CommitCertificate{
+		Height:    block.Header.Height,
+		Round:     2,
+		BlockHash: blockHash,
+		Votes:     []CommitVote{{Validator: &privateKey.PublicKey, Signature: sig}},
+	}
+
+	buf.Reset()
+	assert.Nil(t, block.Encode(buf))
+	block3 := NewBlock()
+	assert.Nil(t, block3.Decode(buf))
+	assert.Equal(t, block.Certificate, block3.Certificate)
 }
 
 func TestHastTransaction(t *testing.T) {
@@ -86,3 +134,37 @@ func TestHastTransaction(t *testing.T) {
 		assert.True(t, block.HasTranaction(txx[i].Hash()))
 	}
 }
+
+func TestBlockHeaderGoldenVector(t *testing.T) {
+	header := &BlockHeader{
+		Version:       1,
+		DataHash:      types.Hash{1},
+		PrevBlockHash: types.Hash{2},
+		Timestamp:     1700000000,
+		Height:        7,
+		Seal:          NewSeal(42),
+	}
+	header.Seal.Extra[0] = 0xff
+
+	headerBytes, err := header.Bytes()
+	assert.Nil(t, err)
+	assert.Equal(t,
+		"01"+"00000001"+
+			"0100000000000000000000000000000000000000000000000000000000000000"+
+			"0200000000000000000000000000000000000000000000000000000000000000"+
+			"000000006553f100"+"00000007"+"000000000000002a"+
+			"ff00000000000000000000000000000000000000000000000000000000000000",
+		hex.EncodeToString(headerBytes),
+	)
+
+	hash, err := header.Hash()
+	assert.Nil(t, err)
+	assert.Equal(t, "ebe10a2271adadd383524cc9b4ae01b910323fa07310ffac9318cc2de278284a", hash.String())
+
+	decoded := &BlockHeader{}
+	assert.Nil(t, decoded.Decode(bytes.NewBuffer(headerBytes)))
+	assert.Equal(t, header, decoded)
+
+	headerBytes[0] = EncodingVersion + 1
+	assert.NotNil(t, decoded.Decode(bytes.NewBuffer(headerBytes)))
+}
@@ -0,0 +1,76 @@
+package core
+
+import (
+	"crypto/ecdsa"
+	"io"
+
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+	"github.com/tusharjoshi4531/block-chain.git/types"
+	"github.com/tusharjoshi4531/block-chain.git/util"
+)
+
+type CommitVote struct {
+	Validator *ecdsa.PublicKey
+	Signature *crypto.Signature
+}
+
+// CommitCertificate records the precommits of a quorum of validators for a
+// block. It is attached after the block is signed and is not covered by the
+// block hash; the finality gadget that produced it decides whether it holds.
+type CommitCertificate struct {
+	Height    uint32
+	Round     uint32
+	BlockHash types.Hash
+	Votes     []CommitVote
+}
+
+func (cert *CommitCertificate) Encode(w io.Writer) error {
+	cw := util.NewCanonicalWriter(w)
+	cw.WriteUint8(EncodingVersion)
+	cw.WriteUint32(cert.Height)
+	cw.WriteUint32(cert.Round)
+	cw.WriteFixed(cert.BlockHash[:])
+	cw.WriteUint32(uint32(len(cert.Votes)))
+	if err := cw.Err(); err != nil {
+		return err
+	}
+
+	for _, vote := range cert.Votes {
+		if err := crypto.SerializePublicKey(vote.Validator).Encode(w); err != nil {
+			return err
+		}
+		if err := vote.Signature.Encode(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cert *CommitCertificate) Decode(r io.Reader) error {
+	cr := util.NewCanonicalReader(r)
+	cr.ReadVersion(EncodingVersion)
+	cert.Height = cr.ReadUint32()
+	cert.Round = cr.ReadUint32()
+	cr.ReadFixed(cert.BlockHash[:])
+	numVotes := cr.ReadUint32()
+	if err := cr.Err(); err != nil {
+		return err
+	}
+
+	cert.Votes = make([]CommitVote, 0, min(numVotes, 1024))
+	for i := uint32(0); i < numVotes; i++ {
+		validator := &crypto.SerializablePublicKey{}
+		if err := validator.Decode(r); err != nil {
+			return err
+		}
+		signature := crypto.NewNilSignature()
+		if err := signature.Decode(r); err != nil {
+			return err
+		}
+		cert.Votes = append(cert.Votes, CommitVote{
+			Validator: crypto.DecodePublicKey(validator),
+			Signature: signature,
+		})
+	}
+	return nil
+}
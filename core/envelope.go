@@ -0,0 +1,201 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+
+	"github.com/tusharjoshi4531/block-chain.git/util"
+)
+
+// TxType tags the payload carried in a transaction envelope. Packages that
+// own a type register a handler for it with RegisterTxType.
+type TxType uint8
+
+const (
+	TxTypeTransfer TxType = iota + 1
+	TxTypeCoinbase
+	TxTypeRegistration
+	TxTypeStake
+	TxTypeUnstake
+	TxTypeSlash
+	TxTypeVote
+	TxTypeMultisig
+	TxTypeEscrow
+	TxTypeRelease
+	TxTypeUTXO
+	TxTypeIssueAsset
+	TxTypeAnchor
+)
+
+const EnvelopeVersion uint8 = 1
+
+// envelopeMagic prefixes the data of typed transactions so they can be told
+// apart from opaque payloads.
+var envelopeMagic = []byte("bc/tx")
+
+// Envelope is the typed content of a transaction's Data: a version, a type
+// tag and a payload whose layout is defined by the type's handler.
+type Envelope struct {
+	Type    TxType
+	Payload []byte
+}
+
+func NewEnvelope(txType TxType, payload []byte) *Envelope {
+	return &Envelope{
+		Type:    txType,
+		Payload: payload,
+	}
+}
+
+func (envelope *Envelope) Bytes() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	cw := util.NewCanonicalWriter(buf)
+	cw.WriteFixed(envelopeMagic)
+	cw.WriteUint8(EnvelopeVersion)
+	cw.WriteUint8(uint8(envelope.Type))
+	cw.WriteBytes(envelope.Payload)
+	if err := cw.Err(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func DecodeEnvelope(data []byte) (*Envelope, error) {
+	if !IsEnvelope(data) {
+		return nil, fmt.Errorf("data is not a transaction envelope")
+	}
+
+	cr := util.NewCanonicalReader(bytes.NewReader(data[len(envelopeMagic):]))
+	cr.ReadVersion(EnvelopeVersion)
+	txType := TxType(cr.ReadUint8())
+	payload := cr.ReadBytes()
+	cr.ReadEnd()
+	if err := cr.Err(); err != nil {
+		return nil, err
+	}
+	return NewEnvelope(txType, payload), nil
+}
+
+func IsEnvelope(data []byte) bool {
+	return bytes.HasPrefix(data, envelopeMagic)
+}
+
+// NewTypedTransaction builds an unsigned transaction whose Data is an
+// envelope of the given type.
+func NewTypedTransaction(txType TxType, payload []byte) (*Transaction, error) {
+	data, err := NewEnvelope(txType, payload).Bytes()
+	if err != nil {
+		return nil, err
+	}
+	return NewTransaction(data), nil
+}
+
+// TxTypeHandler decodes the payload of one transaction type and checks the
+// rules that hold regardless of chain state. Validate may be nil. BlockOnly
+// types are created by block producers and never admitted to the pool. Lock,
+// if set, returns the earliest block a transaction may be included in.
+type TxTypeHandler struct {
+	Name      string
+	Decode    func(payload []byte) (any, error)
+	Validate  func(tx *Transaction, decoded any) error
+	BlockOnly bool
+	Lock      func(decoded any) TxLock
+}
+
+var txTypes = struct {
+	mu       sync.RWMutex
+	handlers map[TxType]TxTypeHandler
+}{
+	handlers: make(map[TxType]TxTypeHandler),
+}
+
+// RegisterTxType installs the handler for a transaction type. It panics if
+// the type is already registered.
+func RegisterTxType(txType TxType, handler TxTypeHandler) {
+	txTypes.mu.Lock()
+	defer txTypes.mu.Unlock()
+
+	if _, ok := txTypes.handlers[txType]; ok {
+		panic(fmt.Sprintf("transaction type (%d) is already registered", txType))
+	}
+	txTypes.handlers[txType] = handler
+}
+
+func GetTxTypeHandler(txType TxType) (TxTypeHandler, bool) {
+	txTypes.mu.RLock()
+	defer txTypes.mu.RUnlock()
+
+	handler, ok := txTypes.handlers[txType]
+	return handler, ok
+}
+
+func TxTypeToString(txType TxType) string {
+	if handler, ok := GetTxTypeHandler(txType); ok {
+		return handler.Name
+	}
+	return "Invalid"
+}
+
+// DecodeTransaction decodes the envelope of tx and its payload with the
+// handler registered for its type.
+func DecodeTransaction(tx *Transaction) (TxType, any, error) {
+	envelope, err := DecodeEnvelope(tx.Data)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	handler, ok := GetTxTypeHandler(envelope.Type)
+	if !ok {
+		return envelope.Type, nil, fmt.Errorf("unknown transaction type (%d)", envelope.Type)
+	}
+	decoded, err := handler.Decode(envelope.Payload)
+	if err != nil {
+		return envelope.Type, nil, fmt.Errorf("malformed %s transaction: %s", handler.Name, err)
+	}
+	return envelope.Type, decoded, nil
+}
+
+// ValidateTransaction checks the signature of tx and, for typed
+// transactions, that the payload decodes and passes its type's rules.
+// Opaque payloads only need a valid signature.
+func ValidateTransaction(tx *Transaction) error {
+	if err := tx.Verify(); err != nil {
+		return err
+	}
+	if !IsEnvelope(tx.Data) {
+		return nil
+	}
+	_, err := validatePayload(tx)
+	return err
+}
+
+// CheckPoolAdmission rejects typed transactions that users may not submit:
+// undecodable or invalid payloads and block only types. Opaque payloads are
+// admitted.
+func CheckPoolAdmission(tx *Transaction) error {
+	if !IsEnvelope(tx.Data) {
+		return nil
+	}
+
+	handler, err := validatePayload(tx)
+	if err != nil {
+		return err
+	}
+	if handler.BlockOnly {
+		return fmt.Errorf("%s transactions are only accepted in blocks", handler.Name)
+	}
+	return nil
+}
+
+func validatePayload(tx *Transaction) (TxTypeHandler, error) {
+	txType, decoded, err := DecodeTransaction(tx)
+	if err != nil {
+		return TxTypeHandler{}, err
+	}
+	handler, _ := GetTxTypeHandler(txType)
+	if handler.Validate == nil {
+		return handler, nil
+	}
+	return handler, handler.Validate(tx, decoded)
+}
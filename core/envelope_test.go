@@ -0,0 +1,86 @@
+package core
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+)
+
+const testTxType TxType = 200
+
+func init() {
+	RegisterTxType(testTxType, TxTypeHandler{
+		Name: "Test",
+		Decode: func(payload []byte) (any, error) {
+			if len(payload) == 0 {
+				return nil, fmt.Errorf("empty payload")
+			}
+			return string(payload), nil
+		},
+		Validate: func(tx *Transaction, decoded any) error {
+			if decoded.(string) == "invalid" {
+				return fmt.Errorf("invalid payload")
+			}
+			return nil
+		},
+	})
+}
+
+func TestEnvelopeEncoding(t *testing.T) {
+	data, err := NewEnvelope(testTxType, []byte("payload")).Bytes()
+	assert.Nil(t, err)
+	assert.True(t, IsEnvelope(data))
+
+	envelope, err := DecodeEnvelope(data)
+	assert.Nil(t, err)
+	assert.Equal(t, testTxType, envelope.Type)
+	assert.Equal(t, []byte("payload"), envelope.Payload)
+
+	_, err = DecodeEnvelope([]byte("opaque"))
+	assert.NotNil(t, err)
+	_, err = DecodeEnvelope(data[:len(data)-1])
+	assert.NotNil(t, err)
+	_, err = DecodeEnvelope(append(data[:len(data):len(data)], 0))
+	assert.NotNil(t, err)
+
+	data[len(envelopeMagic)] = EnvelopeVersion + 1
+	_, err = DecodeEnvelope(data)
+	assert.NotNil(t, err)
+}
+
+func TestTxTypeRegistry(t *testing.T) {
+	assert.Equal(t, "Test", TxTypeToString(testTxType))
+	assert.Equal(t, "Invalid", TxTypeToString(TxType(255)))
+	assert.Panics(t, func() {
+		RegisterTxType(testTxType, TxTypeHandler{Name: "Duplicate"})
+	})
+
+	tx, err := NewTypedTransaction(testTxType, []byte("payload"))
+	assert.Nil(t, err)
+	txType, decoded, err := DecodeTransaction(tx)
+	assert.Nil(t, err)
+	assert.Equal(t, testTxType, txType)
+	assert.Equal(t, "payload", decoded)
+}
+
+func TestValidateTransaction(t *testing.T) {
+	privKey := crypto.GeneratePrivateKey()
+	sign := func(tx *Transaction, err error) *Transaction {
+		assert.Nil(t, err)
+		assert.Nil(t, tx.Sign(privKey))
+		return tx
+	}
+
+	assert.Nil(t, ValidateTransaction(sign(NewTransaction([]byte("opaque")), nil)))
+	assert.Nil(t, ValidateTransaction(sign(NewTypedTransaction(testTxType, []byte("payload")))))
+	assert.NotNil(t, ValidateTransaction(sign(NewTypedTransaction(testTxType, []byte("invalid")))))
+	assert.NotNil(t, ValidateTransaction(sign(NewTypedTransaction(testTxType, []byte{}))))
+	assert.NotNil(t, ValidateTransaction(sign(NewTypedTransaction(TxType(255), []byte("payload")))))
+
+	// Signatures are checked before the payload
+	unsigned, err := NewTypedTransaction(testTxType, []byte("payload"))
+	assert.Nil(t, err)
+	assert.NotNil(t, ValidateTransaction(unsigned))
+}
@@ -0,0 +1,136 @@
+package core
+
+import (
+	"sync"
+
+	"github.com/tusharjoshi4531/block-chain.git/types"
+)
+
+type EventType int
+
+const (
+	EventBlockConnected EventType = iota
+	EventBlockDisconnected
+	EventTipChanged
+	EventReorgRejected
+	EventTransactionAdded
+	EventTransactionRemoved
+	EventTransactionConfirmed
+	EventBlockFinalized
+)
+
+func EventTypeToString(eventType EventType) string {
+	switch eventType {
+	case EventBlockConnected:
+		return "BlockConnected"
+	case EventBlockDisconnected:
+		return "BlockDisconnected"
+	case EventTipChanged:
+		return "TipChanged"
+	case EventReorgRejected:
+		return "ReorgRejected"
+	case EventTransactionAdded:
+		return "TransactionAdded"
+	case EventTransactionRemoved:
+		return "TransactionRemoved"
+	case EventTransactionConfirmed:
+		return "TransactionConfirmed"
+	case EventBlockFinalized:
+		return "BlockFinalized"
+	default:
+		return "Invalid"
+	}
+}
+
+// Event describes a change to the chain or the transaction pool. Block is
+// set for block, tip and reorg events and is the confirming block for
+// EventTransactionConfirmed. Ancestor and ReorgDepth are only set for
+// EventTipChanged and EventReorgRejected.
+type Event struct {
+	Type        EventType
+	Block       *Block
+	Transaction *Transaction
+	Ancestor    types.Hash
+	ReorgDepth  uint32
+}
+
+type Subscription struct {
+	id     int
+	feed   *EventFeed
+	filter map[EventType]bool
+	events chan Event
+}
+
+func (sub *Subscription) Events() <-chan Event {
+	return sub.events
+}
+
+func (sub *Subscription) Unsubscribe() {
+	sub.feed.unsubscribe(sub.id)
+}
+
+func (sub *Subscription) accepts(eventType EventType) bool {
+	return len(sub.filter) == 0 || sub.filter[eventType]
+}
+
+// EventFeed fans events out to buffered subscriber channels. Publishing
+// never blocks; events for a subscriber whose buffer is full are dropped.
+type EventFeed struct {
+	mu          sync.RWMutex
+	nextId      int
+	subscribers map[int]*Subscription
+}
+
+func NewEventFeed() *EventFeed {
+	return &EventFeed{
+		subscribers: make(map[int]*Subscription),
+	}
+}
+
+func (feed *EventFeed) Subscribe(bufferSize int, eventTypes ...EventType) *Subscription {
+	feed.mu.Lock()
+	defer feed.mu.Unlock()
+
+	filter := make(map[EventType]bool)
+	for _, eventType := range eventTypes {
+		filter[eventType] = true
+	}
+
+	sub := &Subscription{
+		id:     feed.nextId,
+		feed:   feed,
+		filter: filter,
+		events: make(chan Event, bufferSize),
+	}
+	feed.subscribers[sub.id] = sub
+	feed.nextId++
+
+	return sub
+}
+
+func (feed *EventFeed) Publish(event Event) {
+	feed.mu.RLock()
+	defer feed.mu.RUnlock()
+
+	for _, sub := range feed.subscribers {
+		if !sub.accepts(event.Type) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+		}
+	}
+}
+
+func (feed *EventFeed) unsubscribe(id int) {
+	feed.mu.Lock()
+	defer feed.mu.Unlock()
+
+	sub, ok := feed.subscribers[id]
+	if !ok {
+		return
+	}
+	delete(feed.subscribers, id)
+	close(sub.events)
+}
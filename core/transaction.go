@@ -1,11 +1,12 @@
 package core
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"crypto/sha256"
-	"encoding/gob"
 	"fmt"
 	"io"
+	"sync/atomic"
 
 	"github.com/tusharjoshi4531/block-chain.git/crypto"
 	"github.com/tusharjoshi4531/block-chain.git/types"
@@ -18,7 +19,9 @@ type Transaction struct {
 	From      *ecdsa.PublicKey
 	Signature *crypto.Signature
 
-	hash      types.Hash
+	// hash caches Hash; transactions are read from several goroutines once
+	// they reach the pool or a block.
+	hash      atomic.Pointer[types.Hash]
 	firstSeen int64
 }
 
@@ -39,7 +42,8 @@ func (tx *Transaction) FirstSeen() int64 {
 }
 
 func (tx *Transaction) Sign(privateKey *ecdsa.PrivateKey) error {
-	sig, err := crypto.SignBytes(privateKey, tx.Data)
+	hash := tx.signingHash()
+	sig, err := crypto.SignBytes(privateKey, hash[:])
 	if err != nil {
 		return err
 	}
@@ -55,16 +59,21 @@ func (tx *Transaction) Verify() error {
 		return fmt.Errorf("transaction has no signature")
 	}
 
-	if !tx.Signature.Verify(tx.From, tx.Data) {
+	hash := tx.signingHash()
+	if !tx.Signature.Verify(tx.From, hash[:]) {
 		return fmt.Errorf("incorrect sign in transaction")
 	}
 
 	return nil
 }
 
+// Encode writes the canonical transaction: encoding version, length
+// prefixed Data, the signer's public key and the signature.
 func (tx *Transaction) Encode(w io.Writer) error {
-	enc := gob.NewEncoder(w)
-	if err := enc.Encode(tx.Data); err != nil {
+	cw := util.NewCanonicalWriter(w)
+	cw.WriteUint8(EncodingVersion)
+	cw.WriteBytes(tx.Data)
+	if err := cw.Err(); err != nil {
 		return err
 	}
 	if err := crypto.SerializePublicKey(tx.From).Encode(w); err != nil {
@@ -81,11 +90,13 @@ func (tx *Transaction) Bytes() ([]byte, error) {
 }
 
 func (tx *Transaction) Decode(r io.Reader) error {
-	dec := gob.NewDecoder(r)
-
-	if err := dec.Decode(&tx.Data); err != nil {
+	cr := util.NewCanonicalReader(r)
+	cr.ReadVersion(EncodingVersion)
+	tx.Data = cr.ReadBytes()
+	if err := cr.Err(); err != nil {
 		return err
 	}
+
 	serializedFrom := &crypto.SerializablePublicKey{}
 	if err := serializedFrom.Decode(r); err != nil {
 		return err
@@ -98,9 +109,35 @@ func (tx *Transaction) Decode(r io.Reader) error {
 	return nil
 }
 
+// SigningBytes returns the canonical bytes identifying the transaction; its
+// hash is what gets signed.
+func (tx *Transaction) SigningBytes() []byte {
+	buf := &bytes.Buffer{}
+	cw := util.NewCanonicalWriter(buf)
+	cw.WriteUint8(EncodingVersion)
+	cw.WriteBytes(tx.Data)
+	return buf.Bytes()
+}
+
+func (tx *Transaction) signingHash() types.Hash {
+	return sha256.Sum256(tx.SigningBytes())
+}
+
 func (tx *Transaction) Hash() types.Hash {
-	if tx.hash.IsZero() {
-		tx.hash = sha256.Sum256(tx.Data)
+	if hash := tx.hash.Load(); hash != nil {
+		return *hash
+	}
+	hash := tx.signingHash()
+	tx.hash.Store(&hash)
+	return hash
+}
+
+// SignedHash is the hash of the full encoding of tx, signer and signature
+// included. Unlike Hash it tells apart separate signings of the same data.
+func (tx *Transaction) SignedHash() (types.Hash, error) {
+	data, err := tx.Bytes()
+	if err != nil {
+		return types.Hash{}, err
 	}
-	return tx.hash
+	return sha256.Sum256(data), nil
 }
@@ -14,31 +14,59 @@ type TransactionPool interface {
 	Transactions() []*Transaction
 	GetTransaction(types.Hash) (*Transaction, error)
 	HasTransaction(types.Hash) bool
+	RemoveTransaction(types.Hash) error
+	Subscribe(bufferSize int, eventTypes ...EventType) *Subscription
 }
 
 type DefaultTransactionPool struct {
 	mu           sync.RWMutex
 	transacitons map[types.Hash]*Transaction
+	events       *EventFeed
 }
 
 func NewDefaultTransactionPool() *DefaultTransactionPool {
 	return &DefaultTransactionPool{
 		transacitons: make(map[types.Hash]*Transaction),
+		events:       NewEventFeed(),
 	}
 }
 
 func (txPool *DefaultTransactionPool) AddTransaction(tx *Transaction) error {
-	txPool.mu.Lock()
-	defer txPool.mu.Unlock()
+	if err := CheckPoolAdmission(tx); err != nil {
+		return err
+	}
 
+	txPool.mu.Lock()
 	hash := tx.Hash()
 	if _, ok := txPool.transacitons[hash]; ok {
+		txPool.mu.Unlock()
 		return fmt.Errorf("transaction (%s) already present in pool", hash)
 	}
 	txPool.transacitons[hash] = tx
+	txPool.mu.Unlock()
+
+	txPool.events.Publish(Event{Type: EventTransactionAdded, Transaction: tx})
 	return nil
 }
 
+func (txPool *DefaultTransactionPool) RemoveTransaction(hash types.Hash) error {
+	txPool.mu.Lock()
+	tx, ok := txPool.transacitons[hash]
+	if !ok {
+		txPool.mu.Unlock()
+		return fmt.Errorf("transaction with hash (%s) does not exist in the pool", hash.String())
+	}
+	delete(txPool.transacitons, hash)
+	txPool.mu.Unlock()
+
+	txPool.events.Publish(Event{Type: EventTransactionRemoved, Transaction: tx})
+	return nil
+}
+
+func (txPool *DefaultTransactionPool) Subscribe(bufferSize int, eventTypes ...EventType) *Subscription {
+	return txPool.events.Subscribe(bufferSize, eventTypes...)
+}
+
 func (txPool *DefaultTransactionPool) Transactions() []*Transaction {
 	txPool.mu.RLock()
 	defer txPool.mu.RUnlock()
@@ -63,6 +91,9 @@ func (txPool *DefaultTransactionPool) Len() int {
 }
 
 func (txPool *DefaultTransactionPool) GetTransaction(hash types.Hash) (*Transaction, error) {
+	txPool.mu.RLock()
+	defer txPool.mu.RUnlock()
+
 	transaction, ok := txPool.transacitons[hash]
 	if !ok {
 		return nil, fmt.Errorf("transaction with hash (%s) does not exist in the pool", hash.String())
@@ -71,6 +102,9 @@ func (txPool *DefaultTransactionPool) GetTransaction(hash types.Hash) (*Transact
 }
 
 func (txPool *DefaultTransactionPool) HasTransaction(hash types.Hash) bool {
+	txPool.mu.RLock()
+	defer txPool.mu.RUnlock()
+
 	_, ok := txPool.transacitons[hash]
 	return ok
 }
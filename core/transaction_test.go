@@ -2,10 +2,15 @@ package core
 
 import (
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"encoding/gob"
+	"encoding/hex"
 	"fmt"
+	"math/big"
 	"math/rand"
 	"strconv"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -39,6 +44,43 @@ func TestTranscactionPool(t *testing.T) {
 	}
 }
 
+func TestTransactionPoolEvents(t *testing.T) {
+	txPool := NewDefaultTransactionPool()
+	sub := txPool.Subscribe(8)
+	defer sub.Unsubscribe()
+
+	tx := newSignedTransaction(t, []byte("FOO"))
+	assert.Nil(t, txPool.AddTransaction(tx))
+	assert.NotNil(t, txPool.AddTransaction(tx))
+
+	event := <-sub.Events()
+	assert.Equal(t, EventTransactionAdded, event.Type)
+	assert.Equal(t, tx, event.Transaction)
+
+	assert.Nil(t, txPool.RemoveTransaction(tx.Hash()))
+	assert.NotNil(t, txPool.RemoveTransaction(tx.Hash()))
+	assert.False(t, txPool.HasTransaction(tx.Hash()))
+
+	event = <-sub.Events()
+	assert.Equal(t, EventTransactionRemoved, event.Type)
+	assert.Equal(t, tx, event.Transaction)
+}
+
+func TestConcurrentHash(t *testing.T) {
+	tx := newSignedTransaction(t, []byte("FOO"))
+
+	// Event subscribers hash what the publisher may not have hashed yet
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, tx.signingHash(), tx.Hash())
+		}()
+	}
+	wg.Wait()
+}
+
 func TestSuccessiveEncoding(t *testing.T) {
 	a := "HASEAE"
 	b := 145
@@ -104,3 +146,25 @@ func newSignedTransaction(t *testing.T, data []byte) *Transaction {
 
 	return tx
 }
+
+func TestTransactionGoldenVector(t *testing.T) {
+	params := elliptic.P256().Params()
+	tx := NewTransaction([]byte("FOO"))
+	tx.From = &ecdsa.PublicKey{Curve: elliptic.P256(), X: params.Gx, Y: params.Gy}
+	tx.Signature = &crypto.Signature{R: big.NewInt(1), S: big.NewInt(2)}
+
+	assert.Equal(t, "0100000003464f4f", hex.EncodeToString(tx.SigningBytes()))
+
+	hash := tx.Hash()
+	assert.Equal(t, "c8103b1c034be06f8fe1d52579684a1232f6a4d71dd73415ac036901cbbe1ac7", hash.String())
+
+	txBytes, err := tx.Bytes()
+	assert.Nil(t, err)
+	assert.Equal(t,
+		"01"+"00000003464f4f"+
+			"00000041046b17d1f2e12c4247f8bce6e563a440f277037d812deb33a0f4a13945d898c296"+
+			"4fe342e2fe1a7f9b8ee7eb4a7c0f9e162bce33576b315ececbb6406837bf51f5"+
+			"00000001010000000102",
+		hex.EncodeToString(txBytes),
+	)
+}
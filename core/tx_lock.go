@@ -0,0 +1,48 @@
+package core
+
+import "fmt"
+
+// TxLock is the earliest block a transaction may be included in: a block at
+// Height or above whose Timestamp is at least Time. Time uses the unit of
+// block timestamps, Unix nanoseconds. Zero fields do not constrain.
+type TxLock struct {
+	Height uint32
+	Time   int64
+}
+
+func (lock TxLock) IsZero() bool {
+	return lock.Height == 0 && lock.Time == 0
+}
+
+func (lock TxLock) IsSatisfied(height uint32, timestamp int64) bool {
+	return height >= lock.Height && timestamp >= lock.Time
+}
+
+// TransactionLock returns the lock of a typed transaction. Opaque payloads,
+// undecodable payloads and types without a Lock handler are not locked.
+func TransactionLock(tx *Transaction) TxLock {
+	txType, decoded, err := DecodeTransaction(tx)
+	if err != nil {
+		return TxLock{}
+	}
+	handler, _ := GetTxTypeHandler(txType)
+	if handler.Lock == nil {
+		return TxLock{}
+	}
+	return handler.Lock(decoded)
+}
+
+// CheckTransactionLock fails if tx may not yet be included in a block at
+// height with the given timestamp.
+func CheckTransactionLock(tx *Transaction, height uint32, timestamp int64) error {
+	lock := TransactionLock(tx)
+	if lock.IsSatisfied(height, timestamp) {
+		return nil
+	}
+	return fmt.Errorf(
+		"transaction (%s) is locked until height (%d) and time (%d)",
+		tx.Hash(),
+		lock.Height,
+		lock.Time,
+	)
+}
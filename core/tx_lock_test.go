@@ -0,0 +1,25 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+)
+
+func TestTxLock(t *testing.T) {
+	assert.True(t, TxLock{}.IsZero())
+	assert.True(t, TxLock{}.IsSatisfied(0, 0))
+
+	lock := TxLock{Height: 5, Time: 100}
+	assert.False(t, lock.IsZero())
+	assert.False(t, lock.IsSatisfied(4, 200))
+	assert.False(t, lock.IsSatisfied(6, 99))
+	assert.True(t, lock.IsSatisfied(5, 100))
+
+	// Opaque payloads are never locked
+	tx := NewTransaction([]byte("data"))
+	assert.Nil(t, tx.Sign(crypto.GeneratePrivateKey()))
+	assert.Equal(t, TxLock{}, TransactionLock(tx))
+	assert.Nil(t, CheckTransactionLock(tx, 0, 0))
+}
@@ -40,7 +40,7 @@ func (DefaultValidator) ValidateBlock(block *Block) error {
 
 func (DefaultValidator) ValidateTransactions(transactions []*Transaction) error {
 	for _, transaction := range transactions {
-		if err := transaction.Verify(); err != nil {
+		if err := ValidateTransaction(transaction); err != nil {
 			return err
 		}
 	}
@@ -4,9 +4,16 @@ import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
-	"encoding/gob"
+	"fmt"
 	"io"
 	"math/big"
+
+	"github.com/tusharjoshi4531/block-chain.git/util"
+)
+
+const (
+	coordinateSize      = 32
+	uncompressedKeySize = 1 + 2*coordinateSize
 )
 
 type Signature struct {
@@ -21,15 +28,60 @@ func NewNilSignature() *Signature {
 }
 
 func (s *Signature) Verify(publicKey *ecdsa.PublicKey, data []byte) bool {
+	if publicKey == nil || publicKey.X == nil || s.R == nil || s.S == nil {
+		return false
+	}
 	return ecdsa.Verify(publicKey, data, s.R, s.S)
 }
 
+// IsLowS reports whether S is in the lower half of the curve order. ECDSA
+// accepts both S and N-S for the same message, so only the low form is
+// canonical.
+func (s *Signature) IsLowS() bool {
+	if s.S == nil {
+		return false
+	}
+	halfOrder := new(big.Int).Rsh(elliptic.P256().Params().N, 1)
+	return s.S.Cmp(halfOrder) <= 0
+}
+
+func (s *Signature) IsNil() bool {
+	return s.R == nil || s.S == nil || s.R.Sign() < 0 || s.S.Sign() < 0
+}
+
+// Encode writes R and S as length prefixed big-endian magnitudes. A nil
+// signature is written as two empty values.
 func (s *Signature) Encode(w io.Writer) error {
-	return gob.NewEncoder(w).Encode(s)
+	cw := util.NewCanonicalWriter(w)
+	if s.IsNil() {
+		cw.WriteBytes(nil)
+		cw.WriteBytes(nil)
+	} else {
+		cw.WriteBytes(s.R.Bytes())
+		cw.WriteBytes(s.S.Bytes())
+	}
+	return cw.Err()
 }
 
 func (s *Signature) Decode(r io.Reader) error {
-	return gob.NewDecoder(r).Decode(s)
+	cr := util.NewCanonicalReader(r)
+	rBytes := cr.ReadBytes()
+	sBytes := cr.ReadBytes()
+	if err := cr.Err(); err != nil {
+		return err
+	}
+
+	if len(rBytes) == 0 && len(sBytes) == 0 {
+		*s = *NewNilSignature()
+		return nil
+	}
+	s.R = new(big.Int).SetBytes(rBytes)
+	s.S = new(big.Int).SetBytes(sBytes)
+	return nil
+}
+
+func (s *Signature) Bytes() ([]byte, error) {
+	return util.EncodeToBytes(s)
 }
 
 type SerializablePublicKey struct {
@@ -37,15 +89,53 @@ type SerializablePublicKey struct {
 	X, Y *big.Int
 }
 
+// Encode writes the key as a length prefixed uncompressed P-256 point
+// (0x04 || X || Y); a missing key is written as an empty value.
 func (key *SerializablePublicKey) Encode(w io.Writer) error {
-	return gob.NewEncoder(w).Encode(key)
+	cw := util.NewCanonicalWriter(w)
+	cw.WriteBytes(key.Bytes())
+	return cw.Err()
 }
 
 func (key *SerializablePublicKey) Decode(r io.Reader) error {
-	return gob.NewDecoder(r).Decode(key)
+	cr := util.NewCanonicalReader(r)
+	data := cr.ReadBytes()
+	if err := cr.Err(); err != nil {
+		return err
+	}
+	return key.SetBytes(data)
+}
+
+func (key *SerializablePublicKey) Bytes() []byte {
+	if key.X == nil || key.Y == nil {
+		return []byte{}
+	}
+
+	buf := make([]byte, uncompressedKeySize)
+	buf[0] = 4
+	key.X.FillBytes(buf[1 : 1+coordinateSize])
+	key.Y.FillBytes(buf[1+coordinateSize:])
+	return buf
+}
+
+func (key *SerializablePublicKey) SetBytes(data []byte) error {
+	if len(data) == 0 {
+		key.X, key.Y = nil, nil
+		return nil
+	}
+	if len(data) != uncompressedKeySize || data[0] != 4 {
+		return fmt.Errorf("invalid public key encoding of length (%d)", len(data))
+	}
+
+	key.X = new(big.Int).SetBytes(data[1 : 1+coordinateSize])
+	key.Y = new(big.Int).SetBytes(data[1+coordinateSize:])
+	return nil
 }
 
 func SerializePublicKey(publicKey *ecdsa.PublicKey) *SerializablePublicKey {
+	if publicKey == nil {
+		return &SerializablePublicKey{}
+	}
 	return &SerializablePublicKey{
 		X: publicKey.X,
 		Y: publicKey.Y,
@@ -60,11 +150,29 @@ func DecodePublicKey(serializedKey *SerializablePublicKey) *ecdsa.PublicKey {
 	}
 }
 
+func PublicKeyBytes(publicKey *ecdsa.PublicKey) []byte {
+	return SerializePublicKey(publicKey).Bytes()
+}
+
+func PublicKeyFromBytes(data []byte) (*ecdsa.PublicKey, error) {
+	key := &SerializablePublicKey{}
+	if err := key.SetBytes(data); err != nil {
+		return nil, err
+	}
+	return DecodePublicKey(key), nil
+}
+
 func SignBytes(privateKey *ecdsa.PrivateKey, data []byte) (*Signature, error) {
 	r, s, err := ecdsa.Sign(rand.Reader, privateKey, data)
 	if err != nil {
 		return nil, err
 	}
+
+	// Signatures are always produced in low-S form
+	curveOrder := privateKey.Curve.Params().N
+	if s.Cmp(new(big.Int).Rsh(curveOrder, 1)) > 0 {
+		s.Sub(curveOrder, s)
+	}
 	return &Signature{
 		R: r,
 		S: s,
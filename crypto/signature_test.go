@@ -2,6 +2,10 @@ package crypto
 
 import (
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/hex"
+	"math/big"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -36,3 +40,47 @@ func TestSignatureEncodeDecode(t *testing.T) {
 	assert.Nil(t, sig2.Decode(buf))
 	assert.Equal(t, sig, sig2)
 }
+
+func TestSignatureLowS(t *testing.T) {
+	privKey := GeneratePrivateKey()
+	data := []byte("Hello world")
+
+	for i := 0; i < 16; i++ {
+		sig, err := SignBytes(privKey, data)
+		assert.Nil(t, err)
+		assert.True(t, sig.IsLowS())
+
+		// The high-S twin verifies but is not canonical
+		highS := &Signature{R: sig.R, S: new(big.Int).Sub(elliptic.P256().Params().N, sig.S)}
+		assert.True(t, highS.Verify(&privKey.PublicKey, data))
+		assert.False(t, highS.IsLowS())
+	}
+}
+
+func TestCanonicalEncodingGoldenVector(t *testing.T) {
+	params := elliptic.P256().Params()
+	pubKey := &ecdsa.PublicKey{Curve: elliptic.P256(), X: params.Gx, Y: params.Gy}
+
+	buf := &bytes.Buffer{}
+	assert.Nil(t, SerializePublicKey(pubKey).Encode(buf))
+	assert.Equal(t,
+		"00000041"+
+			"04"+
+			"6b17d1f2e12c4247f8bce6e563a440f277037d812deb33a0f4a13945d898c296"+
+			"4fe342e2fe1a7f9b8ee7eb4a7c0f9e162bce33576b315ececbb6406837bf51f5",
+		hex.EncodeToString(buf.Bytes()),
+	)
+
+	sig := &Signature{R: big.NewInt(1), S: big.NewInt(2)}
+	buf = &bytes.Buffer{}
+	assert.Nil(t, sig.Encode(buf))
+	assert.Equal(t, "00000001010000000102", hex.EncodeToString(buf.Bytes()))
+
+	buf = &bytes.Buffer{}
+	assert.Nil(t, NewNilSignature().Encode(buf))
+	assert.Equal(t, "0000000000000000", hex.EncodeToString(buf.Bytes()))
+
+	decodedSig := &Signature{}
+	assert.Nil(t, decodedSig.Decode(buf))
+	assert.True(t, decodedSig.IsNil())
+}
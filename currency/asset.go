@@ -0,0 +1,136 @@
+package currency
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/tusharjoshi4531/block-chain.git/core"
+)
+
+// MaxAssetIdLength bounds the length of asset ids.
+const MaxAssetIdLength = 16
+
+// Asset is a token issued by a wallet with a fixed Supply. Transactions
+// that name no asset move the native currency.
+type Asset struct {
+	Id     string
+	Issuer string
+	Supply float64
+}
+
+// NewIssueAssetTransaction creates asset with supply, credited in full to
+// the issuer.
+func NewIssueAssetTransaction(issuer string, asset string, supply float64) *Transaction {
+	tx := NewTransaction(issuer, issuer, supply)
+	tx.Type = core.TxTypeIssueAsset
+	tx.Asset = asset
+	return tx
+}
+
+// NewAssetTransaction transfers amount of asset between wallets. Its fee is
+// paid in the native currency.
+func NewAssetTransaction(from string, to string, asset string, amount float64) *Transaction {
+	tx := NewTransaction(from, to, amount)
+	tx.Asset = asset
+	return tx
+}
+
+// validateAssetId requires ids of letters and digits only, so that they
+// cannot be confused with wallets or system symbols.
+func validateAssetId(asset string) error {
+	if asset == "" || len(asset) > MaxAssetIdLength {
+		return fmt.Errorf("asset id (%s) must have 1 to (%d) characters", asset, MaxAssetIdLength)
+	}
+	for _, c := range asset {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9') {
+			return fmt.Errorf("asset id (%s) may only contain letters and digits", asset)
+		}
+	}
+	return nil
+}
+
+// assetBook keeps the issued assets and the balances of each. Its changes
+// are exact inverses of each other, so ledgers revert them by applying the
+// opposite change.
+type assetBook struct {
+	assets   map[string]Asset
+	balances map[string]map[string]float64
+}
+
+func newAssetBook() assetBook {
+	return assetBook{
+		assets:   make(map[string]Asset),
+		balances: make(map[string]map[string]float64),
+	}
+}
+
+func (book *assetBook) issue(asset Asset) error {
+	if _, ok := book.assets[asset.Id]; ok {
+		return fmt.Errorf("asset (%s) is already issued", asset.Id)
+	}
+	book.assets[asset.Id] = asset
+	book.balances[asset.Id] = map[string]float64{asset.Issuer: asset.Supply}
+	return nil
+}
+
+// unissue removes asset, which must be held in full by its issuer.
+func (book *assetBook) unissue(assetId string) error {
+	asset, ok := book.assets[assetId]
+	if !ok {
+		return fmt.Errorf("asset (%s) is not issued", assetId)
+	}
+	if book.balances[assetId][asset.Issuer] < asset.Supply {
+		return fmt.Errorf("supply of asset (%s) is not held by its issuer (%s)", assetId, asset.Issuer)
+	}
+	delete(book.assets, assetId)
+	delete(book.balances, assetId)
+	return nil
+}
+
+func (book *assetBook) transfer(assetId string, from, to string, amt float64) error {
+	balances, ok := book.balances[assetId]
+	if !ok {
+		return fmt.Errorf("asset (%s) is not issued", assetId)
+	}
+	if balances[from] < amt {
+		return fmt.Errorf("sender (%s) does not have enough of asset (%s)", from, assetId)
+	}
+	balances[from] -= amt
+	balances[to] += amt
+	if balances[from] == 0 {
+		delete(balances, from)
+	}
+	return nil
+}
+
+// GetAssets returns the issued assets ordered by id.
+func (book *assetBook) GetAssets() []Asset {
+	assets := make([]Asset, 0, len(book.assets))
+	for _, asset := range book.assets {
+		assets = append(assets, asset)
+	}
+	sort.Slice(assets, func(i, j int) bool {
+		return assets[i].Id < assets[j].Id
+	})
+	return assets
+}
+
+func (book *assetBook) GetAssetBalance(walletId string, assetId string) (float64, error) {
+	balances, ok := book.balances[assetId]
+	if !ok {
+		return 0, fmt.Errorf("asset (%s) is not issued", assetId)
+	}
+	return balances[walletId], nil
+}
+
+// GetAssetBalances returns the non-zero asset balances of the wallet by
+// asset id.
+func (book *assetBook) GetAssetBalances(walletId string) map[string]float64 {
+	holdings := make(map[string]float64)
+	for assetId, balances := range book.balances {
+		if balance := balances[walletId]; balance > 0 {
+			holdings[assetId] = balance
+		}
+	}
+	return holdings
+}
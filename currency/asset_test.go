@@ -0,0 +1,92 @@
+package currency
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+)
+
+func TestAssetTransactions(t *testing.T) {
+	issue := NewIssueAssetTransaction("A", "GOLD", 1000)
+	assert.Equal(t, core.TxTypeIssueAsset, issue.Type)
+
+	tx, err := issue.ToCoreTransaction()
+	assert.Nil(t, err)
+	decoded, err := NewTransactionFromCoreTransaction(tx)
+	assert.Nil(t, err)
+	assert.Equal(t, "GOLD", decoded.Asset)
+	assert.Equal(t, core.TxTypeIssueAsset, decoded.Type)
+
+	privKey := crypto.GeneratePrivateKey()
+	validate := func(tx *Transaction) error {
+		coreTx, err := tx.ToCoreTransaction()
+		assert.Nil(t, err)
+		assert.Nil(t, coreTx.Sign(privKey))
+		return core.ValidateTransaction(coreTx)
+	}
+	assert.Nil(t, validate(issue))
+	assert.Nil(t, validate(NewAssetTransaction("A", "B", "GOLD", 10)))
+
+	assert.NotNil(t, validate(NewIssueAssetTransaction("A", "", 1000)))
+	assert.NotNil(t, validate(NewIssueAssetTransaction("A", "::GOLD", 1000)))
+	assert.NotNil(t, validate(NewIssueAssetTransaction("A", "TOOLONGASSETNAME1", 1000)))
+	assert.NotNil(t, validate(NewIssueAssetTransaction("A", "GOLD", 0)))
+	assert.NotNil(t, validate(NewIssueAssetTransaction(RewardSymbol, "GOLD", 1000)))
+
+	mismatched := NewIssueAssetTransaction("A", "GOLD", 1000)
+	mismatched.To = "B"
+	assert.NotNil(t, validate(mismatched))
+
+	// Only transfers move assets
+	stake := NewStakeTransaction("A", "v", 10)
+	stake.Asset = "GOLD"
+	assert.NotNil(t, validate(stake))
+}
+
+func TestLedgerAssets(t *testing.T) {
+	for name, state := range map[string]LedgerState{
+		"account": NewMemoryLedgerState(),
+		"utxo":    NewUTXOLedgerState(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Nil(t, state.AddWallet("A", 100))
+			assert.Nil(t, state.AddWallet("B", 100))
+
+			issue := NewIssueAssetTransaction("A", "GOLD", 1000)
+			assert.Nil(t, state.CommitTransaciton(issue))
+			assert.NotNil(t, state.CommitTransaciton(NewIssueAssetTransaction("B", "GOLD", 5)))
+			assert.Equal(t, []Asset{{Id: "GOLD", Issuer: "A", Supply: 1000}}, state.GetAssets())
+
+			// Asset transfers pay their fee in the native currency
+			transfer := NewAssetTransaction("A", "B", "GOLD", 400)
+			transfer.Fee = 2
+			assert.Nil(t, state.CommitTransaciton(transfer))
+			assert.NotNil(t, state.CommitTransaciton(NewAssetTransaction("B", "A", "GOLD", 401)))
+			assert.NotNil(t, state.CommitTransaciton(NewAssetTransaction("B", "A", "SILVER", 1)))
+
+			balance, err := state.GetAssetBalance("B", "GOLD")
+			assert.Nil(t, err)
+			assert.Equal(t, float64(400), balance)
+			assert.Equal(t, map[string]float64{"GOLD": 600}, state.GetAssetBalances("A"))
+			_, err = state.GetAssetBalance("B", "SILVER")
+			assert.NotNil(t, err)
+
+			balance, err = state.GetBalance("A")
+			assert.Nil(t, err)
+			assert.Equal(t, float64(98), balance)
+			balance, err = state.GetBalance("B")
+			assert.Nil(t, err)
+			assert.Equal(t, float64(100), balance)
+
+			assert.Nil(t, state.RevertTransaction(transfer))
+			assert.Nil(t, state.RevertTransaction(issue))
+			assert.Empty(t, state.GetAssets())
+			assert.Empty(t, state.GetAssetBalances("A"))
+			balance, err = state.GetBalance("A")
+			assert.Nil(t, err)
+			assert.Equal(t, float64(100), balance)
+		})
+	}
+}
@@ -1,205 +1,393 @@
 package currency
 
 import (
+	"fmt"
+
 	"github.com/tusharjoshi4531/block-chain.git/core"
 	"github.com/tusharjoshi4531/block-chain.git/types"
-	"github.com/tusharjoshi4531/block-chain.git/util"
 )
 
+const DefaultMaxReorgDepth = 100
+
+// ChainConfig bounds how far the ledger may be rewound by a competing fork.
+// A MaxReorgDepth of 0 disables the depth limit. A nil Genesis uses the
+// default empty genesis block. Blocks must follow the coinbase rules for
+// Reward unless it is nil.
+type ChainConfig struct {
+	MaxReorgDepth uint32
+	Checkpoints   map[uint32]types.Hash
+	Genesis       *core.Block
+	Reward        *RewardSchedule
+}
+
+func DefaultChainConfig() ChainConfig {
+	return ChainConfig{
+		MaxReorgDepth: DefaultMaxReorgDepth,
+		Checkpoints:   make(map[uint32]types.Hash),
+	}
+}
+
 type BlockChain struct {
 	core.DefaultBlockChain
 	state       LedgerState
 	initBalance float64
+	config      ChainConfig
 }
 
 func NewBlockChain(state LedgerState, initBalance float64) *BlockChain {
-	return &BlockChain{
-		DefaultBlockChain: *core.NewDefaultBlockChain(),
+	return NewBlockChainWithConfig(state, initBalance, DefaultChainConfig())
+}
+
+func NewBlockChainWithConfig(state LedgerState, initBalance float64, config ChainConfig) *BlockChain {
+	if config.Checkpoints == nil {
+		config.Checkpoints = make(map[uint32]types.Hash)
+	}
+	genesis := config.Genesis
+	if genesis == nil {
+		genesis = core.NewGenesisBlock(0, core.Seal{})
+	}
+	chain := &BlockChain{
+		DefaultBlockChain: *core.NewDefaultBlockChainWithGenesis(genesis),
 		state:             state,
 		initBalance:       initBalance,
+		config:            config,
 	}
+	chain.SetTipChangeHook(chain.updateLedger)
+	return chain
 }
 
 func (blockChain *BlockChain) AddBlock(block *core.Block) error {
-	prevHighestHash, err := blockChain.DefaultBlockChain.GetHeighestBlock().Hash()
-	if err != nil {
+	if err := blockChain.checkCheckpoint(block); err != nil {
 		return err
 	}
-
-	blockHash, err := block.Hash()
-	if err != nil {
+	if err := blockChain.checkReorgDepth(block); err != nil {
 		return err
 	}
-
-	if err := blockChain.DefaultBlockChain.AddBlock(block); err != nil {
+	if err := blockChain.validateBlockTransactions(block); err != nil {
 		return err
 	}
+	if reward := blockChain.config.Reward; reward != nil {
+		if err := ValidateCoinbase(block, *reward); err != nil {
+			return err
+		}
+	}
 
-	currHighestHash, err := blockChain.DefaultBlockChain.GetHeighestBlock().Hash()
-	if err != nil {
-		return err
+	return blockChain.DefaultBlockChain.AddBlock(block)
+}
+
+// InitialBalance is the amount registrations must credit new wallets with.
+func (blockChain *BlockChain) InitialBalance() float64 {
+	return blockChain.initBalance
+}
+
+// MaxReorgDepth is the deepest fork the chain switches to; 0 means no
+// limit.
+func (blockChain *BlockChain) MaxReorgDepth() uint32 {
+	return blockChain.config.MaxReorgDepth
+}
+
+// validateBlockTransactions applies the signature and per type rules to
+// every transaction of a block, so that blocks cannot carry transactions the
+// pool would refuse, such as plain spends from multisig wallets. Locked
+// transactions must be unlocked at the block's height and timestamp. UTXO
+// transactions need a UTXO ledger and may not spend an output twice within
+// the block, and registrations must credit the initial balance.
+func (blockChain *BlockChain) validateBlockTransactions(block *core.Block) error {
+	_, keepsUTXOs := blockChain.state.(UTXOLedger)
+	spent := make(map[OutPoint]struct{})
+
+	for i, tx := range block.Transactions {
+		if err := core.ValidateTransaction(tx); err != nil {
+			return fmt.Errorf("transaction (%d) of block at height (%d) is invalid: %s", i, block.Header.Height, err)
+		}
+		if err := core.CheckTransactionLock(tx, block.Header.Height, block.Header.Timestamp); err != nil {
+			return err
+		}
+
+		if transaction, err := NewTransactionFromCoreTransaction(tx); err == nil {
+			if err := blockChain.checkRegistration(transaction); err != nil {
+				return err
+			}
+		}
+
+		utxoTx, err := NewUTXOTransactionFromCoreTransaction(tx)
+		if err != nil {
+			continue
+		}
+		if !keepsUTXOs {
+			return fmt.Errorf("transaction (%d) of block at height (%d) needs a UTXO ledger", i, block.Header.Height)
+		}
+		for _, input := range utxoTx.Inputs {
+			if _, ok := spent[input.OutPoint]; ok {
+				return fmt.Errorf("block at height (%d) spends output (%s) twice", block.Header.Height, input.OutPoint)
+			}
+			spent[input.OutPoint] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// FilterTransactions returns the transactions of block that apply to the
+// ledger in order. The ledger is only at block's parent when block extends
+// the tip; otherwise the transactions are returned unchecked.
+func (blockChain *BlockChain) FilterTransactions(block *core.Block) []*core.Transaction {
+	tipHash, err := blockChain.GetHeighestBlock().Hash()
+	if err != nil || tipHash != block.Header.PrevBlockHash {
+		return block.Transactions
 	}
 
-	// Update ledger
-	if currHighestHash == blockHash {
-		return blockChain.updateLedger(prevHighestHash, currHighestHash)
+	kept := make([]*core.Transaction, 0, len(block.Transactions))
+	applied := make([]ledgerEntry, 0, len(block.Transactions))
+	for _, tx := range block.Transactions {
+		entry, ok := blockChain.ledgerTransaction(tx)
+		if !ok {
+			kept = append(kept, tx)
+			continue
+		}
+		if transaction, ok := entry.transaction.(*Transaction); ok && blockChain.checkRegistration(transaction) != nil {
+			continue
+		}
+		if err := blockChain.commitTransaction(block, entry); err != nil {
+			continue
+		}
+		applied = append(applied, entry)
+		kept = append(kept, tx)
 	}
 
+	for i := len(applied) - 1; i >= 0; i-- {
+		blockChain.revertTransaction(applied[i])
+	}
+	return kept
+}
+
+// checkRegistration rejects registrations that credit a new wallet with
+// anything but the chain's initial balance.
+func (blockChain *BlockChain) checkRegistration(transaction *Transaction) error {
+	if transaction.IsRegistration() && transaction.Amount != blockChain.initBalance {
+		return fmt.Errorf(
+			"registration of (%s) credits (%f) instead of the initial balance (%f)",
+			transaction.To,
+			transaction.Amount,
+			blockChain.initBalance,
+		)
+	}
 	return nil
 }
 
-func (blockChain *BlockChain) AddWallet(walletId string) error {
-	return blockChain.state.AddWallet(walletId, blockChain.initBalance)
+func (blockChain *BlockChain) checkCheckpoint(block *core.Block) error {
+	checkpoint, ok := blockChain.config.Checkpoints[block.Header.Height]
+	if !ok {
+		return nil
+	}
+
+	blockHash, err := block.Hash()
+	if err != nil {
+		return err
+	}
+	if blockHash != checkpoint {
+		return fmt.Errorf("block (%s) at height (%d) does not match checkpoint (%s)", blockHash.String(), block.Header.Height, checkpoint.String())
+	}
+	return nil
 }
 
-func (blockChain *BlockChain) updateLedger(prevHighestHash, currHighestHash types.Hash) error {
-	ancestorHash, err := blockChain.commonAncestor(prevHighestHash, currHighestHash)
+func (blockChain *BlockChain) checkReorgDepth(block *core.Block) error {
+	maxDepth := blockChain.config.MaxReorgDepth
+	if maxDepth == 0 || block.Header.Height < blockChain.Height() {
+		return nil
+	}
+
+	// Unknown parents are rejected by the underlying chain
+	if _, err := blockChain.GetBlockWithHash(block.Header.PrevBlockHash); err != nil {
+		return nil
+	}
+
+	tip := blockChain.GetHeighestBlock()
+	tipHash, err := tip.Hash()
 	if err != nil {
 		return err
 	}
 
-	if err := blockChain.revertPath(prevHighestHash, ancestorHash); err != nil {
+	ancestor, err := blockChain.CommonAncestor(tipHash, block.Header.PrevBlockHash)
+	if err != nil {
 		return err
 	}
-	if err := blockChain.commitPath(currHighestHash, ancestorHash); err != nil {
+	ancestorHash, err := ancestor.Hash()
+	if err != nil {
 		return err
 	}
-	return nil
+
+	depth := tip.Header.Height - ancestor.Header.Height
+	if depth <= maxDepth {
+		return nil
+	}
+
+	blockHash, err := block.Hash()
+	if err != nil {
+		return err
+	}
+	blockChain.Events().Publish(core.Event{
+		Type:       core.EventReorgRejected,
+		Block:      block,
+		Ancestor:   ancestorHash,
+		ReorgDepth: depth,
+	})
+
+	return fmt.Errorf("block (%s) requires reorg of depth (%d); maximum allowed is (%d)", blockHash.String(), depth, maxDepth)
 }
 
-func (blockChain *BlockChain) revertPath(child, ancestor types.Hash) error {
-	revertedBlocks := make([]*core.Block, 0)
-	for child != ancestor {
-		currBlock, err := blockChain.GetBlockWithHash(child)
-		if err != nil {
-			return err
+func (blockChain *BlockChain) updateLedger(disconnected, connected []*core.Block) error {
+	if err := blockChain.revertPath(disconnected); err != nil {
+		return err
+	}
+	if err := blockChain.commitPath(connected); err != nil {
+		// Restore the old branch so the ledger matches the unchanged tip
+		for i := len(disconnected) - 1; i >= 0; i-- {
+			blockChain.commitBlock(disconnected[i])
 		}
+		return err
+	}
+	return nil
+}
 
+func (blockChain *BlockChain) revertPath(path []*core.Block) error {
+	for i, currBlock := range path {
 		if err := blockChain.revertBlock(currBlock); err != nil {
 			// Undo all reverts
-			for i := len(revertedBlocks) - 1; i >= 0; i-- {
-				blockChain.commitBlock(revertedBlocks[i])
+			for j := i - 1; j >= 0; j-- {
+				blockChain.commitBlock(path[j])
 			}
 			return err
 		}
-
-		revertedBlocks = append(revertedBlocks, currBlock)
-		child = currBlock.Header.PrevBlockHash
 	}
 	return nil
 }
 
-func (blockChain *BlockChain) commitPath(child, ancestor types.Hash) error {
-	// Store ancestor to child path in a stack
-	path := make([]*core.Block, 0)
-	for child != ancestor {
-		currBlock, err := blockChain.GetBlockWithHash(child)
-		if err != nil {
-			return err
-		}
-
-		path = append(path, currBlock)
-		child = currBlock.Header.PrevBlockHash
-	}
-
+func (blockChain *BlockChain) commitPath(path []*core.Block) error {
 	// Commit blocks from ancestor to child
-	for i := len(path) - 1; i >= 0; i-- {
-		if err := blockChain.commitBlock(path[i]); err != nil {
+	for i, currBlock := range path {
+		if err := blockChain.commitBlock(currBlock); err != nil {
 			// Undo all commits
-			for j := i + 1; j < len(path); j++ {
+			for j := i - 1; j >= 0; j-- {
 				blockChain.revertBlock(path[j])
 			}
-			return nil
+			return err
 		}
 	}
 
 	return nil
 }
 
+// revertBlock reverts the transactions of block last to first, so that
+// spends of outputs created earlier in the block are undone first.
 func (blockChain *BlockChain) revertBlock(block *core.Block) error {
-	return blockChain.processTransacitons(
-		block,
-		func(tx *Transaction) error {
-			return blockChain.state.RevertTransaction(tx)
-		},
-		func(tx *Transaction) {
-			blockChain.state.CommitTransaciton(tx)
-		},
-	)
+	transactions := make([]*core.Transaction, len(block.Transactions))
+	for i, tx := range block.Transactions {
+		transactions[len(transactions)-1-i] = tx
+	}
+	commit := func(entry ledgerEntry) error {
+		return blockChain.commitTransaction(block, entry)
+	}
+	return blockChain.processTransacitons(transactions, blockChain.revertTransaction, commit)
 }
 
 func (blockChain *BlockChain) commitBlock(block *core.Block) error {
-	return blockChain.processTransacitons(
-		block,
-		func(tx *Transaction) error {
-			return blockChain.state.CommitTransaciton(tx)
-		},
-		func(tx *Transaction) {
-			blockChain.state.RevertTransaction(tx)
-		},
-	)
+	commit := func(entry ledgerEntry) error {
+		return blockChain.commitTransaction(block, entry)
+	}
+	return blockChain.processTransacitons(block.Transactions, commit, blockChain.revertTransaction)
 }
 
-func (blockChain *BlockChain) processTransacitons(
-	block *core.Block,
-	process func(*Transaction) error,
-	undo func(*Transaction),
-) error {
-
-	processedTx := make([]*Transaction, 0)
-	for _, tx := range block.Transactions {
-		transaction, err := NewTransactionFromCoreTransaction(tx)
-		if err != nil {
-			return err
+// commitTransaction applies the transaction of entry to the ledger; lock
+// scripts see the block's height and timestamp.
+func (blockChain *BlockChain) commitTransaction(block *core.Block, entry ledgerEntry) error {
+	switch transaction := entry.transaction.(type) {
+	case *UTXOTransaction:
+		ledger, ok := blockChain.state.(UTXOLedger)
+		if !ok {
+			return fmt.Errorf("ledger does not keep unspent outputs")
 		}
-
-		if err := process(transaction); err != nil {
-			// Undo processed transactions
-			for i := len(processedTx) - 1; i >= 0; i-- {
-				undo(processedTx[i])
-			}
-			return err
+		return ledger.CommitUTXOTransaction(transaction, block.Header.Height, block.Header.Timestamp)
+	case *MultisigTransaction:
+		return blockChain.state.CommitMultisigTransaction(transaction)
+	default:
+		if ledger, ok := blockChain.state.(UTXOLedger); ok {
+			return ledger.CommitTransactionAs(transaction.(*Transaction), entry.txHash)
 		}
+		return blockChain.state.CommitTransaciton(transaction.(*Transaction))
+	}
+}
 
-		processedTx = append(processedTx, transaction)
+func (blockChain *BlockChain) revertTransaction(entry ledgerEntry) error {
+	switch transaction := entry.transaction.(type) {
+	case *UTXOTransaction:
+		ledger, ok := blockChain.state.(UTXOLedger)
+		if !ok {
+			return fmt.Errorf("ledger does not keep unspent outputs")
+		}
+		return ledger.RevertUTXOTransaction(transaction)
+	case *MultisigTransaction:
+		return blockChain.state.RevertMultisigTransaction(transaction)
+	default:
+		if ledger, ok := blockChain.state.(UTXOLedger); ok {
+			return ledger.RevertTransactionAs(transaction.(*Transaction), entry.txHash)
+		}
+		return blockChain.state.RevertTransaction(transaction.(*Transaction))
 	}
-	return nil
 }
 
-func (blockChain *BlockChain) commonAncestor(blockHashA, blockHashB types.Hash) (types.Hash, error) {
-	hashChainA, err := blockChain.hashChain(blockHashA)
+// ledgerEntry is a transaction of a block as the ledger applies it. txHash
+// is the SignedHash of the core transaction, which names the outputs of
+// account style transactions in a UTXO ledger; repeating a payment signs
+// it again and so names new outputs.
+type ledgerEntry struct {
+	transaction any
+	txHash      types.Hash
+}
+
+// ledgerTransaction decodes what the ledger applies for tx: a
+// *UTXOTransaction, a *MultisigTransaction or a *Transaction. Transactions carrying other payloads,
+// such as consensus votes, do not touch the ledger.
+func (blockChain *BlockChain) ledgerTransaction(tx *core.Transaction) (ledgerEntry, bool) {
+	if utxoTx, err := NewUTXOTransactionFromCoreTransaction(tx); err == nil {
+		return ledgerEntry{transaction: utxoTx}, true
+	}
+	if multisigTx, err := NewMultisigTransactionFromCoreTransaction(tx); err == nil {
+		return ledgerEntry{transaction: multisigTx}, true
+	}
+
+	transaction, err := NewTransactionFromCoreTransaction(tx)
 	if err != nil {
-		return types.Hash{}, err
+		return ledgerEntry{}, false
 	}
-	hashChainB, err := blockChain.hashChain(blockHashB)
+	txHash, err := tx.SignedHash()
 	if err != nil {
-		return types.Hash{}, err
+		return ledgerEntry{}, false
 	}
+	return ledgerEntry{transaction: transaction, txHash: txHash}, true
+}
 
-	ancestor := types.Hash{}
-	minLen := min(len(hashChainA), len(hashChainB))
-	for i := 0; i < minLen; i++ {
-		if hashChainA[i] != hashChainB[i] {
-			break
-		}
-		ancestor = hashChainA[i]
-	}
+func (blockChain *BlockChain) processTransacitons(
+	transactions []*core.Transaction,
+	process func(ledgerEntry) error,
+	undo func(ledgerEntry) error,
+) error {
 
-	return ancestor, nil
-}
+	processed := make([]ledgerEntry, 0)
+	for _, tx := range transactions {
+		entry, ok := blockChain.ledgerTransaction(tx)
+		if !ok {
+			continue
+		}
 
-func (blockChain *BlockChain) hashChain(blockHash types.Hash) ([]types.Hash, error) {
-	chain := make([]types.Hash, 0)
-	currHash := blockHash
-	for !currHash.IsZero() {
-		currBlock, err := blockChain.GetBlockWithHash(currHash)
-		if err != nil {
-			return nil, err
+		if err := process(entry); err != nil {
+			// Undo processed transactions
+			for i := len(processed) - 1; i >= 0; i-- {
+				undo(processed[i])
+			}
+			return err
 		}
 
-		chain = append(chain, currHash)
-		currHash = currBlock.Header.PrevBlockHash
+		processed = append(processed, entry)
 	}
-	util.RevereseSlice(chain)
-	return chain, nil
+	return nil
 }
@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/tusharjoshi4531/block-chain.git/core"
 	"github.com/tusharjoshi4531/block-chain.git/crypto"
+	"github.com/tusharjoshi4531/block-chain.git/types"
 )
 
 func TestBlockChainLedger(t *testing.T) {
@@ -15,8 +16,8 @@ func TestBlockChainLedger(t *testing.T) {
 	privKey := crypto.GeneratePrivateKey()
 	bc := NewBlockChain(state, initBal)
 
-	bc.AddWallet("A")
-	bc.AddWallet("B")
+	state.AddWallet("A", initBal)
+	state.AddWallet("B", initBal)
 
 	// commonBlocks := make([]*core.Block, 0)
 
@@ -111,3 +112,257 @@ func createTransaction(t *testing.T, from, to string, val float64, privKey *ecds
 	assert.Nil(t, _tx.Sign(privKey))
 	return _tx
 }
+
+func TestReorgDepthLimit(t *testing.T) {
+	state := NewMemoryLedgerState()
+	privKey := crypto.GeneratePrivateKey()
+	config := DefaultChainConfig()
+	config.MaxReorgDepth = 2
+	bc := NewBlockChainWithConfig(state, 1000, config)
+	sub := bc.Subscribe(16, core.EventReorgRejected)
+	defer sub.Unsubscribe()
+
+	state.AddWallet("A", 1000)
+	state.AddWallet("B", 1000)
+
+	genesisHash, err := bc.GetGenesis().Hash()
+	assert.Nil(t, err)
+
+	// Main chain of 3 blocks
+	prevHash := genesisHash
+	for i := 1; i <= 3; i++ {
+		block := core.NewBlockWithHeaderInfo(uint32(i), prevHash)
+		block.AddTransaction(createTransaction(t, "A", "B", 10, privKey))
+		assert.Nil(t, bc.AddBlock(block))
+		prevHash, err = block.Hash()
+		assert.Nil(t, err)
+	}
+
+	// Competing fork from genesis; first blocks do not overtake the tip
+	prevHash = genesisHash
+	for i := 1; i <= 2; i++ {
+		block := core.NewBlockWithHeaderInfo(uint32(i), prevHash)
+		block.AddTransaction(createTransaction(t, "B", "A", 1, privKey))
+		assert.Nil(t, bc.AddBlock(block))
+		prevHash, err = block.Hash()
+		assert.Nil(t, err)
+	}
+
+	// Overtaking the tip would revert 3 blocks
+	block := core.NewBlockWithHeaderInfo(3, prevHash)
+	block.AddTransaction(createTransaction(t, "B", "A", 1, privKey))
+	assert.NotNil(t, bc.AddBlock(block))
+
+	event := <-sub.Events()
+	assert.Equal(t, block, event.Block)
+	assert.Equal(t, uint32(3), event.ReorgDepth)
+	assert.Equal(t, genesisHash, event.Ancestor)
+
+	balanceA, err := state.GetBalance("A")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(1000-30), balanceA)
+}
+
+func TestCheckpoint(t *testing.T) {
+	state := NewMemoryLedgerState()
+	privKey := crypto.GeneratePrivateKey()
+
+	prevHash, err := core.NewDefaultBlockChain().GetGenesis().Hash()
+	assert.Nil(t, err)
+
+	checkpointBlock := core.NewBlockWithHeaderInfo(1, prevHash)
+	checkpointBlock.AddTransaction(createTransaction(t, "A", "B", 10, privKey))
+	checkpointHash, err := checkpointBlock.Hash()
+	assert.Nil(t, err)
+
+	config := DefaultChainConfig()
+	config.Checkpoints[1] = checkpointHash
+	bc := NewBlockChainWithConfig(state, 1000, config)
+
+	state.AddWallet("A", 1000)
+	state.AddWallet("B", 1000)
+
+	otherBlock := core.NewBlockWithHeaderInfo(1, prevHash)
+	otherBlock.AddTransaction(createTransaction(t, "B", "A", 10, privKey))
+	assert.ErrorContains(t, bc.AddBlock(otherBlock), checkpointHash.String())
+
+	assert.Nil(t, bc.AddBlock(checkpointBlock))
+	assert.Equal(t, uint32(1), bc.Height())
+}
+
+func TestRegistrationTransaction(t *testing.T) {
+	state := NewMemoryLedgerState()
+	privKey := crypto.GeneratePrivateKey()
+	bc := NewBlockChain(state, 1000)
+
+	genesisHash, err := bc.GetGenesis().Hash()
+	assert.Nil(t, err)
+
+	signed := func(registration *Transaction) *core.Transaction {
+		tx, err := registration.ToCoreTransaction()
+		assert.Nil(t, err)
+		assert.Nil(t, tx.Sign(privKey))
+		return tx
+	}
+
+	// Registrations must record the chain's initial balance
+	block := core.NewBlockWithHeaderInfo(1, genesisHash)
+	block.AddTransaction(signed(NewRegistrationTransaction("A", 1000000)))
+	assert.NotNil(t, bc.AddBlock(block))
+	assert.Empty(t, bc.FilterTransactions(block))
+
+	block = core.NewBlockWithHeaderInfo(1, genesisHash)
+	block.AddTransaction(signed(NewRegistrationTransaction("A", 1000)))
+	assert.Nil(t, bc.AddBlock(block))
+	blockHash, err := block.Hash()
+	assert.Nil(t, err)
+
+	balance, err := state.GetBalance("A")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(1000), balance)
+
+	block = core.NewBlockWithHeaderInfo(2, blockHash)
+	block.AddTransaction(createTransaction(t, RewardSymbol, "A", 50, privKey))
+	assert.Nil(t, bc.AddBlock(block))
+
+	// A longer fork without the registration removes the wallet
+	prevHash := genesisHash
+	for height := uint32(1); height <= 3; height++ {
+		block = core.NewBlockWithHeaderInfo(height, prevHash)
+		block.AddTransaction(createTransaction(t, RewardSymbol, "B", float64(height), privKey))
+		assert.Nil(t, bc.AddBlock(block))
+		prevHash, err = block.Hash()
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, uint32(3), bc.Height())
+	assert.False(t, state.HasWallet("A"))
+}
+
+func TestEscrowAcrossReorg(t *testing.T) {
+	state := NewMemoryLedgerState()
+	assert.Nil(t, state.AddWallet("A", 100))
+	assert.Nil(t, state.AddWallet("B", 100))
+	privKey := crypto.GeneratePrivateKey()
+	bc := NewBlockChain(state, 100)
+
+	signed := func(tx *Transaction) *core.Transaction {
+		coreTx, err := tx.ToCoreTransaction()
+		assert.Nil(t, err)
+		assert.Nil(t, coreTx.Sign(privKey))
+		return coreTx
+	}
+	addBlock := func(height uint32, prevHash types.Hash, txs ...*core.Transaction) (types.Hash, error) {
+		block := core.NewBlockWithHeaderInfo(height, prevHash)
+		for _, tx := range txs {
+			block.AddTransaction(tx)
+		}
+		if err := bc.AddBlock(block); err != nil {
+			return types.Hash{}, err
+		}
+		return block.Hash()
+	}
+	balance := func(walletId string) float64 {
+		balance, err := state.GetBalance(walletId)
+		assert.Nil(t, err)
+		return balance
+	}
+
+	lock := core.TxLock{Height: 3}
+	genesisHash, err := bc.GetGenesis().Hash()
+	assert.Nil(t, err)
+	hash1, err := addBlock(1, genesisHash, signed(NewEscrowTransaction("A", "B", 40, lock)))
+	assert.Nil(t, err)
+	assert.Equal(t, float64(60), balance("A"))
+	assert.Equal(t, float64(100), balance("B"))
+	assert.Equal(t, []Escrow{{Amount: 40, Lock: lock}}, state.GetEscrows("B"))
+
+	// The release cannot be mined before the lock
+	release := signed(NewReleaseTransaction("B", 40, lock))
+	_, err = addBlock(2, hash1, release)
+	assert.NotNil(t, err)
+	hash2, err := addBlock(2, hash1)
+	assert.Nil(t, err)
+
+	_, err = addBlock(3, hash2, release)
+	assert.Nil(t, err)
+	assert.Equal(t, float64(140), balance("B"))
+	assert.Empty(t, state.GetEscrows("B"))
+
+	// A fork without the release puts the funds back in escrow
+	forkHash, err := addBlock(3, hash2, signed(NewTransaction("A", "B", 1)))
+	assert.Nil(t, err)
+	forkHash, err = addBlock(4, forkHash)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(4), bc.Height())
+	assert.Equal(t, float64(101), balance("B"))
+	assert.Equal(t, []Escrow{{Amount: 40, Lock: lock}}, state.GetEscrows("B"))
+
+	_, err = addBlock(5, forkHash, release)
+	assert.Nil(t, err)
+	assert.Equal(t, float64(141), balance("B"))
+
+	// Dropping the escrow block refunds the sender
+	prevHash := genesisHash
+	for height := uint32(1); height <= 6; height++ {
+		prevHash, err = addBlock(height, prevHash)
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, float64(100), balance("A"))
+	assert.Equal(t, float64(100), balance("B"))
+	assert.Empty(t, state.GetEscrows("B"))
+}
+
+func TestLedgerRejectedBlock(t *testing.T) {
+	state := NewMemoryLedgerState()
+	assert.Nil(t, state.AddWallet("A", 100))
+	assert.Nil(t, state.AddWallet("B", 100))
+	privKey := crypto.GeneratePrivateKey()
+	bc := NewBlockChain(state, 100)
+
+	genesisHash, err := bc.GetGenesis().Hash()
+	assert.Nil(t, err)
+	block1 := core.NewBlockWithHeaderInfo(1, genesisHash)
+	block1.AddTransaction(createTransaction(t, "A", "B", 10, privKey))
+	assert.Nil(t, bc.AddBlock(block1))
+	hash1, err := block1.Hash()
+	assert.Nil(t, err)
+
+	// An overspend keeps the tip and the ledger where they were
+	overspend := core.NewBlockWithHeaderInfo(2, hash1)
+	overspend.AddTransaction(createTransaction(t, "A", "B", 500, privKey))
+	assert.NotNil(t, bc.AddBlock(overspend))
+	assert.Equal(t, block1, bc.GetHeighestBlock())
+
+	block2 := core.NewBlockWithHeaderInfo(2, hash1)
+	block2.AddTransaction(createTransaction(t, "A", "B", 10, privKey))
+	assert.Nil(t, bc.AddBlock(block2))
+	hash2, err := block2.Hash()
+	assert.Nil(t, err)
+
+	// A fork failing part way through its reorg restores the old branch
+	fork1 := core.NewBlockWithHeaderInfo(1, genesisHash)
+	fork1.AddTransaction(createTransaction(t, "B", "A", 50, privKey))
+	assert.Nil(t, bc.AddBlock(fork1))
+	forkHash1, err := fork1.Hash()
+	assert.Nil(t, err)
+	fork2 := core.NewBlockWithHeaderInfo(2, forkHash1)
+	fork2.AddTransaction(createTransaction(t, "B", "A", 100, privKey))
+	assert.NotNil(t, bc.AddBlock(fork2))
+	assert.Equal(t, block2, bc.GetHeighestBlock())
+
+	balanceA, err := state.GetBalance("A")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(80), balanceA)
+	balanceB, err := state.GetBalance("B")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(120), balanceB)
+
+	// Miners leave out what the ledger would refuse
+	candidate := core.NewBlockWithHeaderInfo(3, hash2)
+	valid := createTransaction(t, "A", "B", 80, privKey)
+	candidate.AddTransaction(valid)
+	candidate.AddTransaction(createTransaction(t, "A", "B", 1, privKey))
+	core.FilterTransactions(bc, candidate)
+	assert.Equal(t, []*core.Transaction{valid}, candidate.Transactions)
+	assert.Nil(t, bc.AddBlock(candidate))
+}
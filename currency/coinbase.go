@@ -0,0 +1,83 @@
+package currency
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/tusharjoshi4531/block-chain.git/core"
+)
+
+// RewardSchedule mints InitialReward for each of the first HalvingInterval
+// blocks and halves the reward every HalvingInterval blocks after that. A
+// HalvingInterval of 0 never halves.
+type RewardSchedule struct {
+	InitialReward   float64
+	HalvingInterval uint16
+}
+
+func NewRewardSchedule(initialReward float64, halvingInterval uint16) RewardSchedule {
+	return RewardSchedule{
+		InitialReward:   initialReward,
+		HalvingInterval: halvingInterval,
+	}
+}
+
+// Reward is the amount minted by the coinbase of the block at height.
+func (schedule RewardSchedule) Reward(height uint32) float64 {
+	if height == 0 || schedule.HalvingInterval == 0 {
+		return schedule.InitialReward
+	}
+	halvings := (height - 1) / uint32(schedule.HalvingInterval)
+	return schedule.InitialReward / math.Pow(2, float64(halvings))
+}
+
+// BlockFees sums the fees of the currency transactions among transactions.
+func BlockFees(transactions []*core.Transaction) float64 {
+	fees := float64(0)
+	for _, tx := range transactions {
+		if utxoTx, err := NewUTXOTransactionFromCoreTransaction(tx); err == nil {
+			fees += utxoTx.Fee
+			continue
+		}
+		transaction, err := NewTransactionFromCoreTransaction(tx)
+		if err != nil {
+			continue
+		}
+		fees += transaction.Fee
+	}
+	return fees
+}
+
+// ValidateCoinbase requires the first transaction of block, and no other,
+// to be a coinbase for the block's height paying a wallet the scheduled
+// reward plus the fees of the remaining transactions.
+func ValidateCoinbase(block *core.Block, schedule RewardSchedule) error {
+	height := block.Header.Height
+	if len(block.Transactions) == 0 {
+		return fmt.Errorf("block at height (%d) has no coinbase transaction", height)
+	}
+
+	coinbase, err := NewTransactionFromCoreTransaction(block.Transactions[0])
+	if err != nil || coinbase.Type != core.TxTypeCoinbase {
+		return fmt.Errorf("first transaction of block at height (%d) is not a coinbase", height)
+	}
+	if coinbase.To == "" || IsSystemWallet(coinbase.To) {
+		return fmt.Errorf("coinbase of block at height (%d) pays (%s) instead of a wallet", height, coinbase.To)
+	}
+	if coinbase.Height != height {
+		return fmt.Errorf("coinbase for height (%d) found in block at height (%d)", coinbase.Height, height)
+	}
+
+	for i, tx := range block.Transactions[1:] {
+		transaction, err := NewTransactionFromCoreTransaction(tx)
+		if err == nil && transaction.Type == core.TxTypeCoinbase {
+			return fmt.Errorf("block at height (%d) has a second coinbase at index (%d)", height, i+1)
+		}
+	}
+
+	expected := schedule.Reward(height) + BlockFees(block.Transactions[1:])
+	if coinbase.Amount != expected {
+		return fmt.Errorf("coinbase of block at height (%d) pays (%f); expected (%f)", height, coinbase.Amount, expected)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package currency
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+)
+
+func TestRewardSchedule(t *testing.T) {
+	schedule := NewRewardSchedule(100, 10)
+	assert.Equal(t, float64(100), schedule.Reward(1))
+	assert.Equal(t, float64(100), schedule.Reward(10))
+	assert.Equal(t, float64(50), schedule.Reward(11))
+	assert.Equal(t, float64(25), schedule.Reward(21))
+
+	assert.Equal(t, float64(100), NewRewardSchedule(100, 0).Reward(1000))
+}
+
+func TestValidateCoinbase(t *testing.T) {
+	privKey := crypto.GeneratePrivateKey()
+	schedule := NewRewardSchedule(100, 10)
+	rewarder := NewRewarder(privKey, 100, 10)
+
+	transfer := NewTransaction("A", "B", 10)
+	transfer.Fee = 2
+	transferTx, err := transfer.ToCoreTransaction()
+	assert.Nil(t, err)
+	assert.Nil(t, transferTx.Sign(privKey))
+
+	newBlock := func() *core.Block {
+		block := core.NewBlockWithHeaderInfo(3, [32]byte{})
+		block.AddTransaction(transferTx)
+		coinbase, err := rewarder.GenerateReward("M", block)
+		assert.Nil(t, err)
+		block.PrependTransaction(coinbase)
+		return block
+	}
+
+	block := newBlock()
+	assert.Nil(t, ValidateCoinbase(block, schedule))
+	coinbase, err := NewTransactionFromCoreTransaction(block.Transactions[0])
+	assert.Nil(t, err)
+	assert.Equal(t, float64(102), coinbase.Amount)
+
+	// Missing coinbase
+	assert.NotNil(t, ValidateCoinbase(core.NewBlockWithHeaderInfo(3, [32]byte{}), schedule))
+
+	// Coinbase not first
+	block = newBlock()
+	block.Transactions[0], block.Transactions[1] = block.Transactions[1], block.Transactions[0]
+	assert.NotNil(t, ValidateCoinbase(block, schedule))
+
+	// Second coinbase
+	block = newBlock()
+	block.AddTransaction(createTransaction(t, RewardSymbol, "M", 1, privKey))
+	assert.NotNil(t, ValidateCoinbase(block, schedule))
+
+	// Coinbase from another height
+	block = newBlock()
+	block.Header.Height = 4
+	assert.NotNil(t, ValidateCoinbase(block, schedule))
+
+	// Wrong amount
+	block = core.NewBlockWithHeaderInfo(3, [32]byte{})
+	block.AddTransaction(createTransaction(t, RewardSymbol, "M", 1000, privKey))
+	assert.NotNil(t, ValidateCoinbase(block, schedule))
+
+	// Wrong recipient
+	block = core.NewBlockWithHeaderInfo(3, [32]byte{})
+	minted, err := NewCoinbaseTransaction(StakeSymbol, 100, 3).ToCoreTransaction()
+	assert.Nil(t, err)
+	block.AddTransaction(minted)
+	assert.NotNil(t, ValidateCoinbase(block, schedule))
+}
+
+func TestCoinbaseRulesInChain(t *testing.T) {
+	state := NewMemoryLedgerState()
+	assert.Nil(t, state.AddWallet("A", 100))
+	assert.Nil(t, state.AddWallet("B", 100))
+	privKey := crypto.GeneratePrivateKey()
+	config := DefaultChainConfig()
+	schedule := NewRewardSchedule(50, 10)
+	config.Reward = &schedule
+	bc := NewBlockChainWithConfig(state, 100, config)
+
+	genesisHash, err := bc.GetGenesis().Hash()
+	assert.Nil(t, err)
+
+	// Blocks minting more than the schedule allows are rejected
+	block := core.NewBlockWithHeaderInfo(1, genesisHash)
+	block.AddTransaction(createTransaction(t, RewardSymbol, "A", 1000, privKey))
+	assert.NotNil(t, bc.AddBlock(block))
+
+	transfer := NewTransaction("A", "B", 10)
+	transfer.Fee = 5
+	transferTx, err := transfer.ToCoreTransaction()
+	assert.Nil(t, err)
+	assert.Nil(t, transferTx.Sign(privKey))
+
+	block = core.NewBlockWithHeaderInfo(1, genesisHash)
+	block.AddTransaction(transferTx)
+	coinbase, err := NewRewarder(privKey, 50, 10).GenerateReward("B", block)
+	assert.Nil(t, err)
+	block.PrependTransaction(coinbase)
+	assert.Nil(t, bc.AddBlock(block))
+
+	balance, err := state.GetBalance("A")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(85), balance)
+	balance, err = state.GetBalance("B")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(165), balance)
+}
+
+func TestPoolRejectsCoinbase(t *testing.T) {
+	privKey := crypto.GeneratePrivateKey()
+	txPool := core.NewDefaultTransactionPool()
+
+	coinbase := createTransaction(t, RewardSymbol, "A", 100, privKey)
+	assert.NotNil(t, txPool.AddTransaction(coinbase))
+
+	mislabeled := NewTransaction(RewardSymbol, "A", 100)
+	mislabeled.Type = core.TxTypeTransfer
+	mislabeledTx, err := mislabeled.ToCoreTransaction()
+	assert.Nil(t, err)
+	assert.NotNil(t, txPool.AddTransaction(mislabeledTx))
+
+	assert.Nil(t, txPool.AddTransaction(createTransaction(t, "A", "B", 10, privKey)))
+	assert.Equal(t, 1, txPool.Len())
+}
@@ -1,25 +1,73 @@
 package currency
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/tusharjoshi4531/block-chain.git/core"
+)
 
 const RewardSymbol = "::"
 
+// StakeSymbol stands for the locked stake of a validator. Stake
+// transactions send to it, unstakes send from it and slashes send from it
+// to RewardSymbol.
+const StakeSymbol = "::stake"
+
+// RegisterSymbol is the sender of wallet registrations.
+const RegisterSymbol = "::register"
+
+// EscrowSymbol is the sender of escrow releases.
+const EscrowSymbol = "::escrow"
+
+func IsSystemWallet(id string) bool {
+	return id == RewardSymbol || id == StakeSymbol || id == RegisterSymbol || id == EscrowSymbol
+}
+
+// Escrow is an amount held for a wallet until a release at or after Lock
+// pays it out.
+type Escrow struct {
+	Amount float64
+	Lock   core.TxLock
+}
+
 type LedgerState interface {
 	CommitTransaciton(transaction *Transaction) error
 	RevertTransaction(transaction *Transaction) error
+	CommitMultisigTransaction(transaction *MultisigTransaction) error
+	RevertMultisigTransaction(transaction *MultisigTransaction) error
+	HasUsedMultisigNonce(walletId string, nonce uint64) bool
+	HasUsedSlashEvidence(key string) bool
 	HasWallet(id string) bool
 	AddWallet(id string, balance float64) error
 	GetBalance(id string) (float64, error)
 	GetWallets() []string
+	GetStake(validator string) float64
+	GetStakes() map[string]float64
+	GetEscrows(walletId string) []Escrow
+	GetAssets() []Asset
+	GetAssetBalance(walletId string, asset string) (float64, error)
+	GetAssetBalances(walletId string) map[string]float64
 }
 
 type MemoryLedgerState struct {
-	balance map[string]float64
+	assetBook
+	multisigNonces
+	slashEvidence
+	balance     map[string]float64
+	stakes      map[string]float64
+	stakeOwners map[string]string
+	escrows     map[string][]Escrow
 }
 
 func NewMemoryLedgerState() *MemoryLedgerState {
 	return &MemoryLedgerState{
-		balance: make(map[string]float64),
+		assetBook:      newAssetBook(),
+		multisigNonces: newMultisigNonces(),
+		slashEvidence:  newSlashEvidence(),
+		balance:        make(map[string]float64),
+		stakes:         make(map[string]float64),
+		stakeOwners:    make(map[string]string),
+		escrows:        make(map[string][]Escrow),
 	}
 }
 
@@ -28,42 +76,262 @@ func (state *MemoryLedgerState) HasWallet(id string) bool {
 	return ok
 }
 
+// CommitTransaciton applies a transaction according to its type.
 func (state *MemoryLedgerState) CommitTransaciton(transaction *Transaction) error {
 	from, to := transaction.From, transaction.To
-	amt := transaction.Amount
+	validator, amt := transaction.Validator, transaction.Amount
+
+	switch transaction.Type {
+	case core.TxTypeTransfer:
+		if transaction.Asset != "" {
+			return state.transferAsset(transaction.Asset, from, to, amt, transaction.Fee)
+		}
+		return state.transfer(from, to, amt, transaction.Fee)
 
-	if from == RewardSymbol {
+	case core.TxTypeIssueAsset:
+		if !state.HasWallet(from) {
+			return fmt.Errorf("no member with id (%s) is present in ledger", from)
+		}
+		return state.issue(Asset{Id: transaction.Asset, Issuer: from, Supply: amt})
+
+	case core.TxTypeCoinbase:
 		state.balance[to] += amt
 		return nil
-	}
 
-	if to == RewardSymbol {
+	case core.TxTypeRegistration:
+		return state.AddWallet(to, amt)
+
+	case core.TxTypeStake:
+		if owner, ok := state.stakeOwners[validator]; ok && owner != from {
+			return fmt.Errorf("validator (%s) is staked by (%s)", validator, owner)
+		}
+		if !state.HasWallet(from) {
+			return fmt.Errorf("no member with id (%s) is present in ledger", from)
+		}
+		if state.balance[from] < amt {
+			return fmt.Errorf("sender (%s) does not have enough balance", from)
+		}
 		state.balance[from] -= amt
+		state.stakes[validator] += amt
+		state.stakeOwners[validator] = from
+		return nil
+
+	case core.TxTypeUnstake:
+		if owner := state.stakeOwners[validator]; owner != to {
+			return fmt.Errorf("stake of validator (%s) is not owned by (%s)", validator, to)
+		}
+		if state.stakes[validator] < amt {
+			return fmt.Errorf("validator (%s) does not have (%f) stake to unlock", validator, amt)
+		}
+		state.stakes[validator] -= amt
+		state.balance[to] += amt
+		return nil
+
+	case core.TxTypeSlash:
+		if state.stakes[validator] < amt {
+			return fmt.Errorf("validator (%s) does not have (%f) stake to slash", validator, amt)
+		}
+		if err := state.slashEvidence.use(transaction.Proof); err != nil {
+			return err
+		}
+		state.stakes[validator] -= amt
 		return nil
+
+	case core.TxTypeEscrow:
+		if !state.HasWallet(to) {
+			return fmt.Errorf("no member with id (%s) is present in ledger", to)
+		}
+		if !state.HasWallet(from) {
+			return fmt.Errorf("no member with id (%s) is present in ledger", from)
+		}
+		if state.balance[from] < amt {
+			return fmt.Errorf("sender (%s) does not have enough balance", from)
+		}
+		state.balance[from] -= amt
+		state.escrows[to] = append(state.escrows[to], Escrow{Amount: amt, Lock: transaction.Lock})
+		return nil
+
+	case core.TxTypeRelease:
+		if !state.HasWallet(to) {
+			return fmt.Errorf("no member with id (%s) is present in ledger", to)
+		}
+		if !state.removeEscrow(to, Escrow{Amount: amt, Lock: transaction.Lock}) {
+			return fmt.Errorf("wallet (%s) has no escrow of (%f) with the release lock", to, amt)
+		}
+		state.balance[to] += amt
+		return nil
+
+	default:
+		return fmt.Errorf("ledger cannot apply transaction type (%s)", core.TxTypeToString(transaction.Type))
 	}
+}
+
+// RevertTransaction undoes a transaction previously committed with
+// CommitTransaciton.
+func (state *MemoryLedgerState) RevertTransaction(transaction *Transaction) error {
+	from, to := transaction.From, transaction.To
+	validator, amt := transaction.Validator, transaction.Amount
+
+	switch transaction.Type {
+	case core.TxTypeTransfer:
+		if !state.HasWallet(to) || !state.HasWallet(from) {
+			return fmt.Errorf("transfer from (%s) to (%s) names a missing wallet", from, to)
+		}
+		if transaction.Asset != "" {
+			if err := state.assetBook.transfer(transaction.Asset, to, from, amt); err != nil {
+				return err
+			}
+			state.balance[from] += transaction.Fee
+			return nil
+		}
+		if state.balance[to] < amt {
+			return fmt.Errorf("sender (%s) does not have enough balance", to)
+		}
+		state.balance[to] -= amt
+		state.balance[from] += amt + transaction.Fee
+		return nil
+
+	case core.TxTypeCoinbase:
+		state.balance[to] -= amt
+		return nil
+
+	case core.TxTypeIssueAsset:
+		return state.unissue(transaction.Asset)
+
+	case core.TxTypeRegistration:
+		if !state.HasWallet(to) {
+			return fmt.Errorf("no member with id (%s) is present in ledger", to)
+		}
+		delete(state.balance, to)
+		return nil
+
+	case core.TxTypeStake:
+		if state.stakes[validator] < amt {
+			return fmt.Errorf("validator (%s) does not have (%f) stake to unlock", validator, amt)
+		}
+		state.stakes[validator] -= amt
+		state.balance[from] += amt
+		return nil
+
+	case core.TxTypeUnstake:
+		if state.balance[to] < amt {
+			return fmt.Errorf("sender (%s) does not have enough balance", to)
+		}
+		state.balance[to] -= amt
+		state.stakes[validator] += amt
+		return nil
+
+	case core.TxTypeSlash:
+		state.slashEvidence.release(transaction.Proof)
+		state.stakes[validator] += amt
+		return nil
+
+	case core.TxTypeEscrow:
+		if !state.removeEscrow(to, Escrow{Amount: amt, Lock: transaction.Lock}) {
+			return fmt.Errorf("wallet (%s) has no escrow of (%f) to revert", to, amt)
+		}
+		state.balance[from] += amt
+		return nil
 
+	case core.TxTypeRelease:
+		if state.balance[to] < amt {
+			return fmt.Errorf("sender (%s) does not have enough balance", to)
+		}
+		state.balance[to] -= amt
+		state.escrows[to] = append(state.escrows[to], Escrow{Amount: amt, Lock: transaction.Lock})
+		return nil
+
+	default:
+		return fmt.Errorf("ledger cannot revert transaction type (%s)", core.TxTypeToString(transaction.Type))
+	}
+}
+
+// CommitMultisigTransaction applies the transfer of a multisig spend and
+// uses up its nonce.
+func (state *MemoryLedgerState) CommitMultisigTransaction(transaction *MultisigTransaction) error {
+	walletId := transaction.Wallet.Address()
+	if err := state.multisigNonces.use(walletId, transaction.Nonce); err != nil {
+		return err
+	}
+	if err := state.CommitTransaciton(transaction.Transfer); err != nil {
+		state.multisigNonces.release(walletId, transaction.Nonce)
+		return err
+	}
+	return nil
+}
+
+// RevertMultisigTransaction undoes CommitMultisigTransaction, freeing the
+// nonce again.
+func (state *MemoryLedgerState) RevertMultisigTransaction(transaction *MultisigTransaction) error {
+	if err := state.RevertTransaction(transaction.Transfer); err != nil {
+		return err
+	}
+	state.multisigNonces.release(transaction.Wallet.Address(), transaction.Nonce)
+	return nil
+}
+
+// transfer moves amt from one wallet to another and takes fee from the
+// sender; the fee is paid out by the block's coinbase.
+func (state *MemoryLedgerState) transfer(from, to string, amt, fee float64) error {
 	if !state.HasWallet(to) {
 		return fmt.Errorf("no member with id (%s) is present in ledger", to)
 	}
-
 	if !state.HasWallet(from) {
 		return fmt.Errorf("no member with id (%s) is present in ledger", from)
 	}
-
-	fromAmt := state.balance[from]
-	if fromAmt < amt {
+	if state.balance[from] < amt+fee {
 		return fmt.Errorf("sender (%s) does not have enough balance", from)
 	}
 
-	state.balance[from] -= amt
+	state.balance[from] -= amt + fee
 	state.balance[to] += amt
+	return nil
+}
+
+// transferAsset moves amt of asset between wallets and takes fee in the
+// native currency from the sender.
+func (state *MemoryLedgerState) transferAsset(asset string, from, to string, amt, fee float64) error {
+	if !state.HasWallet(to) {
+		return fmt.Errorf("no member with id (%s) is present in ledger", to)
+	}
+	if !state.HasWallet(from) {
+		return fmt.Errorf("no member with id (%s) is present in ledger", from)
+	}
+	if state.balance[from] < fee {
+		return fmt.Errorf("sender (%s) does not have enough balance", from)
+	}
+	if err := state.assetBook.transfer(asset, from, to, amt); err != nil {
+		return err
+	}
 
+	state.balance[from] -= fee
 	return nil
 }
 
-func (state *MemoryLedgerState) RevertTransaction(transaction *Transaction) error {
-	revTransaction := NewTransaction(transaction.To, transaction.From, transaction.Amount)
-	return state.CommitTransaciton(revTransaction)
+func (state *MemoryLedgerState) removeEscrow(walletId string, escrow Escrow) bool {
+	escrows, ok := removeEscrow(state.escrows[walletId], escrow)
+	if !ok {
+		return false
+	}
+	if len(escrows) == 0 {
+		delete(state.escrows, walletId)
+	} else {
+		state.escrows[walletId] = escrows
+	}
+	return true
+}
+
+// removeEscrow drops one escrow equal to escrow from escrows. Equal escrows
+// are interchangeable, so the most recent one is taken.
+func removeEscrow(escrows []Escrow, escrow Escrow) ([]Escrow, bool) {
+	for i := len(escrows) - 1; i >= 0; i-- {
+		if escrows[i] == escrow {
+			remaining := make([]Escrow, 0, len(escrows)-1)
+			remaining = append(remaining, escrows[:i]...)
+			return append(remaining, escrows[i+1:]...), true
+		}
+	}
+	return escrows, false
 }
 
 func (state *MemoryLedgerState) AddWallet(walletId string, balance float64) error {
@@ -89,3 +357,24 @@ func (state *MemoryLedgerState) GetWallets() []string {
 	}
 	return members
 }
+
+func (state *MemoryLedgerState) GetStake(validator string) float64 {
+	return state.stakes[validator]
+}
+
+func (state *MemoryLedgerState) GetStakes() map[string]float64 {
+	stakes := make(map[string]float64, len(state.stakes))
+	for validator, stake := range state.stakes {
+		if stake > 0 {
+			stakes[validator] = stake
+		}
+	}
+	return stakes
+}
+
+// GetEscrows returns the funds held for the wallet, oldest first.
+func (state *MemoryLedgerState) GetEscrows(walletId string) []Escrow {
+	escrows := make([]Escrow, len(state.escrows[walletId]))
+	copy(escrows, state.escrows[walletId])
+	return escrows
+}
@@ -0,0 +1,394 @@
+package currency
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+	"github.com/tusharjoshi4531/block-chain.git/types"
+	"github.com/tusharjoshi4531/block-chain.git/util"
+)
+
+// MultisigPrefix starts the address of every multisig wallet. Funds at such
+// addresses can only be spent by multisig transactions.
+const MultisigPrefix = "msig:"
+
+const MaxMultisigKeys = 16
+
+const multisigEncodingVersion uint8 = 1
+
+func init() {
+	core.RegisterTxType(core.TxTypeMultisig, core.TxTypeHandler{
+		Name: "Multisig",
+		Decode: func(payload []byte) (any, error) {
+			tx := &MultisigTransaction{}
+			if err := util.DecodeFromBytes(payload, tx); err != nil {
+				return nil, err
+			}
+			return tx, nil
+		},
+		Validate: func(_ *core.Transaction, decoded any) error {
+			return decoded.(*MultisigTransaction).Verify()
+		},
+		Lock: func(decoded any) core.TxLock {
+			return decoded.(*MultisigTransaction).Transfer.Lock
+		},
+	})
+}
+
+// MultisigWallet is an m-of-n wallet: spending from its address needs
+// signatures from Threshold of its Keys. Keys are kept sorted by their
+// encoding so the same set always yields the same address.
+type MultisigWallet struct {
+	Threshold uint8
+	Keys      []*ecdsa.PublicKey
+}
+
+func NewMultisigWallet(threshold uint8, keys []*ecdsa.PublicKey) (*MultisigWallet, error) {
+	sorted := make([]*ecdsa.PublicKey, len(keys))
+	copy(sorted, keys)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(crypto.PublicKeyBytes(sorted[i]), crypto.PublicKeyBytes(sorted[j])) < 0
+	})
+
+	wallet := &MultisigWallet{
+		Threshold: threshold,
+		Keys:      sorted,
+	}
+	if err := wallet.validate(); err != nil {
+		return nil, err
+	}
+	return wallet, nil
+}
+
+func (wallet *MultisigWallet) validate() error {
+	if len(wallet.Keys) == 0 || len(wallet.Keys) > MaxMultisigKeys {
+		return fmt.Errorf("multisig wallet needs between 1 and (%d) keys; found (%d)", MaxMultisigKeys, len(wallet.Keys))
+	}
+	if wallet.Threshold == 0 || int(wallet.Threshold) > len(wallet.Keys) {
+		return fmt.Errorf("threshold (%d) must be between 1 and the number of keys (%d)", wallet.Threshold, len(wallet.Keys))
+	}
+	for i := 1; i < len(wallet.Keys); i++ {
+		if bytes.Compare(crypto.PublicKeyBytes(wallet.Keys[i-1]), crypto.PublicKeyBytes(wallet.Keys[i])) >= 0 {
+			return fmt.Errorf("multisig keys must be distinct and sorted")
+		}
+	}
+	return nil
+}
+
+// Address derives the wallet id from the threshold and the sorted keys.
+func (wallet *MultisigWallet) Address() string {
+	hash := sha256.Sum256(wallet.Bytes())
+	return MultisigPrefix + hex.EncodeToString(hash[:20])
+}
+
+func (wallet *MultisigWallet) KeyIndex(key *ecdsa.PublicKey) (int, bool) {
+	keyBytes := crypto.PublicKeyBytes(key)
+	for i, walletKey := range wallet.Keys {
+		if bytes.Equal(keyBytes, crypto.PublicKeyBytes(walletKey)) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// Encode writes the encoding version, the threshold and the length
+// prefixed list of keys.
+func (wallet *MultisigWallet) Encode(w io.Writer) error {
+	cw := util.NewCanonicalWriter(w)
+	cw.WriteUint8(multisigEncodingVersion)
+	cw.WriteUint8(wallet.Threshold)
+	cw.WriteUint8(uint8(len(wallet.Keys)))
+	for _, key := range wallet.Keys {
+		cw.WriteBytes(crypto.PublicKeyBytes(key))
+	}
+	return cw.Err()
+}
+
+func (wallet *MultisigWallet) Decode(r io.Reader) error {
+	cr := util.NewCanonicalReader(r)
+	cr.ReadVersion(multisigEncodingVersion)
+	wallet.Threshold = cr.ReadUint8()
+	numKeys := cr.ReadUint8()
+	if err := cr.Err(); err != nil {
+		return err
+	}
+	if int(numKeys) > MaxMultisigKeys {
+		return fmt.Errorf("multisig wallet has (%d) keys; maximum is (%d)", numKeys, MaxMultisigKeys)
+	}
+
+	wallet.Keys = make([]*ecdsa.PublicKey, numKeys)
+	for i := range wallet.Keys {
+		keyBytes := cr.ReadBytes()
+		if err := cr.Err(); err != nil {
+			return err
+		}
+		key, err := crypto.PublicKeyFromBytes(keyBytes)
+		if err != nil {
+			return err
+		}
+		wallet.Keys[i] = key
+	}
+	return wallet.validate()
+}
+
+func (wallet *MultisigWallet) Bytes() []byte {
+	buf := &bytes.Buffer{}
+	// Writes to a buffer only fail for invalid wallets, which cannot be built
+	wallet.Encode(buf)
+	return buf.Bytes()
+}
+
+func IsMultisigAddress(id string) bool {
+	return strings.HasPrefix(id, MultisigPrefix)
+}
+
+// MultisigSignature is the approval of the wallet key at index Key.
+type MultisigSignature struct {
+	Key       uint8
+	Signature *crypto.Signature
+}
+
+// MultisigTransaction spends from a multisig wallet. Transfer moves the
+// funds and Signatures approve its SigningHash; Nonce keeps otherwise equal
+// spends distinct. Signatures are ordered by key index.
+type MultisigTransaction struct {
+	Wallet     *MultisigWallet
+	Transfer   *Transaction
+	Nonce      uint64
+	Signatures []MultisigSignature
+}
+
+func NewMultisigTransaction(wallet *MultisigWallet, to string, amount, fee float64, nonce uint64) *MultisigTransaction {
+	transfer := NewTransaction(wallet.Address(), to, amount)
+	transfer.Fee = fee
+	return &MultisigTransaction{
+		Wallet:   wallet,
+		Transfer: transfer,
+		Nonce:    nonce,
+	}
+}
+
+// SigningHash commits to the wallet, the transfer and the nonce but not to
+// the signatures, so key holders can sign independently.
+func (tx *MultisigTransaction) SigningHash() (types.Hash, error) {
+	buf := &bytes.Buffer{}
+	if err := tx.encodeUnsigned(buf); err != nil {
+		return types.Hash{}, err
+	}
+	return sha256.Sum256(buf.Bytes()), nil
+}
+
+// Approve adds the signature of privKey, replacing any earlier signature of
+// the same key.
+func (tx *MultisigTransaction) Approve(privKey *ecdsa.PrivateKey) error {
+	index, ok := tx.Wallet.KeyIndex(&privKey.PublicKey)
+	if !ok {
+		return fmt.Errorf("key is not a member of multisig wallet (%s)", tx.Wallet.Address())
+	}
+
+	hash, err := tx.SigningHash()
+	if err != nil {
+		return err
+	}
+	sig, err := crypto.SignBytes(privKey, hash[:])
+	if err != nil {
+		return err
+	}
+
+	signatures := make([]MultisigSignature, 0, len(tx.Signatures)+1)
+	for _, signature := range tx.Signatures {
+		if int(signature.Key) != index {
+			signatures = append(signatures, signature)
+		}
+	}
+	signatures = append(signatures, MultisigSignature{Key: uint8(index), Signature: sig})
+	sort.Slice(signatures, func(i, j int) bool {
+		return signatures[i].Key < signatures[j].Key
+	})
+	tx.Signatures = signatures
+	return nil
+}
+
+// Verify checks that the transfer spends from the wallet and that at least
+// Threshold distinct keys signed it.
+func (tx *MultisigTransaction) Verify() error {
+	if err := tx.Wallet.validate(); err != nil {
+		return err
+	}
+	if tx.Transfer.Type != core.TxTypeTransfer || tx.Transfer.From != tx.Wallet.Address() {
+		return fmt.Errorf("multisig transaction must transfer from (%s)", tx.Wallet.Address())
+	}
+	if err := validateCurrencyTransaction(tx.Transfer); err != nil {
+		return err
+	}
+
+	hash, err := tx.SigningHash()
+	if err != nil {
+		return err
+	}
+	for i, signature := range tx.Signatures {
+		if i > 0 && signature.Key <= tx.Signatures[i-1].Key {
+			return fmt.Errorf("multisig signatures must be ordered by distinct key index")
+		}
+		if int(signature.Key) >= len(tx.Wallet.Keys) {
+			return fmt.Errorf("multisig signature names key (%d) of (%d)", signature.Key, len(tx.Wallet.Keys))
+		}
+		if !signature.Signature.IsLowS() {
+			return fmt.Errorf("multisig signature of key (%d) is not in low-S form", signature.Key)
+		}
+		if !signature.Signature.Verify(tx.Wallet.Keys[signature.Key], hash[:]) {
+			return fmt.Errorf("invalid multisig signature of key (%d)", signature.Key)
+		}
+	}
+	if len(tx.Signatures) < int(tx.Wallet.Threshold) {
+		return fmt.Errorf("multisig transaction has (%d) of (%d) required signatures", len(tx.Signatures), tx.Wallet.Threshold)
+	}
+	return nil
+}
+
+func (tx *MultisigTransaction) encodeUnsigned(w io.Writer) error {
+	if err := tx.Wallet.Encode(w); err != nil {
+		return err
+	}
+	if err := tx.Transfer.Encode(w); err != nil {
+		return err
+	}
+	cw := util.NewCanonicalWriter(w)
+	cw.WriteUint64(tx.Nonce)
+	return cw.Err()
+}
+
+// Encode writes the wallet, the transfer, the nonce and the signatures.
+func (tx *MultisigTransaction) Encode(w io.Writer) error {
+	if err := tx.encodeUnsigned(w); err != nil {
+		return err
+	}
+
+	cw := util.NewCanonicalWriter(w)
+	cw.WriteUint8(uint8(len(tx.Signatures)))
+	if err := cw.Err(); err != nil {
+		return err
+	}
+	for _, signature := range tx.Signatures {
+		cw.WriteUint8(signature.Key)
+		if err := cw.Err(); err != nil {
+			return err
+		}
+		if err := signature.Signature.Encode(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tx *MultisigTransaction) Decode(r io.Reader) error {
+	tx.Wallet = &MultisigWallet{}
+	if err := tx.Wallet.Decode(r); err != nil {
+		return err
+	}
+	tx.Transfer = &Transaction{Type: core.TxTypeTransfer}
+	if err := tx.Transfer.Decode(r); err != nil {
+		return err
+	}
+
+	cr := util.NewCanonicalReader(r)
+	tx.Nonce = cr.ReadUint64()
+	numSignatures := cr.ReadUint8()
+	if err := cr.Err(); err != nil {
+		return err
+	}
+	if int(numSignatures) > MaxMultisigKeys {
+		return fmt.Errorf("multisig transaction has (%d) signatures; maximum is (%d)", numSignatures, MaxMultisigKeys)
+	}
+
+	tx.Signatures = make([]MultisigSignature, numSignatures)
+	for i := range tx.Signatures {
+		tx.Signatures[i].Key = cr.ReadUint8()
+		if err := cr.Err(); err != nil {
+			return err
+		}
+		tx.Signatures[i].Signature = &crypto.Signature{}
+		if err := tx.Signatures[i].Signature.Decode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tx *MultisigTransaction) Bytes() ([]byte, error) {
+	return util.EncodeToBytes(tx)
+}
+
+func DecodeMultisigTransaction(data []byte) (*MultisigTransaction, error) {
+	tx := &MultisigTransaction{}
+	if err := tx.Decode(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// NewMultisigTransactionFromCoreTransaction decodes a multisig envelope.
+func NewMultisigTransactionFromCoreTransaction(tx *core.Transaction) (*MultisigTransaction, error) {
+	txType, decoded, err := core.DecodeTransaction(tx)
+	if err != nil {
+		return nil, err
+	}
+	multisigTx, ok := decoded.(*MultisigTransaction)
+	if !ok {
+		return nil, fmt.Errorf("transaction type (%s) is not a multisig transaction", core.TxTypeToString(txType))
+	}
+	return multisigTx, nil
+}
+
+// ToCoreTransaction wraps the spend in an envelope. The submitter signs the
+// returned transaction; only the multisig signatures authorize the spend.
+func (tx *MultisigTransaction) ToCoreTransaction() (*core.Transaction, error) {
+	payload, err := tx.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	return core.NewTypedTransaction(core.TxTypeMultisig, payload)
+}
+
+// multisigNonces records the nonces each multisig wallet has spent with, so
+// that a mined spend cannot be applied again under a new hash.
+type multisigNonces struct {
+	used map[string]map[uint64]struct{}
+}
+
+func newMultisigNonces() multisigNonces {
+	return multisigNonces{used: make(map[string]map[uint64]struct{})}
+}
+
+func (nonces *multisigNonces) use(walletId string, nonce uint64) error {
+	if nonces.HasUsedMultisigNonce(walletId, nonce) {
+		return fmt.Errorf("nonce (%d) of multisig wallet (%s) is already used", nonce, walletId)
+	}
+	if _, ok := nonces.used[walletId]; !ok {
+		nonces.used[walletId] = make(map[uint64]struct{})
+	}
+	nonces.used[walletId][nonce] = struct{}{}
+	return nil
+}
+
+func (nonces *multisigNonces) release(walletId string, nonce uint64) {
+	delete(nonces.used[walletId], nonce)
+	if len(nonces.used[walletId]) == 0 {
+		delete(nonces.used, walletId)
+	}
+}
+
+// HasUsedMultisigNonce reports whether a committed spend of the wallet
+// carried nonce.
+func (nonces *multisigNonces) HasUsedMultisigNonce(walletId string, nonce uint64) bool {
+	_, ok := nonces.used[walletId][nonce]
+	return ok
+}
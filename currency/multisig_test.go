@@ -0,0 +1,206 @@
+package currency
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+	"github.com/tusharjoshi4531/block-chain.git/types"
+)
+
+func createMultisigKeys(n int) []*ecdsa.PrivateKey {
+	keys := make([]*ecdsa.PrivateKey, n)
+	for i := range keys {
+		keys[i] = crypto.GeneratePrivateKey()
+	}
+	return keys
+}
+
+func publicKeys(privKeys []*ecdsa.PrivateKey) []*ecdsa.PublicKey {
+	keys := make([]*ecdsa.PublicKey, len(privKeys))
+	for i, privKey := range privKeys {
+		keys[i] = &privKey.PublicKey
+	}
+	return keys
+}
+
+func TestMultisigWallet(t *testing.T) {
+	privKeys := createMultisigKeys(3)
+	keys := publicKeys(privKeys)
+
+	wallet, err := NewMultisigWallet(2, keys)
+	assert.Nil(t, err)
+	assert.True(t, IsMultisigAddress(wallet.Address()))
+
+	// The address does not depend on the order of the keys
+	reversed, err := NewMultisigWallet(2, []*ecdsa.PublicKey{keys[2], keys[1], keys[0]})
+	assert.Nil(t, err)
+	assert.Equal(t, wallet.Address(), reversed.Address())
+
+	other, err := NewMultisigWallet(3, keys)
+	assert.Nil(t, err)
+	assert.NotEqual(t, wallet.Address(), other.Address())
+
+	_, err = NewMultisigWallet(4, keys)
+	assert.NotNil(t, err)
+	_, err = NewMultisigWallet(0, keys)
+	assert.NotNil(t, err)
+	_, err = NewMultisigWallet(1, []*ecdsa.PublicKey{keys[0], keys[0]})
+	assert.NotNil(t, err)
+}
+
+func TestMultisigThreshold(t *testing.T) {
+	privKeys := createMultisigKeys(3)
+	wallet, err := NewMultisigWallet(2, publicKeys(privKeys))
+	assert.Nil(t, err)
+
+	tx := NewMultisigTransaction(wallet, "B", 10, 1, 1)
+	assert.Nil(t, tx.Approve(privKeys[0]))
+	assert.NotNil(t, tx.Verify())
+
+	// Signing twice with the same key does not count twice
+	assert.Nil(t, tx.Approve(privKeys[0]))
+	assert.Equal(t, 1, len(tx.Signatures))
+	assert.NotNil(t, tx.Verify())
+
+	assert.NotNil(t, tx.Approve(crypto.GeneratePrivateKey()))
+
+	// Partial signatures survive a round trip through their encoding
+	data, err := tx.Bytes()
+	assert.Nil(t, err)
+	tx, err = DecodeMultisigTransaction(data)
+	assert.Nil(t, err)
+	assert.Nil(t, tx.Approve(privKeys[2]))
+	assert.Nil(t, tx.Verify())
+
+	// Signatures do not carry over to another spend
+	tx.Transfer.Amount = 100
+	assert.NotNil(t, tx.Verify())
+}
+
+func TestMultisigSpend(t *testing.T) {
+	privKeys := createMultisigKeys(2)
+	wallet, err := NewMultisigWallet(2, publicKeys(privKeys))
+	assert.Nil(t, err)
+	address := wallet.Address()
+
+	state := NewMemoryLedgerState()
+	assert.Nil(t, state.AddWallet(address, 100))
+	assert.Nil(t, state.AddWallet("B", 100))
+	submitter := crypto.GeneratePrivateKey()
+	bc := NewBlockChain(state, 100)
+	prevHash, err := bc.GetGenesis().Hash()
+	assert.Nil(t, err)
+
+	// Plain transactions cannot spend from a multisig wallet
+	txPool := core.NewDefaultTransactionPool()
+	plain := createTransaction(t, address, "B", 10, submitter)
+	assert.NotNil(t, txPool.AddTransaction(plain))
+	block := core.NewBlockWithHeaderInfo(1, prevHash)
+	block.AddTransaction(plain)
+	assert.NotNil(t, bc.AddBlock(block))
+
+	spend := NewMultisigTransaction(wallet, "B", 30, 0, 1)
+	for _, privKey := range privKeys {
+		assert.Nil(t, spend.Approve(privKey))
+	}
+	spendTx, err := spend.ToCoreTransaction()
+	assert.Nil(t, err)
+	assert.Nil(t, spendTx.Sign(submitter))
+	assert.Nil(t, txPool.AddTransaction(spendTx))
+
+	decoded, err := NewTransactionFromCoreTransaction(spendTx)
+	assert.Nil(t, err)
+	assert.Equal(t, address, decoded.From)
+
+	block = core.NewBlockWithHeaderInfo(1, prevHash)
+	block.AddTransaction(spendTx)
+	assert.Nil(t, bc.AddBlock(block))
+
+	balance, err := state.GetBalance(address)
+	assert.Nil(t, err)
+	assert.Equal(t, float64(70), balance)
+	balance, err = state.GetBalance("B")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(130), balance)
+}
+
+func TestMultisigReplay(t *testing.T) {
+	for name, state := range map[string]LedgerState{
+		"memory": NewMemoryLedgerState(),
+		"utxo":   NewUTXOLedgerState(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			privKeys := createMultisigKeys(2)
+			wallet, err := NewMultisigWallet(2, publicKeys(privKeys))
+			assert.Nil(t, err)
+			address := wallet.Address()
+			assert.Nil(t, state.AddWallet(address, 1000))
+			assert.Nil(t, state.AddWallet("B", 0))
+			submitter := crypto.GeneratePrivateKey()
+			bc := NewBlockChain(state, 0)
+
+			spend := NewMultisigTransaction(wallet, "B", 100, 0, 7)
+			for _, privKey := range privKeys {
+				assert.Nil(t, spend.Approve(privKey))
+			}
+			toCore := func(tx *MultisigTransaction) *core.Transaction {
+				coreTx, err := tx.ToCoreTransaction()
+				assert.Nil(t, err)
+				assert.Nil(t, coreTx.Sign(submitter))
+				return coreTx
+			}
+			addBlock := func(prevHash types.Hash, height uint32, tx *core.Transaction) (types.Hash, error) {
+				block := core.NewBlockWithHeaderInfo(height, prevHash)
+				if tx != nil {
+					block.AddTransaction(tx)
+				}
+				if err := bc.AddBlock(block); err != nil {
+					return types.Hash{}, err
+				}
+				return block.Hash()
+			}
+			genesisHash, err := bc.GetGenesis().Hash()
+			assert.Nil(t, err)
+			hash1, err := addBlock(genesisHash, 1, toCore(spend))
+			assert.Nil(t, err)
+			assert.True(t, state.HasUsedMultisigNonce(address, 7))
+
+			spendBytes, err := spend.Bytes()
+			assert.Nil(t, err)
+
+			// A high-S twin of a signature is refused outright
+			flipped, err := DecodeMultisigTransaction(spendBytes)
+			assert.Nil(t, err)
+			flippedSig := flipped.Signatures[0].Signature
+			flippedSig.S = new(big.Int).Sub(elliptic.P256().Params().N, flippedSig.S)
+			assert.NotNil(t, flipped.Verify())
+
+			// A holder signing again yields a new hash but the same nonce
+			resigned, err := DecodeMultisigTransaction(spendBytes)
+			assert.Nil(t, err)
+			assert.Nil(t, resigned.Approve(privKeys[0]))
+			assert.Nil(t, resigned.Verify())
+			_, err = addBlock(hash1, 2, toCore(resigned))
+			assert.NotNil(t, err)
+
+			balance, err := state.GetBalance(address)
+			assert.Nil(t, err)
+			assert.Equal(t, float64(900), balance)
+
+			// Reorging the spend out frees the nonce again
+			forkHash, err := addBlock(genesisHash, 1, nil)
+			assert.Nil(t, err)
+			_, err = addBlock(forkHash, 2, nil)
+			assert.Nil(t, err)
+			assert.False(t, state.HasUsedMultisigNonce(address, 7))
+			balance, err = state.GetBalance(address)
+			assert.Nil(t, err)
+			assert.Equal(t, float64(1000), balance)
+		})
+	}
+}
@@ -2,33 +2,30 @@ package currency
 
 import (
 	"crypto/ecdsa"
-	"math"
 
 	"github.com/tusharjoshi4531/block-chain.git/core"
 )
 
 type Rewarder struct {
-	privateKey         *ecdsa.PrivateKey
-	blockChain         core.BlockChain
-	initAmount         float64
-	blocksToHalfAmount uint16
+	privateKey *ecdsa.PrivateKey
+	schedule   RewardSchedule
 }
 
-func NewRewarder(privKey *ecdsa.PrivateKey, bc core.BlockChain, initAmount float64, blocksToHalfAmount uint16) *Rewarder {
+func NewRewarder(privKey *ecdsa.PrivateKey, initAmount float64, blocksToHalfAmount uint16) *Rewarder {
 	return &Rewarder{
-		privateKey:         privKey,
-		blockChain:         bc,
-		initAmount:         initAmount,
-		blocksToHalfAmount: blocksToHalfAmount,
+		privateKey: privKey,
+		schedule:   NewRewardSchedule(initAmount, blocksToHalfAmount),
 	}
 }
 
-func (rewarder *Rewarder) GenerateReward(winner string) (*core.Transaction, error) {
-	bcHeight := rewarder.blockChain.Height()
-	rewardAmount := rewarder.initAmount / math.Pow(2, float64(bcHeight/uint32(rewarder.blocksToHalfAmount)))
-	
-	reward := NewTransaction("::", winner, rewardAmount)
-	rewardTx, err := reward.ToCoreTransaction() 
+// GenerateReward builds the coinbase of block, paying the winner the
+// scheduled reward for its height plus the fees of its transactions.
+func (rewarder *Rewarder) GenerateReward(winner string, block *core.Block) (*core.Transaction, error) {
+	height := block.Header.Height
+	rewardAmount := rewarder.schedule.Reward(height) + BlockFees(block.Transactions)
+
+	reward := NewCoinbaseTransaction(winner, rewardAmount, height)
+	rewardTx, err := reward.ToCoreTransaction()
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,94 @@
+package currency
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+	"github.com/tusharjoshi4531/block-chain.git/util"
+)
+
+// SlashProofVersion is the encoding version of the double sign evidence a
+// slash carries as its proof: the version byte, then the first header and
+// its signature, the second header and its signature and the validator key.
+const SlashProofVersion uint8 = 1
+
+// SlashEvidenceKey identifies the evidence in a slash proof by its pair of
+// headers, in either order. Signatures are left out since a header can be
+// signed again and a signature can be altered without invalidating it. It
+// fails unless the proof holds two different headers at the same height;
+// the consensus engine checks the signatures.
+func SlashEvidenceKey(proof []byte) (string, error) {
+	r := bytes.NewReader(proof)
+	cr := util.NewCanonicalReader(r)
+	cr.ReadVersion(SlashProofVersion)
+	if err := cr.Err(); err != nil {
+		return "", err
+	}
+
+	headerA, headerB := core.BlockHeader{}, core.BlockHeader{}
+	if err := headerA.Decode(r); err != nil {
+		return "", err
+	}
+	if err := crypto.NewNilSignature().Decode(r); err != nil {
+		return "", err
+	}
+	if err := headerB.Decode(r); err != nil {
+		return "", err
+	}
+
+	if headerA.Height != headerB.Height {
+		return "", fmt.Errorf("slash evidence headers are at different heights (%d) and (%d)", headerA.Height, headerB.Height)
+	}
+	hashA, err := headerA.Hash()
+	if err != nil {
+		return "", err
+	}
+	hashB, err := headerB.Hash()
+	if err != nil {
+		return "", err
+	}
+	if hashA == hashB {
+		return "", fmt.Errorf("slash evidence headers are identical")
+	}
+	if bytes.Compare(hashA[:], hashB[:]) > 0 {
+		hashA, hashB = hashB, hashA
+	}
+	return hashA.String() + hashB.String(), nil
+}
+
+// slashEvidence records the evidence slashes have been mined with, so that
+// the same double sign cannot slash a validator again after it re-stakes.
+type slashEvidence struct {
+	used map[string]struct{}
+}
+
+func newSlashEvidence() slashEvidence {
+	return slashEvidence{used: make(map[string]struct{})}
+}
+
+func (evidence *slashEvidence) use(proof []byte) error {
+	key, err := SlashEvidenceKey(proof)
+	if err != nil {
+		return fmt.Errorf("slash has malformed evidence: %s", err)
+	}
+	if evidence.HasUsedSlashEvidence(key) {
+		return fmt.Errorf("slash evidence (%s) is already used", key)
+	}
+	evidence.used[key] = struct{}{}
+	return nil
+}
+
+func (evidence *slashEvidence) release(proof []byte) {
+	if key, err := SlashEvidenceKey(proof); err == nil {
+		delete(evidence.used, key)
+	}
+}
+
+// HasUsedSlashEvidence reports whether a committed slash carried the
+// evidence with the given SlashEvidenceKey.
+func (evidence *slashEvidence) HasUsedSlashEvidence(key string) bool {
+	_, ok := evidence.used[key]
+	return ok
+}
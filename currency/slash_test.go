@@ -0,0 +1,113 @@
+package currency
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+	"github.com/tusharjoshi4531/block-chain.git/types"
+)
+
+// slashProof encodes evidence for the two headers with empty signatures;
+// the ledger only reads the headers.
+func slashProof(t *testing.T, headers ...core.BlockHeader) []byte {
+	buf := &bytes.Buffer{}
+	buf.WriteByte(SlashProofVersion)
+	for _, header := range headers {
+		assert.Nil(t, header.Encode(buf))
+		assert.Nil(t, crypto.NewNilSignature().Encode(buf))
+	}
+	return buf.Bytes()
+}
+
+func TestSlashEvidenceKey(t *testing.T) {
+	headerA := core.NewBlockWithHeaderInfo(1, types.Hash{1}).Header
+	headerB := core.NewBlockWithHeaderInfo(1, types.Hash{2}).Header
+
+	key, err := SlashEvidenceKey(slashProof(t, headerA, headerB))
+	assert.Nil(t, err)
+	swapped, err := SlashEvidenceKey(slashProof(t, headerB, headerA))
+	assert.Nil(t, err)
+	assert.Equal(t, key, swapped)
+
+	_, err = SlashEvidenceKey([]byte("proof"))
+	assert.NotNil(t, err)
+	_, err = SlashEvidenceKey(slashProof(t, headerA, headerA))
+	assert.NotNil(t, err)
+	_, err = SlashEvidenceKey(slashProof(t, headerA, core.NewBlockWithHeaderInfo(2, types.Hash{2}).Header))
+	assert.NotNil(t, err)
+}
+
+func TestSlashNeedsEvidence(t *testing.T) {
+	headerA := core.NewBlockWithHeaderInfo(1, types.Hash{1}).Header
+	headerB := core.NewBlockWithHeaderInfo(1, types.Hash{2}).Header
+	privKey := crypto.GeneratePrivateKey()
+	signed := func(slash *Transaction) *core.Transaction {
+		tx, err := slash.ToCoreTransaction()
+		assert.Nil(t, err)
+		assert.Nil(t, tx.Sign(privKey))
+		return tx
+	}
+
+	// Without an engine checking evidence, a slash must still carry it
+	state := NewMemoryLedgerState()
+	assert.Nil(t, state.AddWallet("A", 100))
+	assert.Nil(t, state.CommitTransaciton(NewStakeTransaction("A", "v", 30)))
+	bc := NewBlockChain(state, 100)
+	genesisHash, err := bc.GetGenesis().Hash()
+	assert.Nil(t, err)
+
+	for _, proof := range [][]byte{nil, []byte("proof"), slashProof(t, headerA, headerA)} {
+		unproven := signed(NewSlashTransaction("v", 30, proof))
+		assert.NotNil(t, core.CheckPoolAdmission(unproven))
+		block := core.NewBlockWithHeaderInfo(1, genesisHash)
+		block.AddTransaction(unproven)
+		assert.NotNil(t, bc.AddBlock(block))
+		assert.NotNil(t, state.CommitTransaciton(NewSlashTransaction("v", 30, proof)))
+	}
+	assert.Equal(t, 30.0, state.GetStake("v"))
+
+	proven := signed(NewSlashTransaction("v", 30, slashProof(t, headerA, headerB)))
+	assert.Nil(t, core.CheckPoolAdmission(proven))
+	block := core.NewBlockWithHeaderInfo(1, genesisHash)
+	block.AddTransaction(proven)
+	assert.Nil(t, bc.AddBlock(block))
+	assert.Equal(t, 0.0, state.GetStake("v"))
+}
+
+func TestSlashEvidenceReuse(t *testing.T) {
+	headerA := core.NewBlockWithHeaderInfo(1, types.Hash{1}).Header
+	headerB := core.NewBlockWithHeaderInfo(1, types.Hash{2}).Header
+	key, err := SlashEvidenceKey(slashProof(t, headerA, headerB))
+	assert.Nil(t, err)
+
+	for name, state := range map[string]LedgerState{
+		"memory": NewMemoryLedgerState(),
+		"utxo":   NewUTXOLedgerState(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Nil(t, state.AddWallet("A", 100))
+			stake := NewStakeTransaction("A", "v", 30)
+			assert.Nil(t, state.CommitTransaciton(stake))
+
+			slash := NewSlashTransaction("v", 30, slashProof(t, headerA, headerB))
+			assert.Nil(t, state.CommitTransaciton(slash))
+			assert.True(t, state.HasUsedSlashEvidence(key))
+
+			// The validator stakes again; the same double sign cannot slash it
+			restake := NewStakeTransaction("A", "v", 30)
+			assert.Nil(t, state.CommitTransaciton(restake))
+			assert.NotNil(t, state.CommitTransaciton(NewSlashTransaction("v", 30, slashProof(t, headerB, headerA))))
+			assert.NotNil(t, state.CommitTransaciton(NewSlashTransaction("v", 30, []byte("proof"))))
+			assert.Equal(t, 30.0, state.GetStake("v"))
+
+			// Reverting the slash frees its evidence
+			assert.Nil(t, state.RevertTransaction(restake))
+			assert.Nil(t, state.RevertTransaction(slash))
+			assert.False(t, state.HasUsedSlashEvidence(key))
+			assert.Nil(t, state.RevertTransaction(stake))
+		})
+	}
+}
@@ -1,40 +1,196 @@
 package currency
 
 import (
-	"bytes"
-	"encoding/gob"
+	"fmt"
 	"io"
+	"math"
 
 	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/types"
 	"github.com/tusharjoshi4531/block-chain.git/util"
 )
 
+const transactionEncodingVersion uint8 = 2
+
+// Transaction moves Amount from one wallet to another. Type says how the
+// ledger applies it. Transfers pay Fee to the block producer on top of
+// Amount, and a coinbase records the Height of its block. Stake transfers
+// also name the Validator the stake belongs to, and slashes carry the double
+// sign evidence that justified them as their Proof. A transaction cannot be mined before its Lock, except
+// for escrows, whose Lock holds back the release of the funds instead.
+// Transfers and issues naming an Asset move that asset instead of the
+// native currency.
 type Transaction struct {
-	From   string
-	To     string
-	Amount float64
+	Type      core.TxType
+	From      string
+	To        string
+	Amount    float64
+	Fee       float64
+	Height    uint32
+	Validator string
+	Proof     []byte
+	Lock      core.TxLock
+	Asset     string
+}
+
+func init() {
+	for txType, name := range map[core.TxType]string{
+		core.TxTypeTransfer:     "Transfer",
+		core.TxTypeCoinbase:     "Coinbase",
+		core.TxTypeRegistration: "Registration",
+		core.TxTypeStake:        "Stake",
+		core.TxTypeUnstake:      "Unstake",
+		core.TxTypeSlash:        "Slash",
+		core.TxTypeEscrow:       "Escrow",
+		core.TxTypeRelease:      "Release",
+		core.TxTypeIssueAsset:   "IssueAsset",
+	} {
+		core.RegisterTxType(txType, core.TxTypeHandler{
+			Name:      name,
+			Decode:    decodeTransactionPayload(txType),
+			Validate:  validateTransaction,
+			BlockOnly: txType == core.TxTypeCoinbase,
+			Lock:      transactionLock,
+		})
+	}
 }
 
+// NewTransaction builds a transaction whose type follows from the system
+// wallets it names; transfers between ordinary wallets are TxTypeTransfer.
 func NewTransaction(from string, to string, amount float64) *Transaction {
 	return &Transaction{
+		Type:   transactionType(from, to),
 		From:   from,
 		To:     to,
 		Amount: amount,
 	}
 }
 
+func transactionType(from, to string) core.TxType {
+	switch {
+	case from == RewardSymbol:
+		return core.TxTypeCoinbase
+	case from == RegisterSymbol:
+		return core.TxTypeRegistration
+	case to == StakeSymbol:
+		return core.TxTypeStake
+	case from == StakeSymbol && to == RewardSymbol:
+		return core.TxTypeSlash
+	case from == StakeSymbol:
+		return core.TxTypeUnstake
+	case from == EscrowSymbol:
+		return core.TxTypeRelease
+	default:
+		return core.TxTypeTransfer
+	}
+}
+
+// NewCoinbaseTransaction mints amount to the wallet of the producer of the
+// block at height.
+func NewCoinbaseTransaction(walletId string, amount float64, height uint32) *Transaction {
+	tx := NewTransaction(RewardSymbol, walletId, amount)
+	tx.Height = height
+	return tx
+}
+
+func NewStakeTransaction(walletId string, validator string, amount float64) *Transaction {
+	tx := NewTransaction(walletId, StakeSymbol, amount)
+	tx.Validator = validator
+	return tx
+}
+
+func NewUnstakeTransaction(walletId string, validator string, amount float64) *Transaction {
+	tx := NewTransaction(StakeSymbol, walletId, amount)
+	tx.Validator = validator
+	return tx
+}
+
+func NewSlashTransaction(validator string, amount float64, proof []byte) *Transaction {
+	tx := NewTransaction(StakeSymbol, RewardSymbol, amount)
+	tx.Validator = validator
+	tx.Proof = proof
+	return tx
+}
+
+// NewLockedTransaction builds a transfer that cannot be mined before lock.
+func NewLockedTransaction(from string, to string, amount float64, lock core.TxLock) *Transaction {
+	tx := NewTransaction(from, to, amount)
+	tx.Lock = lock
+	return tx
+}
+
+// NewEscrowTransaction takes amount from the sender at once but only lets
+// the recipient spend it through a release mined at or after lock.
+func NewEscrowTransaction(from string, to string, amount float64, lock core.TxLock) *Transaction {
+	tx := NewLockedTransaction(from, to, amount, lock)
+	tx.Type = core.TxTypeEscrow
+	return tx
+}
+
+// NewReleaseTransaction pays an escrow out to its recipient. The release is
+// locked like the escrow it spends, so it cannot be mined any earlier.
+func NewReleaseTransaction(to string, amount float64, lock core.TxLock) *Transaction {
+	return NewLockedTransaction(EscrowSymbol, to, amount, lock)
+}
+
+// NewRegistrationTransaction creates walletId on chain with balance, which
+// chains only accept if it is their initial balance.
+func NewRegistrationTransaction(walletId string, balance float64) *Transaction {
+	return NewTransaction(RegisterSymbol, walletId, balance)
+}
+
+// NewTransactionFromCoreTransaction decodes the envelope of tx; multisig
+// spends yield their transfer. It fails for opaque payloads and for types
+// not owned by the currency package.
 func NewTransactionFromCoreTransaction(tx *core.Transaction) (*Transaction, error) {
-	transaction := &Transaction{}
-	err := transaction.Decode(bytes.NewReader(tx.Data))
-	return transaction, err
+	txType, decoded, err := core.DecodeTransaction(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	switch decoded := decoded.(type) {
+	case *Transaction:
+		return decoded, nil
+	case *MultisigTransaction:
+		return decoded.Transfer, nil
+	default:
+		return nil, fmt.Errorf("transaction type (%s) is not a currency transaction", core.TxTypeToString(txType))
+	}
 }
 
+// Encode writes the envelope payload: encoding version, From, To, Amount,
+// Fee, Height, Validator, Proof, Lock and Asset. The type is carried by the
+// envelope.
 func (tx *Transaction) Encode(w io.Writer) error {
-	return gob.NewEncoder(w).Encode(tx)
+	cw := util.NewCanonicalWriter(w)
+	cw.WriteUint8(transactionEncodingVersion)
+	cw.WriteString(tx.From)
+	cw.WriteString(tx.To)
+	cw.WriteFloat64(tx.Amount)
+	cw.WriteFloat64(tx.Fee)
+	cw.WriteUint32(tx.Height)
+	cw.WriteString(tx.Validator)
+	cw.WriteBytes(tx.Proof)
+	cw.WriteUint32(tx.Lock.Height)
+	cw.WriteInt64(tx.Lock.Time)
+	cw.WriteString(tx.Asset)
+	return cw.Err()
 }
 
 func (tx *Transaction) Decode(r io.Reader) error {
-	return gob.NewDecoder(r).Decode(tx)
+	cr := util.NewCanonicalReader(r)
+	cr.ReadVersion(transactionEncodingVersion)
+	tx.From = cr.ReadString()
+	tx.To = cr.ReadString()
+	tx.Amount = cr.ReadFloat64()
+	tx.Fee = cr.ReadFloat64()
+	tx.Height = cr.ReadUint32()
+	tx.Validator = cr.ReadString()
+	tx.Proof = cr.ReadBytes()
+	tx.Lock.Height = cr.ReadUint32()
+	tx.Lock.Time = cr.ReadInt64()
+	tx.Asset = cr.ReadString()
+	return cr.Err()
 }
 
 func (tx *Transaction) ToBytes() ([]byte, error) {
@@ -42,9 +198,143 @@ func (tx *Transaction) ToBytes() ([]byte, error) {
 }
 
 func (tx *Transaction) ToCoreTransaction() (*core.Transaction, error) {
-	data, err := tx.ToBytes()
+	payload, err := tx.ToBytes()
 	if err != nil {
 		return nil, err
 	}
-	return core.NewTransaction(data), nil
+	return core.NewTypedTransaction(tx.Type, payload)
+}
+
+// Hash is the hash of the core transaction carrying tx.
+func (tx *Transaction) Hash() (types.Hash, error) {
+	coreTx, err := tx.ToCoreTransaction()
+	if err != nil {
+		return types.Hash{}, err
+	}
+	return coreTx.Hash(), nil
+}
+
+func (tx *Transaction) IsRegistration() bool {
+	return tx.Type == core.TxTypeRegistration
+}
+
+func decodeTransactionPayload(txType core.TxType) func([]byte) (any, error) {
+	return func(payload []byte) (any, error) {
+		transaction := &Transaction{Type: txType}
+		if err := util.DecodeFromBytes(payload, transaction); err != nil {
+			return nil, err
+		}
+		return transaction, nil
+	}
+}
+
+// transactionLock is the inclusion lock of a transaction. Escrows can be
+// mined at once; their lock applies to the release.
+func transactionLock(decoded any) core.TxLock {
+	tx := decoded.(*Transaction)
+	if tx.Type == core.TxTypeEscrow {
+		return core.TxLock{}
+	}
+	return tx.Lock
+}
+
+// validateTransaction also keeps plain transactions from spending the funds
+// of multisig wallets.
+func validateTransaction(_ *core.Transaction, decoded any) error {
+	tx := decoded.(*Transaction)
+	if IsMultisigAddress(tx.From) {
+		return fmt.Errorf("funds of (%s) can only be spent by a multisig transaction", tx.From)
+	}
+	return validateCurrencyTransaction(tx)
+}
+
+// validateCurrencyTransaction checks that the wallets named by a transaction
+// agree with its type and that its amount is usable.
+func validateCurrencyTransaction(tx *Transaction) error {
+	if !isFinite(tx.Amount) || !isFinite(tx.Fee) {
+		return fmt.Errorf("transaction amount (%f) or fee (%f) is not a finite number", tx.Amount, tx.Fee)
+	}
+	if tx.Amount < 0 || tx.Fee < 0 {
+		return fmt.Errorf("transaction amount (%f) or fee (%f) is negative", tx.Amount, tx.Fee)
+	}
+	if tx.Fee != 0 && tx.Type != core.TxTypeTransfer {
+		return fmt.Errorf("only transfers pay fees")
+	}
+	if tx.Height != 0 && tx.Type != core.TxTypeCoinbase {
+		return fmt.Errorf("only coinbase transactions record a height")
+	}
+	if tx.Lock.Time < 0 {
+		return fmt.Errorf("transaction lock time (%d) is negative", tx.Lock.Time)
+	}
+	if tx.Asset != "" || tx.Type == core.TxTypeIssueAsset {
+		if tx.Type != core.TxTypeTransfer && tx.Type != core.TxTypeIssueAsset {
+			return fmt.Errorf("%s transaction cannot move an asset", core.TxTypeToString(tx.Type))
+		}
+		if err := validateAssetId(tx.Asset); err != nil {
+			return err
+		}
+	}
+	if expectedType(tx) != tx.Type {
+		return fmt.Errorf(
+			"%s transaction cannot move funds from (%s) to (%s)",
+			core.TxTypeToString(tx.Type),
+			tx.From,
+			tx.To,
+		)
+	}
+
+	switch tx.Type {
+	case core.TxTypeTransfer:
+		if tx.From == "" || tx.To == "" || IsSystemWallet(tx.From) || IsSystemWallet(tx.To) {
+			return fmt.Errorf("transfer from (%s) to (%s) must be between wallets", tx.From, tx.To)
+		}
+	case core.TxTypeEscrow, core.TxTypeRelease:
+		if tx.Lock.IsZero() {
+			return fmt.Errorf("%s transaction does not name a lock", core.TxTypeToString(tx.Type))
+		}
+		if tx.To == "" || IsSystemWallet(tx.To) {
+			return fmt.Errorf("%s to (%s) must name a wallet", core.TxTypeToString(tx.Type), tx.To)
+		}
+		if tx.Type == core.TxTypeEscrow && (tx.From == "" || IsSystemWallet(tx.From)) {
+			return fmt.Errorf("escrow from (%s) must name a wallet", tx.From)
+		}
+	case core.TxTypeIssueAsset:
+		if tx.From != tx.To || tx.From == "" || IsSystemWallet(tx.From) {
+			return fmt.Errorf("asset must be issued to its issuer's wallet")
+		}
+		if tx.Amount <= 0 {
+			return fmt.Errorf("asset supply (%f) must be positive", tx.Amount)
+		}
+	case core.TxTypeCoinbase, core.TxTypeRegistration:
+		if tx.To == "" || IsSystemWallet(tx.To) {
+			return fmt.Errorf("%s to (%s) must name a wallet", core.TxTypeToString(tx.Type), tx.To)
+		}
+	case core.TxTypeStake, core.TxTypeUnstake, core.TxTypeSlash:
+		if tx.Validator == "" {
+			return fmt.Errorf("stake transaction does not name a validator")
+		}
+		if tx.Type == core.TxTypeStake && IsSystemWallet(tx.From) || tx.Type == core.TxTypeUnstake && IsSystemWallet(tx.To) {
+			return fmt.Errorf("stake transaction from (%s) to (%s) must involve a wallet", tx.From, tx.To)
+		}
+		if tx.Type == core.TxTypeSlash {
+			if _, err := SlashEvidenceKey(tx.Proof); err != nil {
+				return fmt.Errorf("slash of validator (%s) has no double sign evidence: %s", tx.Validator, err)
+			}
+		}
+	}
+	return nil
+}
+
+func isFinite(value float64) bool {
+	return !math.IsNaN(value) && !math.IsInf(value, 0)
+}
+
+// expectedType is the type implied by the wallets a transaction names.
+// Escrows and asset issues name the same wallets as transfers.
+func expectedType(tx *Transaction) core.TxType {
+	txType := transactionType(tx.From, tx.To)
+	if txType == core.TxTypeTransfer && (tx.Type == core.TxTypeEscrow || tx.Type == core.TxTypeIssueAsset) {
+		return tx.Type
+	}
+	return txType
 }
@@ -0,0 +1,165 @@
+package currency
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+	"github.com/tusharjoshi4531/block-chain.git/types"
+)
+
+func TestTransactionTypes(t *testing.T) {
+	assert.Equal(t, core.TxTypeTransfer, NewTransaction("A", "B", 1).Type)
+	assert.Equal(t, core.TxTypeCoinbase, NewCoinbaseTransaction("A", 1, 1).Type)
+	assert.Equal(t, core.TxTypeRegistration, NewRegistrationTransaction("A", 0).Type)
+	assert.Equal(t, core.TxTypeStake, NewStakeTransaction("A", "v", 1).Type)
+	assert.Equal(t, core.TxTypeUnstake, NewUnstakeTransaction("A", "v", 1).Type)
+	assert.Equal(t, core.TxTypeSlash, NewSlashTransaction("v", 1, []byte("proof")).Type)
+}
+
+func TestTransactionEnvelope(t *testing.T) {
+	slash := NewSlashTransaction("v", 10, []byte("proof"))
+	tx, err := slash.ToCoreTransaction()
+	assert.Nil(t, err)
+
+	envelope, err := core.DecodeEnvelope(tx.Data)
+	assert.Nil(t, err)
+	assert.Equal(t, core.TxTypeSlash, envelope.Type)
+
+	decoded, err := NewTransactionFromCoreTransaction(tx)
+	assert.Nil(t, err)
+	assert.Equal(t, slash, decoded)
+
+	_, err = NewTransactionFromCoreTransaction(core.NewTransaction([]byte("REWARD")))
+	assert.NotNil(t, err)
+
+	// Bytes after the payload make the encoding invalid
+	payload, err := slash.ToBytes()
+	assert.Nil(t, err)
+	padded, err := core.NewTypedTransaction(core.TxTypeSlash, append(payload, 0))
+	assert.Nil(t, err)
+	_, err = NewTransactionFromCoreTransaction(padded)
+	assert.NotNil(t, err)
+}
+
+func TestValidateTransactionType(t *testing.T) {
+	privKey := crypto.GeneratePrivateKey()
+	validate := func(tx *Transaction) error {
+		coreTx, err := tx.ToCoreTransaction()
+		assert.Nil(t, err)
+		assert.Nil(t, coreTx.Sign(privKey))
+		return core.ValidateTransaction(coreTx)
+	}
+
+	assert.Nil(t, validate(NewTransaction("A", "B", 10)))
+	assert.Nil(t, validate(NewCoinbaseTransaction("A", 10, 1)))
+	assert.Nil(t, validate(NewStakeTransaction("A", "v", 10)))
+
+	assert.NotNil(t, validate(NewTransaction("A", "B", -10)))
+	assert.NotNil(t, validate(NewCoinbaseTransaction(StakeSymbol, 10, 1)))
+	assert.NotNil(t, validate(NewStakeTransaction("A", "", 10)))
+
+	// The type must agree with the wallets named
+	mislabeled := NewTransaction(RewardSymbol, "A", 10)
+	mislabeled.Type = core.TxTypeTransfer
+	assert.NotNil(t, validate(mislabeled))
+}
+
+func TestNonFiniteAmounts(t *testing.T) {
+	privKey := crypto.GeneratePrivateKey()
+	validate := func(tx *Transaction) error {
+		coreTx, err := tx.ToCoreTransaction()
+		assert.Nil(t, err)
+		assert.Nil(t, coreTx.Sign(privKey))
+		return core.ValidateTransaction(coreTx)
+	}
+
+	for _, value := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		assert.NotNil(t, validate(NewTransaction("A", "B", value)))
+		assert.NotNil(t, validate(NewCoinbaseTransaction("A", value, 1)))
+
+		withFee := NewTransaction("A", "B", 10)
+		withFee.Fee = value
+		assert.NotNil(t, validate(withFee))
+	}
+
+	// Ledgers are never asked to apply them
+	state := NewMemoryLedgerState()
+	assert.Nil(t, state.AddWallet("A", 100))
+	assert.Nil(t, state.AddWallet("B", 100))
+	bc := NewBlockChain(state, 100)
+	prevHash, err := bc.GetGenesis().Hash()
+	assert.Nil(t, err)
+	block := core.NewBlockWithHeaderInfo(1, prevHash)
+	block.AddTransaction(createTransaction(t, "A", "B", math.NaN(), privKey))
+	assert.NotNil(t, bc.AddBlock(block))
+
+	balance, err := state.GetBalance("A")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(100), balance)
+}
+
+func TestRevertTransactionTypes(t *testing.T) {
+	state := NewMemoryLedgerState()
+	assert.Nil(t, state.AddWallet("A", 100))
+	headerA := core.NewBlockWithHeaderInfo(1, types.Hash{1}).Header
+	headerB := core.NewBlockWithHeaderInfo(1, types.Hash{2}).Header
+
+	transactions := []*Transaction{
+		NewRegistrationTransaction("B", 0),
+		NewCoinbaseTransaction("A", 50, 1),
+		NewStakeTransaction("A", "v", 120),
+		NewUnstakeTransaction("A", "v", 20),
+		NewSlashTransaction("v", 30, slashProof(t, headerA, headerB)),
+		NewTransaction("A", "B", 10),
+	}
+	for _, tx := range transactions {
+		assert.Nil(t, state.CommitTransaciton(tx))
+	}
+	assert.Equal(t, float64(70), state.GetStake("v"))
+
+	for i := len(transactions) - 1; i >= 0; i-- {
+		assert.Nil(t, state.RevertTransaction(transactions[i]))
+	}
+	balance, err := state.GetBalance("A")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(100), balance)
+	assert.False(t, state.HasWallet("B"))
+	assert.Equal(t, float64(0), state.GetStake("v"))
+}
+
+func TestLockedTransaction(t *testing.T) {
+	lock := core.TxLock{Height: 10, Time: 1000}
+
+	locked := NewLockedTransaction("A", "B", 10, lock)
+	tx, err := locked.ToCoreTransaction()
+	assert.Nil(t, err)
+	decoded, err := NewTransactionFromCoreTransaction(tx)
+	assert.Nil(t, err)
+	assert.Equal(t, lock, decoded.Lock)
+	assert.Equal(t, lock, core.TransactionLock(tx))
+	assert.NotNil(t, core.CheckTransactionLock(tx, 9, 1000))
+	assert.Nil(t, core.CheckTransactionLock(tx, 10, 1000))
+
+	// Escrows are mined at once and lock their release instead
+	escrow := NewEscrowTransaction("A", "B", 10, lock)
+	assert.Equal(t, core.TxTypeEscrow, escrow.Type)
+	tx, err = escrow.ToCoreTransaction()
+	assert.Nil(t, err)
+	assert.Equal(t, core.TxLock{}, core.TransactionLock(tx))
+
+	release := NewReleaseTransaction("B", 10, lock)
+	assert.Equal(t, core.TxTypeRelease, release.Type)
+	tx, err = release.ToCoreTransaction()
+	assert.Nil(t, err)
+	assert.Equal(t, lock, core.TransactionLock(tx))
+
+	assert.Nil(t, validateCurrencyTransaction(escrow))
+	assert.Nil(t, validateCurrencyTransaction(release))
+	assert.NotNil(t, validateCurrencyTransaction(NewEscrowTransaction("A", "B", 10, core.TxLock{})))
+	assert.NotNil(t, validateCurrencyTransaction(NewEscrowTransaction("A", StakeSymbol, 10, lock)))
+	assert.NotNil(t, validateCurrencyTransaction(NewReleaseTransaction(RewardSymbol, 10, lock)))
+	assert.NotNil(t, validateCurrencyTransaction(NewLockedTransaction("A", "B", 10, core.TxLock{Time: -1})))
+}
@@ -0,0 +1,240 @@
+package currency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/script"
+	"github.com/tusharjoshi4531/block-chain.git/types"
+	"github.com/tusharjoshi4531/block-chain.git/util"
+)
+
+// MaxUTXOEntries bounds the inputs and the outputs of a UTXO transaction.
+const MaxUTXOEntries = 256
+
+const utxoEncodingVersion uint8 = 2
+
+func init() {
+	core.RegisterTxType(core.TxTypeUTXO, core.TxTypeHandler{
+		Name: "UTXO",
+		Decode: func(payload []byte) (any, error) {
+			tx := &UTXOTransaction{}
+			if err := util.DecodeFromBytes(payload, tx); err != nil {
+				return nil, err
+			}
+			return tx, nil
+		},
+		Validate: func(_ *core.Transaction, decoded any) error {
+			return decoded.(*UTXOTransaction).validate()
+		},
+	})
+}
+
+// OutPoint names output Index of the transaction with hash TxHash. Account
+// style transactions in blocks are named by the SignedHash of their core
+// transaction and multisig spends by their signing hash.
+type OutPoint struct {
+	TxHash types.Hash
+	Index  uint32
+}
+
+func (outPoint OutPoint) String() string {
+	return fmt.Sprintf("%s:%d", outPoint.TxHash.String(), outPoint.Index)
+}
+
+func (outPoint OutPoint) less(other OutPoint) bool {
+	if cmp := bytes.Compare(outPoint.TxHash[:], other.TxHash[:]); cmp != 0 {
+		return cmp < 0
+	}
+	return outPoint.Index < other.Index
+}
+
+// TxInput spends the output named by OutPoint. Unlock is the script that
+// satisfies the output's lock script and is empty for unscripted outputs.
+type TxInput struct {
+	OutPoint OutPoint
+	Unlock   []byte
+}
+
+// TxOutput is an amount owned by a wallet. An output with a lock Script
+// can only be spent by an input whose unlock script satisfies it; the
+// owner alone cannot spend it.
+type TxOutput struct {
+	Owner  string
+	Amount float64
+	Script []byte
+}
+
+// UTXO is an output that has not been spent yet.
+type UTXO struct {
+	OutPoint OutPoint
+	Output   TxOutput
+}
+
+// UTXOTransaction spends the outputs named by Inputs and creates Outputs.
+// The inputs must add up to the outputs plus Fee, which is paid to the
+// block producer. Its outputs are named by the hash of the core transaction
+// carrying it.
+type UTXOTransaction struct {
+	Inputs  []TxInput
+	Outputs []TxOutput
+	Fee     float64
+}
+
+func NewUTXOTransaction(inputs []TxInput, outputs []TxOutput, fee float64) *UTXOTransaction {
+	return &UTXOTransaction{
+		Inputs:  inputs,
+		Outputs: outputs,
+		Fee:     fee,
+	}
+}
+
+// NewUTXOTransactionFromCoreTransaction decodes tx if it carries a UTXO
+// transaction.
+func NewUTXOTransactionFromCoreTransaction(tx *core.Transaction) (*UTXOTransaction, error) {
+	txType, decoded, err := core.DecodeTransaction(tx)
+	if err != nil {
+		return nil, err
+	}
+	utxoTx, ok := decoded.(*UTXOTransaction)
+	if !ok {
+		return nil, fmt.Errorf("transaction type (%s) is not a UTXO transaction", core.TxTypeToString(txType))
+	}
+	return utxoTx, nil
+}
+
+// validate checks the rules that do not depend on the UTXO set.
+func (tx *UTXOTransaction) validate() error {
+	if len(tx.Inputs) == 0 || len(tx.Outputs) == 0 {
+		return fmt.Errorf("utxo transaction needs inputs and outputs")
+	}
+	if !isFinite(tx.Fee) || tx.Fee < 0 {
+		return fmt.Errorf("utxo transaction fee (%f) must be finite and not negative", tx.Fee)
+	}
+
+	inputs := make(map[OutPoint]struct{}, len(tx.Inputs))
+	for _, input := range tx.Inputs {
+		if _, ok := inputs[input.OutPoint]; ok {
+			return fmt.Errorf("utxo transaction spends (%s) twice", input.OutPoint)
+		}
+		inputs[input.OutPoint] = struct{}{}
+		if !script.IsPushOnly(input.Unlock) {
+			return fmt.Errorf("unlock script of input (%s) must only push data", input.OutPoint)
+		}
+	}
+	for _, output := range tx.Outputs {
+		if !isFinite(output.Amount) || output.Amount <= 0 {
+			return fmt.Errorf("utxo output amount (%f) must be finite and positive", output.Amount)
+		}
+		if output.Owner == "" || IsSystemWallet(output.Owner) {
+			return fmt.Errorf("utxo output to (%s) must name a wallet", output.Owner)
+		}
+		if err := script.Check(output.Script); err != nil {
+			return fmt.Errorf("lock script of output to (%s) is invalid: %s", output.Owner, err)
+		}
+	}
+	return nil
+}
+
+// Encode writes the encoding version, the length prefixed inputs and
+// outputs, and the fee.
+func (tx *UTXOTransaction) Encode(w io.Writer) error {
+	return tx.encode(w, true)
+}
+
+// encode writes tx, leaving out the unlock scripts unless withUnlocks is
+// set.
+func (tx *UTXOTransaction) encode(w io.Writer, withUnlocks bool) error {
+	cw := util.NewCanonicalWriter(w)
+	cw.WriteUint8(utxoEncodingVersion)
+	cw.WriteUint16(uint16(len(tx.Inputs)))
+	for _, input := range tx.Inputs {
+		cw.WriteFixed(input.OutPoint.TxHash[:])
+		cw.WriteUint32(input.OutPoint.Index)
+		if withUnlocks {
+			cw.WriteBytes(input.Unlock)
+		} else {
+			cw.WriteBytes(nil)
+		}
+	}
+	cw.WriteUint16(uint16(len(tx.Outputs)))
+	for _, output := range tx.Outputs {
+		cw.WriteString(output.Owner)
+		cw.WriteFloat64(output.Amount)
+		cw.WriteBytes(output.Script)
+	}
+	cw.WriteFloat64(tx.Fee)
+	return cw.Err()
+}
+
+func (tx *UTXOTransaction) Decode(r io.Reader) error {
+	cr := util.NewCanonicalReader(r)
+	cr.ReadVersion(utxoEncodingVersion)
+
+	numInputs := cr.ReadUint16()
+	if err := cr.Err(); err != nil {
+		return err
+	}
+	if numInputs > MaxUTXOEntries {
+		return fmt.Errorf("utxo transaction has (%d) inputs; maximum is (%d)", numInputs, MaxUTXOEntries)
+	}
+	tx.Inputs = make([]TxInput, numInputs)
+	for i := range tx.Inputs {
+		cr.ReadFixed(tx.Inputs[i].OutPoint.TxHash[:])
+		tx.Inputs[i].OutPoint.Index = cr.ReadUint32()
+		tx.Inputs[i].Unlock = cr.ReadBytes()
+	}
+
+	numOutputs := cr.ReadUint16()
+	if err := cr.Err(); err != nil {
+		return err
+	}
+	if numOutputs > MaxUTXOEntries {
+		return fmt.Errorf("utxo transaction has (%d) outputs; maximum is (%d)", numOutputs, MaxUTXOEntries)
+	}
+	tx.Outputs = make([]TxOutput, numOutputs)
+	for i := range tx.Outputs {
+		tx.Outputs[i].Owner = cr.ReadString()
+		tx.Outputs[i].Amount = cr.ReadFloat64()
+		tx.Outputs[i].Script = cr.ReadBytes()
+	}
+
+	tx.Fee = cr.ReadFloat64()
+	return cr.Err()
+}
+
+func (tx *UTXOTransaction) ToBytes() ([]byte, error) {
+	return util.EncodeToBytes(tx)
+}
+
+func (tx *UTXOTransaction) ToCoreTransaction() (*core.Transaction, error) {
+	payload, err := tx.ToBytes()
+	if err != nil {
+		return nil, err
+	}
+	return core.NewTypedTransaction(core.TxTypeUTXO, payload)
+}
+
+// Hash is the hash of the core transaction carrying tx; its outputs are
+// named by it.
+func (tx *UTXOTransaction) Hash() (types.Hash, error) {
+	coreTx, err := tx.ToCoreTransaction()
+	if err != nil {
+		return types.Hash{}, err
+	}
+	return coreTx.Hash(), nil
+}
+
+// SigningHash is the hash signed by the CheckSig opcodes of the lock
+// scripts tx spends. It leaves out the unlock scripts, which carry the
+// signatures.
+func (tx *UTXOTransaction) SigningHash() (types.Hash, error) {
+	buf := &bytes.Buffer{}
+	if err := tx.encode(buf, false); err != nil {
+		return types.Hash{}, err
+	}
+	return sha256.Sum256(buf.Bytes()), nil
+}
@@ -0,0 +1,556 @@
+package currency
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/script"
+	"github.com/tusharjoshi4531/block-chain.git/types"
+)
+
+// utxoAmountTolerance absorbs float rounding when inputs are matched
+// against outputs.
+const utxoAmountTolerance = 1e-9
+
+// UTXOLedger is a LedgerState that keeps funds as unspent outputs and also
+// applies UTXO transactions. Lock scripts are evaluated at the height and
+// timestamp of the block committing the transaction.
+type UTXOLedger interface {
+	LedgerState
+	CommitTransactionAs(transaction *Transaction, txHash types.Hash) error
+	RevertTransactionAs(transaction *Transaction, txHash types.Hash) error
+	CommitUTXOTransaction(tx *UTXOTransaction, height uint32, timestamp int64) error
+	RevertUTXOTransaction(tx *UTXOTransaction) error
+	GetUTXOs(walletId string) []UTXO
+}
+
+// UTXOLedgerState keeps the funds of wallets as a set of unspent outputs; a
+// balance is the sum of the outputs a wallet owns. Account style
+// transactions spend the sender's outputs in outpoint order and return the
+// change as a new output. Every commit records how to undo itself, so
+// transactions must be reverted in the reverse order of their commits.
+type UTXOLedgerState struct {
+	assetBook
+	multisigNonces
+	slashEvidence
+	utxos       map[OutPoint]TxOutput
+	wallets     map[string]struct{}
+	stakes      map[string]float64
+	stakeOwners map[string]string
+	escrows     map[string][]Escrow
+	journal     []*ledgerChange
+}
+
+// ledgerChange undoes the commit of the transaction with hash txHash.
+type ledgerChange struct {
+	txHash  types.Hash
+	outputs uint32
+	undo    []func()
+}
+
+func (change *ledgerChange) rollback() {
+	for i := len(change.undo) - 1; i >= 0; i-- {
+		change.undo[i]()
+	}
+}
+
+func NewUTXOLedgerState() *UTXOLedgerState {
+	return &UTXOLedgerState{
+		assetBook:      newAssetBook(),
+		multisigNonces: newMultisigNonces(),
+		slashEvidence:  newSlashEvidence(),
+		utxos:          make(map[OutPoint]TxOutput),
+		wallets:        make(map[string]struct{}),
+		stakes:         make(map[string]float64),
+		stakeOwners:    make(map[string]string),
+		escrows:        make(map[string][]Escrow),
+	}
+}
+
+func (state *UTXOLedgerState) HasWallet(id string) bool {
+	_, ok := state.wallets[id]
+	return ok
+}
+
+// AddWallet creates a wallet whose balance is a single output named after
+// the wallet.
+func (state *UTXOLedgerState) AddWallet(walletId string, balance float64) error {
+	if state.HasWallet(walletId) {
+		return fmt.Errorf("member with id (%s) is already present in ledger", walletId)
+	}
+	state.wallets[walletId] = struct{}{}
+	if balance > 0 {
+		state.utxos[allocationOutPoint(walletId)] = TxOutput{Owner: walletId, Amount: balance}
+	}
+	return nil
+}
+
+func allocationOutPoint(walletId string) OutPoint {
+	return OutPoint{TxHash: sha256.Sum256([]byte(walletId))}
+}
+
+// CommitTransaciton applies transaction with its outputs named after the
+// transaction's own hash. Transactions without a nonce that are equal to an
+// earlier one would name the same outputs; chains commit them with
+// CommitTransactionAs instead.
+func (state *UTXOLedgerState) CommitTransaciton(transaction *Transaction) error {
+	txHash, err := transaction.Hash()
+	if err != nil {
+		return err
+	}
+	return state.CommitTransactionAs(transaction, txHash)
+}
+
+// RevertTransaction undoes transaction, which must be the last one
+// committed.
+func (state *UTXOLedgerState) RevertTransaction(transaction *Transaction) error {
+	txHash, err := transaction.Hash()
+	if err != nil {
+		return err
+	}
+	return state.RevertTransactionAs(transaction, txHash)
+}
+
+// CommitTransactionAs applies transaction with its outputs named after
+// txHash, the SignedHash of the core transaction carrying it.
+func (state *UTXOLedgerState) CommitTransactionAs(transaction *Transaction, txHash types.Hash) error {
+	return state.commit(txHash, func(change *ledgerChange) error {
+		return state.applyTransaction(change, transaction)
+	})
+}
+
+// RevertTransactionAs undoes CommitTransactionAs; transaction must be the
+// last one committed.
+func (state *UTXOLedgerState) RevertTransactionAs(_ *Transaction, txHash types.Hash) error {
+	return state.revert(txHash)
+}
+
+// CommitMultisigTransaction applies the transfer of a multisig spend and
+// uses up its nonce. Its outputs are named after the signing hash, which
+// covers the nonce.
+func (state *UTXOLedgerState) CommitMultisigTransaction(transaction *MultisigTransaction) error {
+	txHash, err := transaction.SigningHash()
+	if err != nil {
+		return err
+	}
+	return state.commit(txHash, func(change *ledgerChange) error {
+		if err := state.useMultisigNonce(change, transaction.Wallet.Address(), transaction.Nonce); err != nil {
+			return err
+		}
+		return state.applyTransaction(change, transaction.Transfer)
+	})
+}
+
+// RevertMultisigTransaction undoes transaction, which must be the last one
+// committed.
+func (state *UTXOLedgerState) RevertMultisigTransaction(transaction *MultisigTransaction) error {
+	txHash, err := transaction.SigningHash()
+	if err != nil {
+		return err
+	}
+	return state.revert(txHash)
+}
+
+// CommitUTXOTransaction spends the inputs of tx and creates its outputs.
+// Inputs must be unspent, not held by multisig wallets and satisfy the lock
+// scripts of their outputs, and must add up to the outputs plus the fee.
+func (state *UTXOLedgerState) CommitUTXOTransaction(tx *UTXOTransaction, height uint32, timestamp int64) error {
+	if err := tx.validate(); err != nil {
+		return err
+	}
+	txHash, err := tx.Hash()
+	if err != nil {
+		return err
+	}
+	sigHash, err := tx.SigningHash()
+	if err != nil {
+		return err
+	}
+	ctx := script.Context{SigHash: sigHash, Height: height, Timestamp: timestamp}
+
+	return state.commit(txHash, func(change *ledgerChange) error {
+		total := float64(0)
+		for _, input := range tx.Inputs {
+			output, ok := state.utxos[input.OutPoint]
+			if !ok {
+				return fmt.Errorf("output (%s) is spent or does not exist", input.OutPoint)
+			}
+			if IsMultisigAddress(output.Owner) {
+				return fmt.Errorf("output (%s) of (%s) can only be spent by a multisig transaction", input.OutPoint, output.Owner)
+			}
+			if err := checkUnlock(input, output, ctx); err != nil {
+				return err
+			}
+			total += output.Amount
+			state.spend(change, input.OutPoint)
+		}
+
+		spent := tx.Fee
+		for _, output := range tx.Outputs {
+			if !state.HasWallet(output.Owner) {
+				return fmt.Errorf("no member with id (%s) is present in ledger", output.Owner)
+			}
+			spent += output.Amount
+			if err := state.createOutput(change, output); err != nil {
+				return err
+			}
+		}
+		// Written so that a NaN on either side fails the check
+		if !(math.Abs(total-spent) <= utxoAmountTolerance) {
+			return fmt.Errorf("utxo inputs (%f) do not match outputs and fee (%f)", total, spent)
+		}
+		return nil
+	})
+}
+
+// checkUnlock runs the unlock script of input against the lock script of
+// the output it spends.
+func checkUnlock(input TxInput, output TxOutput, ctx script.Context) error {
+	if len(output.Script) == 0 {
+		if len(input.Unlock) != 0 {
+			return fmt.Errorf("output (%s) has no lock script to unlock", input.OutPoint)
+		}
+		return nil
+	}
+	if err := script.Verify(input.Unlock, output.Script, ctx, script.DefaultLimits); err != nil {
+		return fmt.Errorf("output (%s) is not unlocked: %s", input.OutPoint, err)
+	}
+	return nil
+}
+
+func (state *UTXOLedgerState) RevertUTXOTransaction(tx *UTXOTransaction) error {
+	txHash, err := tx.Hash()
+	if err != nil {
+		return err
+	}
+	return state.revert(txHash)
+}
+
+func (state *UTXOLedgerState) commit(txHash types.Hash, apply func(*ledgerChange) error) error {
+	change := &ledgerChange{txHash: txHash}
+	if err := apply(change); err != nil {
+		change.rollback()
+		return err
+	}
+	state.journal = append(state.journal, change)
+	return nil
+}
+
+func (state *UTXOLedgerState) revert(txHash types.Hash) error {
+	if len(state.journal) == 0 || state.journal[len(state.journal)-1].txHash != txHash {
+		return fmt.Errorf("transaction (%s) is not the last committed transaction", txHash.String())
+	}
+	last := len(state.journal) - 1
+	state.journal[last].rollback()
+	state.journal = state.journal[:last]
+	return nil
+}
+
+// applyTransaction applies transaction according to its type, recording
+// the undo steps in change.
+func (state *UTXOLedgerState) applyTransaction(change *ledgerChange, transaction *Transaction) error {
+	from, to := transaction.From, transaction.To
+	validator, amt := transaction.Validator, transaction.Amount
+
+	switch transaction.Type {
+	case core.TxTypeTransfer:
+		if !state.HasWallet(to) {
+			return fmt.Errorf("no member with id (%s) is present in ledger", to)
+		}
+		if transaction.Asset != "" {
+			if err := state.pay(change, from, "", 0, transaction.Fee); err != nil {
+				return err
+			}
+			return state.transferAsset(change, transaction.Asset, from, to, amt)
+		}
+		return state.pay(change, from, to, amt, transaction.Fee)
+
+	case core.TxTypeIssueAsset:
+		if !state.HasWallet(from) {
+			return fmt.Errorf("no member with id (%s) is present in ledger", from)
+		}
+		return state.issueAsset(change, Asset{Id: transaction.Asset, Issuer: from, Supply: amt})
+
+	case core.TxTypeCoinbase:
+		if !state.HasWallet(to) {
+			state.addWallet(change, to)
+		}
+		return state.createOutput(change, TxOutput{Owner: to, Amount: amt})
+
+	case core.TxTypeRegistration:
+		if state.HasWallet(to) {
+			return fmt.Errorf("member with id (%s) is already present in ledger", to)
+		}
+		state.addWallet(change, to)
+		return state.createOutput(change, TxOutput{Owner: to, Amount: amt})
+
+	case core.TxTypeStake:
+		if owner, ok := state.stakeOwners[validator]; ok && owner != from {
+			return fmt.Errorf("validator (%s) is staked by (%s)", validator, owner)
+		}
+		if err := state.pay(change, from, "", amt, 0); err != nil {
+			return err
+		}
+		state.setStake(change, validator, state.stakes[validator]+amt)
+		state.setStakeOwner(change, validator, from)
+		return nil
+
+	case core.TxTypeUnstake:
+		if owner := state.stakeOwners[validator]; owner != to {
+			return fmt.Errorf("stake of validator (%s) is not owned by (%s)", validator, to)
+		}
+		if state.stakes[validator] < amt {
+			return fmt.Errorf("validator (%s) does not have (%f) stake to unlock", validator, amt)
+		}
+		state.setStake(change, validator, state.stakes[validator]-amt)
+		return state.createOutput(change, TxOutput{Owner: to, Amount: amt})
+
+	case core.TxTypeSlash:
+		if state.stakes[validator] < amt {
+			return fmt.Errorf("validator (%s) does not have (%f) stake to slash", validator, amt)
+		}
+		if err := state.useSlashEvidence(change, transaction.Proof); err != nil {
+			return err
+		}
+		state.setStake(change, validator, state.stakes[validator]-amt)
+		return nil
+
+	case core.TxTypeEscrow:
+		if !state.HasWallet(to) {
+			return fmt.Errorf("no member with id (%s) is present in ledger", to)
+		}
+		if err := state.pay(change, from, "", amt, 0); err != nil {
+			return err
+		}
+		state.setEscrows(change, to, append(state.GetEscrows(to), Escrow{Amount: amt, Lock: transaction.Lock}))
+		return nil
+
+	case core.TxTypeRelease:
+		if !state.HasWallet(to) {
+			return fmt.Errorf("no member with id (%s) is present in ledger", to)
+		}
+		escrows, ok := removeEscrow(state.escrows[to], Escrow{Amount: amt, Lock: transaction.Lock})
+		if !ok {
+			return fmt.Errorf("wallet (%s) has no escrow of (%f) with the release lock", to, amt)
+		}
+		state.setEscrows(change, to, escrows)
+		return state.createOutput(change, TxOutput{Owner: to, Amount: amt})
+
+	default:
+		return fmt.Errorf("ledger cannot apply transaction type (%s)", core.TxTypeToString(transaction.Type))
+	}
+}
+
+// outputOwners lists the owners of the outputs applyTransaction creates for
+// transaction, by output index.
+func (transaction *Transaction) outputOwners() []string {
+	switch transaction.Type {
+	case core.TxTypeTransfer:
+		if transaction.Asset != "" {
+			return []string{transaction.From}
+		}
+		return []string{transaction.To, transaction.From}
+	case core.TxTypeStake, core.TxTypeEscrow:
+		return []string{transaction.From}
+	case core.TxTypeCoinbase, core.TxTypeRegistration, core.TxTypeUnstake, core.TxTypeRelease:
+		return []string{transaction.To}
+	default:
+		return nil
+	}
+}
+
+// pay spends unscripted outputs of from covering amt and fee, pays amt to
+// the wallet to unless it is empty, and returns the change to from.
+func (state *UTXOLedgerState) pay(change *ledgerChange, from, to string, amt, fee float64) error {
+	if !state.HasWallet(from) {
+		return fmt.Errorf("no member with id (%s) is present in ledger", from)
+	}
+
+	total := float64(0)
+	for _, utxo := range state.GetUTXOs(from) {
+		if total >= amt+fee {
+			break
+		}
+		if len(utxo.Output.Script) != 0 {
+			continue
+		}
+		total += utxo.Output.Amount
+		state.spend(change, utxo.OutPoint)
+	}
+	if total < amt+fee {
+		return fmt.Errorf("sender (%s) does not have enough balance", from)
+	}
+
+	if to != "" {
+		if err := state.createOutput(change, TxOutput{Owner: to, Amount: amt}); err != nil {
+			return err
+		}
+	}
+	return state.createOutput(change, TxOutput{Owner: from, Amount: total - amt - fee})
+}
+
+func (state *UTXOLedgerState) spend(change *ledgerChange, outPoint OutPoint) {
+	output := state.utxos[outPoint]
+	delete(state.utxos, outPoint)
+	change.undo = append(change.undo, func() {
+		state.utxos[outPoint] = output
+	})
+}
+
+// createOutput adds the next output of the committed transaction. Empty
+// outputs only take up their index.
+func (state *UTXOLedgerState) createOutput(change *ledgerChange, output TxOutput) error {
+	outPoint := OutPoint{TxHash: change.txHash, Index: change.outputs}
+	change.outputs++
+	if output.Amount <= 0 {
+		return nil
+	}
+	if _, ok := state.utxos[outPoint]; ok {
+		return fmt.Errorf("output (%s) already exists", outPoint)
+	}
+
+	state.utxos[outPoint] = output
+	change.undo = append(change.undo, func() {
+		delete(state.utxos, outPoint)
+	})
+	return nil
+}
+
+func (state *UTXOLedgerState) issueAsset(change *ledgerChange, asset Asset) error {
+	if err := state.issue(asset); err != nil {
+		return err
+	}
+	change.undo = append(change.undo, func() {
+		state.unissue(asset.Id)
+	})
+	return nil
+}
+
+func (state *UTXOLedgerState) transferAsset(change *ledgerChange, asset string, from, to string, amt float64) error {
+	if err := state.assetBook.transfer(asset, from, to, amt); err != nil {
+		return err
+	}
+	change.undo = append(change.undo, func() {
+		state.assetBook.transfer(asset, to, from, amt)
+	})
+	return nil
+}
+
+func (state *UTXOLedgerState) useMultisigNonce(change *ledgerChange, walletId string, nonce uint64) error {
+	if err := state.multisigNonces.use(walletId, nonce); err != nil {
+		return err
+	}
+	change.undo = append(change.undo, func() { state.multisigNonces.release(walletId, nonce) })
+	return nil
+}
+
+func (state *UTXOLedgerState) useSlashEvidence(change *ledgerChange, proof []byte) error {
+	if err := state.slashEvidence.use(proof); err != nil {
+		return err
+	}
+	change.undo = append(change.undo, func() { state.slashEvidence.release(proof) })
+	return nil
+}
+
+func (state *UTXOLedgerState) addWallet(change *ledgerChange, walletId string) {
+	state.wallets[walletId] = struct{}{}
+	change.undo = append(change.undo, func() {
+		delete(state.wallets, walletId)
+	})
+}
+
+func (state *UTXOLedgerState) setStake(change *ledgerChange, validator string, stake float64) {
+	prevStake, ok := state.stakes[validator]
+	state.stakes[validator] = stake
+	change.undo = append(change.undo, func() {
+		if ok {
+			state.stakes[validator] = prevStake
+		} else {
+			delete(state.stakes, validator)
+		}
+	})
+}
+
+func (state *UTXOLedgerState) setStakeOwner(change *ledgerChange, validator string, owner string) {
+	prevOwner, ok := state.stakeOwners[validator]
+	state.stakeOwners[validator] = owner
+	change.undo = append(change.undo, func() {
+		if ok {
+			state.stakeOwners[validator] = prevOwner
+		} else {
+			delete(state.stakeOwners, validator)
+		}
+	})
+}
+
+func (state *UTXOLedgerState) setEscrows(change *ledgerChange, walletId string, escrows []Escrow) {
+	prevEscrows := state.escrows[walletId]
+	state.escrows[walletId] = escrows
+	change.undo = append(change.undo, func() {
+		if prevEscrows == nil {
+			delete(state.escrows, walletId)
+		} else {
+			state.escrows[walletId] = prevEscrows
+		}
+	})
+}
+
+func (state *UTXOLedgerState) GetBalance(id string) (float64, error) {
+	if !state.HasWallet(id) {
+		return 0, fmt.Errorf("member with id (%s) is not present in the ledger", id)
+	}
+	// Scripted outputs are not spendable by their owner alone
+	balance := float64(0)
+	for _, utxo := range state.GetUTXOs(id) {
+		if len(utxo.Output.Script) == 0 {
+			balance += utxo.Output.Amount
+		}
+	}
+	return balance, nil
+}
+
+// GetUTXOs returns the unspent outputs owned by the wallet in outpoint
+// order.
+func (state *UTXOLedgerState) GetUTXOs(walletId string) []UTXO {
+	utxos := make([]UTXO, 0)
+	for outPoint, output := range state.utxos {
+		if output.Owner == walletId {
+			utxos = append(utxos, UTXO{OutPoint: outPoint, Output: output})
+		}
+	}
+	sort.Slice(utxos, func(i, j int) bool {
+		return utxos[i].OutPoint.less(utxos[j].OutPoint)
+	})
+	return utxos
+}
+
+func (state *UTXOLedgerState) GetWallets() []string {
+	wallets := make([]string, 0, len(state.wallets))
+	for wallet := range state.wallets {
+		wallets = append(wallets, wallet)
+	}
+	return wallets
+}
+
+func (state *UTXOLedgerState) GetStake(validator string) float64 {
+	return state.stakes[validator]
+}
+
+func (state *UTXOLedgerState) GetStakes() map[string]float64 {
+	stakes := make(map[string]float64, len(state.stakes))
+	for validator, stake := range state.stakes {
+		if stake > 0 {
+			stakes[validator] = stake
+		}
+	}
+	return stakes
+}
+
+func (state *UTXOLedgerState) GetEscrows(walletId string) []Escrow {
+	escrows := make([]Escrow, len(state.escrows[walletId]))
+	copy(escrows, state.escrows[walletId])
+	return escrows
+}
@@ -0,0 +1,391 @@
+package currency
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+	"github.com/tusharjoshi4531/block-chain.git/script"
+)
+
+func TestUTXOLedgerTransactions(t *testing.T) {
+	state := NewUTXOLedgerState()
+	assert.Nil(t, state.AddWallet("A", 100))
+	assert.Nil(t, state.AddWallet("B", 0))
+	assert.NotNil(t, state.AddWallet("A", 100))
+
+	// Transfers spend the sender's outputs and return the change
+	transfer := NewTransaction("A", "B", 30)
+	transfer.Fee = 5
+	assert.Nil(t, state.CommitTransaciton(transfer))
+	balance, err := state.GetBalance("A")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(65), balance)
+	balance, err = state.GetBalance("B")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(30), balance)
+	assert.Equal(t, 1, len(state.GetUTXOs("A")))
+
+	utxos := state.GetUTXOs("B")
+	assert.Equal(t, 1, len(utxos))
+	transferHash, err := transfer.Hash()
+	assert.Nil(t, err)
+	assert.Equal(t, OutPoint{TxHash: transferHash, Index: 0}, utxos[0].OutPoint)
+
+	assert.NotNil(t, state.CommitTransaciton(NewTransaction("B", "A", 31)))
+
+	// Only the last committed transaction can be reverted
+	stake := NewStakeTransaction("A", "v", 15)
+	assert.Nil(t, state.CommitTransaciton(stake))
+	assert.NotNil(t, state.RevertTransaction(transfer))
+	assert.Nil(t, state.RevertTransaction(stake))
+	assert.Nil(t, state.RevertTransaction(transfer))
+
+	balance, err = state.GetBalance("A")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(100), balance)
+	assert.Equal(t, []UTXO{{OutPoint: allocationOutPoint("A"), Output: TxOutput{Owner: "A", Amount: 100}}}, state.GetUTXOs("A"))
+	assert.Empty(t, state.GetUTXOs("B"))
+	assert.Equal(t, float64(0), state.GetStake("v"))
+}
+
+func TestUTXOLedgerSpends(t *testing.T) {
+	state := NewUTXOLedgerState()
+	assert.Nil(t, state.AddWallet("A", 100))
+	assert.Nil(t, state.AddWallet("B", 0))
+	input := allocationOutPoint("A")
+
+	// Inputs must cover the outputs and the fee exactly
+	assert.NotNil(t, state.CommitUTXOTransaction(NewUTXOTransaction(
+		[]TxInput{{OutPoint: input}},
+		[]TxOutput{{Owner: "B", Amount: 100}},
+		1,
+	), 0, 0))
+	assert.NotNil(t, state.CommitUTXOTransaction(NewUTXOTransaction(
+		[]TxInput{{OutPoint: input}},
+		[]TxOutput{{Owner: "C", Amount: 100}},
+		0,
+	), 0, 0))
+	assert.Equal(t, 1, len(state.GetUTXOs("A")))
+
+	// A NaN fee cannot balance an inflated output
+	assert.NotNil(t, state.CommitUTXOTransaction(NewUTXOTransaction(
+		[]TxInput{{OutPoint: input}},
+		[]TxOutput{{Owner: "B", Amount: 1e12}},
+		math.NaN(),
+	), 0, 0))
+	assert.NotNil(t, state.CommitUTXOTransaction(NewUTXOTransaction(
+		[]TxInput{{OutPoint: input}},
+		[]TxOutput{{Owner: "B", Amount: math.NaN()}},
+		0,
+	), 0, 0))
+	assert.Empty(t, state.GetUTXOs("B"))
+
+	spend := NewUTXOTransaction(
+		[]TxInput{{OutPoint: input}},
+		[]TxOutput{{Owner: "B", Amount: 60}, {Owner: "A", Amount: 39}},
+		1,
+	)
+	assert.Nil(t, state.CommitUTXOTransaction(spend, 0, 0))
+	balance, err := state.GetBalance("B")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(60), balance)
+
+	// The spent output cannot be spent again
+	assert.NotNil(t, state.CommitUTXOTransaction(NewUTXOTransaction(
+		[]TxInput{{OutPoint: input}},
+		[]TxOutput{{Owner: "B", Amount: 100}},
+		0,
+	), 0, 0))
+
+	assert.Nil(t, state.RevertUTXOTransaction(spend))
+	balance, err = state.GetBalance("A")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(100), balance)
+	assert.Empty(t, state.GetUTXOs("B"))
+}
+
+func TestUTXOBlockChain(t *testing.T) {
+	state := NewUTXOLedgerState()
+	assert.Nil(t, state.AddWallet("A", 100))
+	assert.Nil(t, state.AddWallet("B", 0))
+	privKey := crypto.GeneratePrivateKey()
+	bc := NewBlockChain(state, 100)
+	genesisHash, err := bc.GetGenesis().Hash()
+	assert.Nil(t, err)
+
+	signed := func(tx *UTXOTransaction) *core.Transaction {
+		coreTx, err := tx.ToCoreTransaction()
+		assert.Nil(t, err)
+		assert.Nil(t, coreTx.Sign(privKey))
+		return coreTx
+	}
+
+	// Outputs created earlier in a block can be spent later in it
+	first := NewUTXOTransaction([]TxInput{{OutPoint: allocationOutPoint("A")}}, []TxOutput{{Owner: "B", Amount: 100}}, 0)
+	firstHash, err := first.Hash()
+	assert.Nil(t, err)
+	second := NewUTXOTransaction([]TxInput{{OutPoint: OutPoint{TxHash: firstHash}}}, []TxOutput{{Owner: "A", Amount: 40}, {Owner: "B", Amount: 60}}, 0)
+
+	block := core.NewBlockWithHeaderInfo(1, genesisHash)
+	block.AddTransaction(signed(first))
+	block.AddTransaction(signed(second))
+	block.AddTransaction(createTransaction(t, "A", "B", 10, privKey))
+	assert.Nil(t, bc.AddBlock(block))
+
+	balance, err := state.GetBalance("A")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(30), balance)
+	balance, err = state.GetBalance("B")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(70), balance)
+
+	// Blocks may not spend an output twice
+	block = core.NewBlockWithHeaderInfo(1, genesisHash)
+	block.AddTransaction(signed(first))
+	block.AddTransaction(signed(NewUTXOTransaction([]TxInput{{OutPoint: allocationOutPoint("A")}}, []TxOutput{{Owner: "A", Amount: 100}}, 0)))
+	assert.NotNil(t, bc.AddBlock(block))
+
+	// A longer fork restores the outputs spent by the block
+	prevHash := genesisHash
+	for height := uint32(1); height <= 2; height++ {
+		block = core.NewBlockWithHeaderInfo(height, prevHash)
+		assert.Nil(t, bc.AddBlock(block))
+		prevHash, err = block.Hash()
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, []UTXO{{OutPoint: allocationOutPoint("A"), Output: TxOutput{Owner: "A", Amount: 100}}}, state.GetUTXOs("A"))
+	assert.Empty(t, state.GetUTXOs("B"))
+
+	// Account ledgers do not accept UTXO transactions
+	accountChain := NewBlockChain(NewMemoryLedgerState(), 100)
+	block = core.NewBlockWithHeaderInfo(1, genesisHash)
+	block.AddTransaction(signed(first))
+	assert.NotNil(t, accountChain.AddBlock(block))
+}
+
+func TestUTXORepeatedTransfers(t *testing.T) {
+	privKeys := createMultisigKeys(2)
+	wallet, err := NewMultisigWallet(2, publicKeys(privKeys))
+	assert.Nil(t, err)
+	address := wallet.Address()
+
+	state := NewUTXOLedgerState()
+	assert.Nil(t, state.AddWallet("A", 100))
+	assert.Nil(t, state.AddWallet("B", 0))
+	assert.Nil(t, state.AddWallet(address, 100))
+	privKey := crypto.GeneratePrivateKey()
+	bc := NewBlockChain(state, 100)
+	prevHash, err := bc.GetGenesis().Hash()
+	assert.Nil(t, err)
+
+	// Paying the same amount again creates new outputs
+	for height := uint32(1); height <= 2; height++ {
+		spend := NewMultisigTransaction(wallet, "B", 5, 0, uint64(height))
+		for _, key := range privKeys {
+			assert.Nil(t, spend.Approve(key))
+		}
+		spendTx, err := spend.ToCoreTransaction()
+		assert.Nil(t, err)
+		assert.Nil(t, spendTx.Sign(privKey))
+
+		block := core.NewBlockWithHeaderInfo(height, prevHash)
+		block.AddTransaction(createTransaction(t, "A", "B", 5, privKey))
+		block.AddTransaction(spendTx)
+		assert.Nil(t, bc.AddBlock(block))
+		prevHash, err = block.Hash()
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, uint32(2), bc.Height())
+	balance, err := state.GetBalance("B")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(20), balance)
+	assert.Equal(t, 4, len(state.GetUTXOs("B")))
+
+	// A fork restores the allocations
+	forkHash, err := bc.GetGenesis().Hash()
+	assert.Nil(t, err)
+	for height := uint32(1); height <= 3; height++ {
+		block := core.NewBlockWithHeaderInfo(height, forkHash)
+		assert.Nil(t, bc.AddBlock(block))
+		forkHash, err = block.Hash()
+		assert.Nil(t, err)
+	}
+	assert.Empty(t, state.GetUTXOs("B"))
+	balance, err = state.GetBalance("A")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(100), balance)
+}
+
+// htlcSpend spends the first output of lockTx to owner, with the unlock
+// script built from the signature of privKey.
+func htlcSpend(t *testing.T, lockTx *UTXOTransaction, owner string, privKey *ecdsa.PrivateKey, unlock func(sig []byte) []byte) *UTXOTransaction {
+	lockHash, err := lockTx.Hash()
+	assert.Nil(t, err)
+	tx := NewUTXOTransaction(
+		[]TxInput{{OutPoint: OutPoint{TxHash: lockHash}}},
+		[]TxOutput{{Owner: owner, Amount: lockTx.Outputs[0].Amount}},
+		0,
+	)
+	sigHash, err := tx.SigningHash()
+	assert.Nil(t, err)
+	sig, err := script.SignatureBytes(privKey, sigHash)
+	assert.Nil(t, err)
+	tx.Inputs[0].Unlock = unlock(sig)
+	return tx
+}
+
+func TestHTLCCrossChainSwap(t *testing.T) {
+	alice, bob := crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey()
+	secret := []byte("swap secret")
+	hash := sha256.Sum256(secret)
+
+	// Alice trades 100 on chain one for Bob's 50 on chain two
+	chainOne, chainTwo := NewUTXOLedgerState(), NewUTXOLedgerState()
+	for _, state := range []*UTXOLedgerState{chainOne, chainTwo} {
+		assert.Nil(t, state.AddWallet("Alice", 100))
+		assert.Nil(t, state.AddWallet("Bob", 50))
+	}
+
+	// Alice locks first with the longer timeout, so that Bob can always
+	// claim after she reveals the secret
+	aliceLock := NewUTXOTransaction(
+		[]TxInput{{OutPoint: allocationOutPoint("Alice")}},
+		[]TxOutput{{Owner: "Bob", Amount: 100, Script: script.NewHTLC(hash, &bob.PublicKey, &alice.PublicKey, 10)}},
+		0,
+	)
+	assert.Nil(t, chainOne.CommitUTXOTransaction(aliceLock, 1, 0))
+	bobLock := NewUTXOTransaction(
+		[]TxInput{{OutPoint: allocationOutPoint("Bob")}},
+		[]TxOutput{{Owner: "Alice", Amount: 50, Script: script.NewHTLC(hash, &alice.PublicKey, &bob.PublicKey, 5)}},
+		0,
+	)
+	assert.Nil(t, chainTwo.CommitUTXOTransaction(bobLock, 1, 0))
+
+	// Locked outputs are not part of the balance
+	balance, err := chainOne.GetBalance("Bob")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(50), balance)
+	assert.NotNil(t, chainOne.CommitTransaciton(NewTransaction("Bob", "Alice", 100)))
+
+	// Neither side can take the funds back before the timeout
+	assert.NotNil(t, chainTwo.CommitUTXOTransaction(htlcSpend(t, bobLock, "Bob", bob, script.HTLCRefund), 2, 0))
+	assert.NotNil(t, chainTwo.CommitUTXOTransaction(htlcSpend(t, bobLock, "Alice", alice, func(sig []byte) []byte {
+		return script.HTLCClaim(sig, []byte("wrong secret"))
+	}), 2, 0))
+
+	// Alice claims on chain two, revealing the secret
+	aliceClaim := htlcSpend(t, bobLock, "Alice", alice, func(sig []byte) []byte {
+		return script.HTLCClaim(sig, secret)
+	})
+	assert.Nil(t, chainTwo.CommitUTXOTransaction(aliceClaim, 2, 0))
+
+	// Bob reads the secret from the claim and claims on chain one
+	claimTx, err := aliceClaim.ToCoreTransaction()
+	assert.Nil(t, err)
+	seen, err := NewUTXOTransactionFromCoreTransaction(claimTx)
+	assert.Nil(t, err)
+	revealed, ok := script.HTLCPreimage(seen.Inputs[0].Unlock)
+	assert.True(t, ok)
+	assert.Equal(t, secret, revealed)
+
+	bobClaim := htlcSpend(t, aliceLock, "Bob", bob, func(sig []byte) []byte {
+		return script.HTLCClaim(sig, revealed)
+	})
+	assert.Nil(t, chainOne.CommitUTXOTransaction(bobClaim, 3, 0))
+
+	balance, err = chainOne.GetBalance("Bob")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(150), balance)
+	balance, err = chainTwo.GetBalance("Alice")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(150), balance)
+}
+
+func TestHTLCRefundInBlocks(t *testing.T) {
+	alice, bob := crypto.GeneratePrivateKey(), crypto.GeneratePrivateKey()
+	state := NewUTXOLedgerState()
+	assert.Nil(t, state.AddWallet("Alice", 100))
+	assert.Nil(t, state.AddWallet("Bob", 0))
+	bc := NewBlockChain(state, 100)
+
+	lock := NewUTXOTransaction(
+		[]TxInput{{OutPoint: allocationOutPoint("Alice")}},
+		[]TxOutput{{Owner: "Bob", Amount: 100, Script: script.NewHTLC(sha256.Sum256([]byte("secret")), &bob.PublicKey, &alice.PublicKey, 3)}},
+		0,
+	)
+	refund := htlcSpend(t, lock, "Alice", alice, script.HTLCRefund)
+
+	addBlock := func(txs ...*UTXOTransaction) error {
+		prevHash, err := bc.GetHeighestBlock().Hash()
+		assert.Nil(t, err)
+		block := core.NewBlockWithHeaderInfo(bc.Height()+1, prevHash)
+		for _, tx := range txs {
+			coreTx, err := tx.ToCoreTransaction()
+			assert.Nil(t, err)
+			assert.Nil(t, coreTx.Sign(alice))
+			block.AddTransaction(coreTx)
+		}
+		return bc.AddBlock(block)
+	}
+
+	// A block refunding below the timeout height is rejected
+	assert.Nil(t, addBlock(lock))
+	assert.NotNil(t, addBlock(refund))
+	assert.Equal(t, uint32(1), bc.Height())
+	balance, err := state.GetBalance("Alice")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(0), balance)
+
+	assert.Nil(t, addBlock())
+	assert.Nil(t, addBlock(refund))
+	balance, err = state.GetBalance("Alice")
+	assert.Nil(t, err)
+	assert.Equal(t, float64(100), balance)
+	assert.Empty(t, state.GetUTXOs("Bob"))
+}
+
+func TestLockTimeInBlocks(t *testing.T) {
+	alice := crypto.GeneratePrivateKey()
+	state := NewUTXOLedgerState()
+	assert.Nil(t, state.AddWallet("Alice", 100))
+	bc := NewBlockChain(state, 100)
+
+	unlockTime := time.Now().Add(time.Hour).UnixNano()
+	lock := NewUTXOTransaction(
+		[]TxInput{{OutPoint: allocationOutPoint("Alice")}},
+		[]TxOutput{{Owner: "Alice", Amount: 100, Script: script.NewBuilder().AddInt(unlockTime).AddOp(script.OpCheckLockTimeVerify).Script()}},
+		0,
+	)
+	lockHash, err := lock.Hash()
+	assert.Nil(t, err)
+	spend := NewUTXOTransaction([]TxInput{{OutPoint: OutPoint{TxHash: lockHash}}}, []TxOutput{{Owner: "Alice", Amount: 100}}, 0)
+
+	addBlock := func(timestamp int64, tx *UTXOTransaction) error {
+		prev := bc.GetHeighestBlock()
+		prevHash, err := prev.Hash()
+		assert.Nil(t, err)
+		block := core.NewBlockWithHeaderInfo(bc.Height()+1, prevHash)
+		block.Header.Timestamp = timestamp
+		coreTx, err := tx.ToCoreTransaction()
+		assert.Nil(t, err)
+		assert.Nil(t, coreTx.Sign(alice))
+		block.AddTransaction(coreTx)
+		return bc.AddBlock(block)
+	}
+	assert.Nil(t, addBlock(time.Now().UnixNano(), lock))
+
+	// The producer cannot stamp the block past the lock time to spend early
+	assert.NotNil(t, addBlock(unlockTime, spend))
+	assert.NotNil(t, addBlock(time.Now().UnixNano(), spend))
+	assert.NotNil(t, addBlock(time.Now().UnixNano(), spend))
+	assert.Equal(t, uint32(1), bc.Height())
+	utxos := state.GetUTXOs("Alice")
+	assert.Equal(t, 1, len(utxos))
+	assert.Equal(t, lockHash, utxos[0].OutPoint.TxHash)
+}
@@ -0,0 +1,102 @@
+package currency
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+	"github.com/tusharjoshi4531/block-chain.git/script"
+	"github.com/tusharjoshi4531/block-chain.git/types"
+)
+
+func TestUTXOTransactionEncoding(t *testing.T) {
+	tx := NewUTXOTransaction(
+		[]TxInput{{OutPoint: OutPoint{TxHash: types.Hash{1}, Index: 0}, Unlock: []byte{}}, {OutPoint: OutPoint{TxHash: types.Hash{2}, Index: 3}, Unlock: []byte{0x01, 0xab}}},
+		[]TxOutput{{Owner: "A", Amount: 10, Script: []byte{}}, {Owner: "B", Amount: 5, Script: []byte{byte(script.OpTrue)}}},
+		1,
+	)
+
+	coreTx, err := tx.ToCoreTransaction()
+	assert.Nil(t, err)
+	decoded, err := NewUTXOTransactionFromCoreTransaction(coreTx)
+	assert.Nil(t, err)
+	assert.Equal(t, tx, decoded)
+
+	hash, err := tx.Hash()
+	assert.Nil(t, err)
+	assert.Equal(t, coreTx.Hash(), hash)
+
+	// Currency transactions are not UTXO transactions and the other way round
+	transfer, err := NewTransaction("A", "B", 1).ToCoreTransaction()
+	assert.Nil(t, err)
+	_, err = NewUTXOTransactionFromCoreTransaction(transfer)
+	assert.NotNil(t, err)
+	_, err = NewTransactionFromCoreTransaction(coreTx)
+	assert.NotNil(t, err)
+
+	data, err := tx.ToBytes()
+	assert.Nil(t, err)
+	assert.NotNil(t, (&UTXOTransaction{}).Decode(bytes.NewReader(data[:len(data)-1])))
+
+	assert.Equal(t, "ab"+strings.Repeat("00", 31)+":3", OutPoint{TxHash: types.Hash{0xab}, Index: 3}.String())
+}
+
+func TestValidateUTXOTransaction(t *testing.T) {
+	privKey := crypto.GeneratePrivateKey()
+	validate := func(tx *UTXOTransaction) error {
+		coreTx, err := tx.ToCoreTransaction()
+		assert.Nil(t, err)
+		assert.Nil(t, coreTx.Sign(privKey))
+		return core.ValidateTransaction(coreTx)
+	}
+
+	input := TxInput{OutPoint: OutPoint{TxHash: types.Hash{1}}}
+	output := TxOutput{Owner: "B", Amount: 10}
+	assert.Nil(t, validate(NewUTXOTransaction([]TxInput{input}, []TxOutput{output}, 0)))
+
+	assert.NotNil(t, validate(NewUTXOTransaction(nil, []TxOutput{output}, 0)))
+	assert.NotNil(t, validate(NewUTXOTransaction([]TxInput{input}, nil, 0)))
+	assert.NotNil(t, validate(NewUTXOTransaction([]TxInput{input, input}, []TxOutput{output}, 0)))
+	assert.NotNil(t, validate(NewUTXOTransaction([]TxInput{input}, []TxOutput{output}, -1)))
+	assert.NotNil(t, validate(NewUTXOTransaction([]TxInput{input}, []TxOutput{{Owner: "B", Amount: 0}}, 0)))
+	assert.NotNil(t, validate(NewUTXOTransaction([]TxInput{input}, []TxOutput{{Owner: RewardSymbol, Amount: 10}}, 0)))
+	for _, value := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		assert.NotNil(t, validate(NewUTXOTransaction([]TxInput{input}, []TxOutput{output}, value)))
+		assert.NotNil(t, validate(NewUTXOTransaction([]TxInput{input}, []TxOutput{{Owner: "B", Amount: value}}, 0)))
+	}
+
+	// Unlock scripts only push data and lock scripts must parse
+	unlocking := TxInput{OutPoint: input.OutPoint, Unlock: []byte{byte(script.OpDup)}}
+	assert.NotNil(t, validate(NewUTXOTransaction([]TxInput{unlocking}, []TxOutput{output}, 0)))
+	assert.NotNil(t, validate(NewUTXOTransaction([]TxInput{input}, []TxOutput{{Owner: "B", Amount: 10, Script: []byte{0x05}}}, 0)))
+}
+
+func TestUTXOSigningHash(t *testing.T) {
+	tx := NewUTXOTransaction(
+		[]TxInput{{OutPoint: OutPoint{TxHash: types.Hash{1}}}},
+		[]TxOutput{{Owner: "A", Amount: 10}},
+		0,
+	)
+	sigHash, err := tx.SigningHash()
+	assert.Nil(t, err)
+	txHash, err := tx.Hash()
+	assert.Nil(t, err)
+
+	// Signatures in the unlock scripts do not change what they sign
+	tx.Inputs[0].Unlock = script.NewBuilder().AddData([]byte("sig")).Script()
+	unlockedHash, err := tx.SigningHash()
+	assert.Nil(t, err)
+	assert.Equal(t, sigHash, unlockedHash)
+	unlockedTxHash, err := tx.Hash()
+	assert.Nil(t, err)
+	assert.NotEqual(t, txHash, unlockedTxHash)
+
+	tx.Outputs[0].Amount = 11
+	changedHash, err := tx.SigningHash()
+	assert.Nil(t, err)
+	assert.NotEqual(t, sigHash, changedHash)
+}
@@ -0,0 +1,84 @@
+package currency
+
+import (
+	"slices"
+
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/types"
+)
+
+// TransactionWallets returns the wallets whose funds tx moves, system
+// wallets excluded: the sender and recipient of an account style
+// transaction, the wallet and recipient of a multisig spend, and the owners
+// of the outputs a UTXO transaction spends and creates. owner names the
+// owners of spent outputs and may be nil; inputs it does not know are
+// skipped.
+func TransactionWallets(tx *core.Transaction, owner func(OutPoint) (string, bool)) []string {
+	wallets := make([]string, 0, 2)
+	add := func(walletId string) {
+		if walletId != "" && !IsSystemWallet(walletId) && !slices.Contains(wallets, walletId) {
+			wallets = append(wallets, walletId)
+		}
+	}
+
+	if utxoTx, err := NewUTXOTransactionFromCoreTransaction(tx); err == nil {
+		for _, input := range utxoTx.Inputs {
+			if owner == nil {
+				break
+			}
+			if walletId, ok := owner(input.OutPoint); ok {
+				add(walletId)
+			}
+		}
+		for _, output := range utxoTx.Outputs {
+			add(output.Owner)
+		}
+		return wallets
+	}
+	if multisigTx, err := NewMultisigTransactionFromCoreTransaction(tx); err == nil {
+		add(multisigTx.Wallet.Address())
+		add(multisigTx.Transfer.To)
+		return wallets
+	}
+
+	transfer, err := NewTransactionFromCoreTransaction(tx)
+	if err != nil {
+		return nil
+	}
+	add(transfer.From)
+	add(transfer.To)
+	return wallets
+}
+
+// TransactionOutputs returns the owners of the outputs a UTXO ledger
+// creates when tx is mined, by outpoint. Outputs that turn out empty are
+// listed as well, since the amounts of account style outputs depend on the
+// ledger.
+func TransactionOutputs(tx *core.Transaction) map[OutPoint]string {
+	outputs := make(map[OutPoint]string)
+	if utxoTx, err := NewUTXOTransactionFromCoreTransaction(tx); err == nil {
+		for i, output := range utxoTx.Outputs {
+			outputs[OutPoint{TxHash: tx.Hash(), Index: uint32(i)}] = output.Owner
+		}
+		return outputs
+	}
+
+	if multisigTx, err := NewMultisigTransactionFromCoreTransaction(tx); err == nil {
+		if txHash, err := multisigTx.SigningHash(); err == nil {
+			addOutputOwners(outputs, txHash, multisigTx.Transfer)
+		}
+		return outputs
+	}
+	if transfer, err := NewTransactionFromCoreTransaction(tx); err == nil {
+		if txHash, err := tx.SignedHash(); err == nil {
+			addOutputOwners(outputs, txHash, transfer)
+		}
+	}
+	return outputs
+}
+
+func addOutputOwners(outputs map[OutPoint]string, txHash types.Hash, transaction *Transaction) {
+	for i, walletId := range transaction.outputOwners() {
+		outputs[OutPoint{TxHash: txHash, Index: uint32(i)}] = walletId
+	}
+}
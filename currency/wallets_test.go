@@ -0,0 +1,76 @@
+package currency
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+)
+
+func TestTransactionWallets(t *testing.T) {
+	privKey := crypto.GeneratePrivateKey()
+	assert.Equal(t, []string{"A", "B"}, TransactionWallets(createTransaction(t, "A", "B", 1, privKey), nil))
+	assert.Equal(t, []string{"A"}, TransactionWallets(createTransaction(t, RewardSymbol, "A", 1, privKey), nil))
+	assert.Nil(t, TransactionWallets(core.NewTransaction([]byte("data")), nil))
+
+	utxoTx, err := NewUTXOTransaction(
+		[]TxInput{{OutPoint: allocationOutPoint("A")}, {OutPoint: allocationOutPoint("C")}},
+		[]TxOutput{{Owner: "B", Amount: 1}, {Owner: "A", Amount: 1}},
+		0,
+	).ToCoreTransaction()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"B", "A"}, TransactionWallets(utxoTx, nil))
+	owner := func(outPoint OutPoint) (string, bool) {
+		return "A", outPoint == allocationOutPoint("A")
+	}
+	assert.Equal(t, []string{"A", "B"}, TransactionWallets(utxoTx, owner))
+}
+
+func TestTransactionOutputs(t *testing.T) {
+	state := NewUTXOLedgerState()
+	assert.Nil(t, state.AddWallet("A", 100))
+	assert.Nil(t, state.AddWallet("B", 0))
+	privKey := crypto.GeneratePrivateKey()
+	bc := NewBlockChain(state, 50)
+	genesisHash, err := bc.GetGenesis().Hash()
+	assert.Nil(t, err)
+
+	signed := func(transaction *Transaction) *core.Transaction {
+		tx, err := transaction.ToCoreTransaction()
+		assert.Nil(t, err)
+		assert.Nil(t, tx.Sign(privKey))
+		return tx
+	}
+	transactions := []*core.Transaction{
+		signed(NewTransaction("A", "B", 30)),
+		signed(NewStakeTransaction("A", "v", 20)),
+		signed(NewUnstakeTransaction("A", "v", 5)),
+		signed(NewRegistrationTransaction("C", 50)),
+		signed(NewCoinbaseTransaction("B", 10, 1)),
+	}
+	block := core.NewBlockWithHeaderInfo(1, genesisHash)
+	for _, tx := range transactions {
+		block.AddTransaction(tx)
+	}
+	assert.Nil(t, bc.AddBlock(block))
+
+	// Every output the ledger created is listed with its owner
+	outputs := make(map[OutPoint]string)
+	for _, tx := range transactions {
+		for outPoint, walletId := range TransactionOutputs(tx) {
+			outputs[outPoint] = walletId
+		}
+	}
+	created := 0
+	for _, walletId := range []string{"A", "B", "C"} {
+		for _, utxo := range state.GetUTXOs(walletId) {
+			if utxo.OutPoint == allocationOutPoint(walletId) {
+				continue
+			}
+			assert.Equal(t, walletId, outputs[utxo.OutPoint])
+			created++
+		}
+	}
+	assert.Equal(t, 5, created)
+}
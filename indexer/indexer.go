@@ -0,0 +1,238 @@
+package indexer
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/tusharjoshi4531/block-chain.git/anchor"
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/currency"
+	"github.com/tusharjoshi4531/block-chain.git/types"
+)
+
+const EventBufferSize = 1024
+
+type TransactionLocation struct {
+	BlockHash types.Hash
+	Height    uint32
+	Index     int
+}
+
+// Indexer maintains lookups from transaction hash to the main chain block
+// containing it, from wallet id to the transactions touching it and from
+// document hash to the transactions anchoring it. It also keeps the owners
+// of the outputs main chain transactions create, so that UTXO spends are
+// indexed under the wallets they spend from.
+type Indexer struct {
+	mu           sync.RWMutex
+	blockChain   core.BlockChain
+	transactions map[types.Hash]TransactionLocation
+	wallets      map[string][]types.Hash
+	anchors      map[types.Hash][]types.Hash
+	outputs      map[currency.OutPoint]string
+	chainEvents  *core.Subscription
+	done         chan struct{}
+}
+
+func NewIndexer(blockChain core.BlockChain) *Indexer {
+	return &Indexer{
+		blockChain:   blockChain,
+		transactions: make(map[types.Hash]TransactionLocation),
+		wallets:      make(map[string][]types.Hash),
+		anchors:      make(map[types.Hash][]types.Hash),
+		outputs:      make(map[currency.OutPoint]string),
+	}
+}
+
+// Start indexes the current main chain and keeps the indexes up to date
+// with blocks connected and disconnected afterwards.
+func (ix *Indexer) Start() error {
+	ix.chainEvents = ix.blockChain.Subscribe(
+		EventBufferSize,
+		core.EventBlockConnected,
+		core.EventBlockDisconnected,
+	)
+	ix.done = make(chan struct{})
+
+	tip := ix.blockChain.GetHeighestBlock()
+	for height := uint32(1); height <= tip.Header.Height; height++ {
+		block, err := ix.blockChain.GetBlockAtHeight(height)
+		if err != nil {
+			ix.chainEvents.Unsubscribe()
+			return err
+		}
+		if err := ix.ConnectBlock(block); err != nil {
+			ix.chainEvents.Unsubscribe()
+			return err
+		}
+	}
+
+	go ix.processEvents(ix.chainEvents, ix.done)
+	return nil
+}
+
+func (ix *Indexer) Stop() {
+	if ix.chainEvents == nil {
+		return
+	}
+	ix.chainEvents.Unsubscribe()
+	<-ix.done
+	ix.chainEvents = nil
+}
+
+func (ix *Indexer) processEvents(sub *core.Subscription, done chan struct{}) {
+	defer close(done)
+	for event := range sub.Events() {
+		switch event.Type {
+		case core.EventBlockConnected:
+			ix.ConnectBlock(event.Block)
+		case core.EventBlockDisconnected:
+			ix.DisconnectBlock(event.Block)
+		}
+	}
+}
+
+func (ix *Indexer) ConnectBlock(block *core.Block) error {
+	blockHash, err := block.Hash()
+	if err != nil {
+		return err
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	for i, tx := range block.Transactions {
+		txHash := tx.Hash()
+		if _, ok := ix.transactions[txHash]; ok {
+			continue
+		}
+
+		ix.transactions[txHash] = TransactionLocation{
+			BlockHash: blockHash,
+			Height:    block.Header.Height,
+			Index:     i,
+		}
+		for _, walletId := range currency.TransactionWallets(tx, ix.outputOwner) {
+			ix.wallets[walletId] = append(ix.wallets[walletId], txHash)
+		}
+		for outPoint, walletId := range currency.TransactionOutputs(tx) {
+			ix.outputs[outPoint] = walletId
+		}
+		if docAnchor, err := anchor.DecodeAnchor(tx); err == nil {
+			ix.anchors[docAnchor.Hash] = append(ix.anchors[docAnchor.Hash], txHash)
+		}
+	}
+	return nil
+}
+
+func (ix *Indexer) DisconnectBlock(block *core.Block) error {
+	blockHash, err := block.Hash()
+	if err != nil {
+		return err
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	disconnected := make([]*core.Transaction, 0, len(block.Transactions))
+	for _, tx := range block.Transactions {
+		txHash := tx.Hash()
+		location, ok := ix.transactions[txHash]
+		if !ok || location.BlockHash != blockHash {
+			continue
+		}
+
+		delete(ix.transactions, txHash)
+		disconnected = append(disconnected, tx)
+		for _, walletId := range currency.TransactionWallets(tx, ix.outputOwner) {
+			ix.wallets[walletId] = removeHash(ix.wallets[walletId], txHash)
+			if len(ix.wallets[walletId]) == 0 {
+				delete(ix.wallets, walletId)
+			}
+		}
+		if docAnchor, err := anchor.DecodeAnchor(tx); err == nil {
+			ix.anchors[docAnchor.Hash] = removeHash(ix.anchors[docAnchor.Hash], txHash)
+			if len(ix.anchors[docAnchor.Hash]) == 0 {
+				delete(ix.anchors, docAnchor.Hash)
+			}
+		}
+	}
+
+	// Outputs are dropped after the whole block, since its transactions may
+	// spend outputs created earlier in it
+	for _, tx := range disconnected {
+		for outPoint := range currency.TransactionOutputs(tx) {
+			delete(ix.outputs, outPoint)
+		}
+	}
+	return nil
+}
+
+func (ix *Indexer) GetTransactionLocation(hash types.Hash) (TransactionLocation, error) {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	location, ok := ix.transactions[hash]
+	if !ok {
+		return TransactionLocation{}, fmt.Errorf("transaction with hash (%s) is not present in the main chain", hash.String())
+	}
+	return location, nil
+}
+
+func (ix *Indexer) GetTransaction(hash types.Hash) (*core.Transaction, TransactionLocation, error) {
+	location, err := ix.GetTransactionLocation(hash)
+	if err != nil {
+		return nil, location, err
+	}
+
+	block, err := ix.blockChain.GetBlockWithHash(location.BlockHash)
+	if err != nil {
+		return nil, location, err
+	}
+	return block.Transactions[location.Index], location, nil
+}
+
+// GetWalletTransactions returns the hashes of main chain transactions that
+// touch the wallet, oldest first.
+func (ix *Indexer) GetWalletTransactions(walletId string) []types.Hash {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	hashes := make([]types.Hash, len(ix.wallets[walletId]))
+	copy(hashes, ix.wallets[walletId])
+	return hashes
+}
+
+// GetAnchorTransactions returns the hashes of main chain transactions that
+// anchor the document, oldest first.
+func (ix *Indexer) GetAnchorTransactions(documentHash types.Hash) []types.Hash {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	hashes := make([]types.Hash, len(ix.anchors[documentHash]))
+	copy(hashes, ix.anchors[documentHash])
+	return hashes
+}
+
+// GetOutputOwner returns the owner of an output created by a main chain
+// transaction.
+func (ix *Indexer) GetOutputOwner(outPoint currency.OutPoint) (string, bool) {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	return ix.outputOwner(outPoint)
+}
+
+func (ix *Indexer) outputOwner(outPoint currency.OutPoint) (string, bool) {
+	walletId, ok := ix.outputs[outPoint]
+	return walletId, ok
+}
+
+func removeHash(hashes []types.Hash, hash types.Hash) []types.Hash {
+	for i := range hashes {
+		if hashes[i] == hash {
+			return append(hashes[:i], hashes[i+1:]...)
+		}
+	}
+	return hashes
+}
@@ -0,0 +1,186 @@
+package indexer
+
+import (
+	"crypto/ecdsa"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tusharjoshi4531/block-chain.git/anchor"
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+	"github.com/tusharjoshi4531/block-chain.git/currency"
+	"github.com/tusharjoshi4531/block-chain.git/types"
+)
+
+func TestIndexConnectDisconnect(t *testing.T) {
+	ledger := currency.NewMemoryLedgerState()
+	ledger.AddWallet("A", 1000)
+	ledger.AddWallet("B", 1000)
+	ledger.AddWallet("C", 1000)
+	bc := currency.NewBlockChain(ledger, 1000)
+	privKey := crypto.GeneratePrivateKey()
+
+	genesisHash, err := bc.GetGenesis().Hash()
+	assert.Nil(t, err)
+
+	// Blocks present before the indexer starts are indexed on start
+	txAB := createTransaction(t, "A", "B", 10, privKey)
+	blockA := addBlock(t, bc, 1, genesisHash, txAB)
+	blockAHash, err := blockA.Hash()
+	assert.Nil(t, err)
+
+	ix := NewIndexer(bc)
+	assert.Nil(t, ix.Start())
+	defer ix.Stop()
+
+	location, err := ix.GetTransactionLocation(txAB.Hash())
+	assert.Nil(t, err)
+	assert.Equal(t, blockAHash, location.BlockHash)
+	assert.Equal(t, []types.Hash{txAB.Hash()}, ix.GetWalletTransactions("B"))
+
+	// Fork replacing blockA
+	txAC := createTransaction(t, "A", "C", 10, privKey)
+	blockB1 := addBlock(t, bc, 1, genesisHash, txAC)
+	blockB1Hash, err := blockB1.Hash()
+	assert.Nil(t, err)
+	txCB := createTransaction(t, "C", "B", 5, privKey)
+	blockB2 := addBlock(t, bc, 2, blockB1Hash, txCB)
+	blockB2Hash, err := blockB2.Hash()
+	assert.Nil(t, err)
+
+	assert.Eventually(t, func() bool {
+		location, err := ix.GetTransactionLocation(txCB.Hash())
+		return err == nil && location.BlockHash == blockB2Hash
+	}, time.Second, 10*time.Millisecond)
+
+	_, err = ix.GetTransactionLocation(txAB.Hash())
+	assert.NotNil(t, err)
+	assert.Equal(t, []types.Hash{txCB.Hash()}, ix.GetWalletTransactions("B"))
+	assert.Equal(t, []types.Hash{txAC.Hash(), txCB.Hash()}, ix.GetWalletTransactions("C"))
+
+	tx, location, err := ix.GetTransaction(txAC.Hash())
+	assert.Nil(t, err)
+	assert.Equal(t, txAC, tx)
+	assert.Equal(t, uint32(1), location.Height)
+}
+
+func TestIndexUTXOAndMultisig(t *testing.T) {
+	key := crypto.GeneratePrivateKey()
+	wallet, err := currency.NewMultisigWallet(1, []*ecdsa.PublicKey{&key.PublicKey})
+	assert.Nil(t, err)
+	ledger := currency.NewUTXOLedgerState()
+	ledger.AddWallet("A", 100)
+	ledger.AddWallet("B", 0)
+	ledger.AddWallet("C", 0)
+	ledger.AddWallet(wallet.Address(), 100)
+	bc := currency.NewBlockChain(ledger, 1000)
+	privKey := crypto.GeneratePrivateKey()
+
+	genesisHash, err := bc.GetGenesis().Hash()
+	assert.Nil(t, err)
+
+	ix := NewIndexer(bc)
+	assert.Nil(t, ix.Start())
+	defer ix.Stop()
+
+	// Multisig spends are indexed under the wallet and the recipient
+	txAB := createTransaction(t, "A", "B", 30, privKey)
+	spend := currency.NewMultisigTransaction(wallet, "C", 10, 0, 1)
+	assert.Nil(t, spend.Approve(key))
+	spendTx, err := spend.ToCoreTransaction()
+	assert.Nil(t, err)
+	assert.Nil(t, spendTx.Sign(privKey))
+	block1 := addBlock(t, bc, 1, genesisHash, txAB, spendTx)
+	block1Hash, err := block1.Hash()
+	assert.Nil(t, err)
+
+	// UTXO spends are indexed under the owners of their inputs and outputs
+	abHash, err := txAB.SignedHash()
+	assert.Nil(t, err)
+	utxoTx, err := currency.NewUTXOTransaction(
+		[]currency.TxInput{{OutPoint: currency.OutPoint{TxHash: abHash}}},
+		[]currency.TxOutput{{Owner: "C", Amount: 30}},
+		0,
+	).ToCoreTransaction()
+	assert.Nil(t, err)
+	assert.Nil(t, utxoTx.Sign(privKey))
+	addBlock(t, bc, 2, block1Hash, utxoTx)
+
+	assert.Eventually(t, func() bool {
+		_, err := ix.GetTransactionLocation(utxoTx.Hash())
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []types.Hash{txAB.Hash(), utxoTx.Hash()}, ix.GetWalletTransactions("B"))
+	assert.Equal(t, []types.Hash{spendTx.Hash(), utxoTx.Hash()}, ix.GetWalletTransactions("C"))
+	assert.Equal(t, []types.Hash{spendTx.Hash()}, ix.GetWalletTransactions(wallet.Address()))
+	owner, ok := ix.GetOutputOwner(currency.OutPoint{TxHash: utxoTx.Hash()})
+	assert.True(t, ok)
+	assert.Equal(t, "C", owner)
+
+	// A longer fork drops them
+	prevHash := genesisHash
+	for height := uint32(1); height <= 3; height++ {
+		block := addBlock(t, bc, height, prevHash)
+		prevHash, err = block.Hash()
+		assert.Nil(t, err)
+	}
+	assert.Eventually(t, func() bool {
+		return len(ix.GetWalletTransactions("C")) == 0
+	}, time.Second, 10*time.Millisecond)
+	assert.Empty(t, ix.GetWalletTransactions("B"))
+	assert.Empty(t, ix.GetWalletTransactions(wallet.Address()))
+	_, ok = ix.GetOutputOwner(currency.OutPoint{TxHash: abHash})
+	assert.False(t, ok)
+}
+
+func TestIndexAnchors(t *testing.T) {
+	bc := currency.NewBlockChain(currency.NewMemoryLedgerState(), 1000)
+	privKey := crypto.GeneratePrivateKey()
+	document := types.Hash{1}
+
+	genesisHash, err := bc.GetGenesis().Hash()
+	assert.Nil(t, err)
+
+	ix := NewIndexer(bc)
+	assert.Nil(t, ix.Start())
+	defer ix.Stop()
+
+	anchorA, err := anchor.NewAnchorTransaction(document, "a", privKey)
+	assert.Nil(t, err)
+	addBlock(t, bc, 1, genesisHash, anchorA)
+	assert.Eventually(t, func() bool {
+		return len(ix.GetAnchorTransactions(document)) == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []types.Hash{anchorA.Hash()}, ix.GetAnchorTransactions(document))
+
+	// Anchors disconnected by a reorg are dropped
+	anchorB, err := anchor.NewAnchorTransaction(document, "b", privKey)
+	assert.Nil(t, err)
+	blockB1 := addBlock(t, bc, 1, genesisHash)
+	blockB1Hash, err := blockB1.Hash()
+	assert.Nil(t, err)
+	addBlock(t, bc, 2, blockB1Hash, anchorB)
+
+	assert.Eventually(t, func() bool {
+		hashes := ix.GetAnchorTransactions(document)
+		return len(hashes) == 1 && hashes[0] == anchorB.Hash()
+	}, time.Second, 10*time.Millisecond)
+	assert.Empty(t, ix.GetAnchorTransactions(types.Hash{2}))
+}
+
+func addBlock(t *testing.T, bc core.BlockChain, height uint32, prevHash types.Hash, txx ...*core.Transaction) *core.Block {
+	block := core.NewBlockWithHeaderInfo(height, prevHash)
+	for _, tx := range txx {
+		block.AddTransaction(tx)
+	}
+	assert.Nil(t, bc.AddBlock(block))
+	return block
+}
+
+func createTransaction(t *testing.T, from, to string, val float64, privKey *ecdsa.PrivateKey) *core.Transaction {
+	tx, err := currency.NewTransaction(from, to, val).ToCoreTransaction()
+	assert.Nil(t, err)
+	assert.Nil(t, tx.Sign(privKey))
+	return tx
+}
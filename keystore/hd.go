@@ -0,0 +1,159 @@
+package keystore
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// HardenedOffset is added to child indexes derived from the parent private
+// key; children below it are derived from the parent public key.
+const HardenedOffset uint32 = 1 << 31
+
+const (
+	MinSeedSize = 16
+	MaxSeedSize = 64
+)
+
+// masterKeySalt is the HMAC key deriving the master key from a seed; it is
+// the one SLIP-0010 uses for P-256, so derived keys match other wallets.
+var masterKeySalt = []byte("Nist256p1 seed")
+
+// ExtendedKey is a P-256 private key together with the chain code needed
+// to derive its children.
+type ExtendedKey struct {
+	key       *big.Int
+	chainCode []byte
+}
+
+// NewSeed returns a random seed of the recommended size.
+func NewSeed() ([]byte, error) {
+	seed := make([]byte, 32)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, err
+	}
+	return seed, nil
+}
+
+// NewMasterKey derives the root of the key tree of seed.
+func NewMasterKey(seed []byte) (*ExtendedKey, error) {
+	if len(seed) < MinSeedSize || len(seed) > MaxSeedSize {
+		return nil, fmt.Errorf("seed of (%d) bytes must have (%d) to (%d) bytes", len(seed), MinSeedSize, MaxSeedSize)
+	}
+
+	data := seed
+	for {
+		mac := hmacSha512(masterKeySalt, data)
+		key := new(big.Int).SetBytes(mac[:32])
+		if key.Sign() > 0 && key.Cmp(curveOrder()) < 0 {
+			return &ExtendedKey{key: key, chainCode: mac[32:]}, nil
+		}
+		data = mac
+	}
+}
+
+// Child derives the child at index; indexes from HardenedOffset up are
+// hardened.
+func (extKey *ExtendedKey) Child(index uint32) (*ExtendedKey, error) {
+	data := make([]byte, 0, 37)
+	if index >= HardenedOffset {
+		data = append(data, 0)
+		data = append(data, extKey.key.FillBytes(make([]byte, 32))...)
+	} else {
+		publicKey := extKey.PrivateKey().PublicKey
+		data = append(data, elliptic.MarshalCompressed(publicKey.Curve, publicKey.X, publicKey.Y)...)
+	}
+	data = binary.BigEndian.AppendUint32(data, index)
+
+	for {
+		mac := hmacSha512(extKey.chainCode, data)
+		tweak := new(big.Int).SetBytes(mac[:32])
+		if tweak.Cmp(curveOrder()) < 0 {
+			key := tweak.Add(tweak, extKey.key)
+			key.Mod(key, curveOrder())
+			if key.Sign() != 0 {
+				return &ExtendedKey{key: key, chainCode: mac[32:]}, nil
+			}
+		}
+
+		// Invalid keys are derived again from the right half
+		data = append([]byte{1}, mac[32:]...)
+		data = binary.BigEndian.AppendUint32(data, index)
+	}
+}
+
+// DerivePath derives the key at path, such as "m/44'/0'/1", from extKey,
+// which is taken to be the master key.
+func (extKey *ExtendedKey) DerivePath(path string) (*ExtendedKey, error) {
+	indexes, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key := extKey
+	for _, index := range indexes {
+		if key, err = key.Child(index); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+func (extKey *ExtendedKey) PrivateKey() *ecdsa.PrivateKey {
+	curve := elliptic.P256()
+	scalar := extKey.key.FillBytes(make([]byte, 32))
+	x, y := curve.ScalarBaseMult(scalar)
+	return &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{Curve: curve, X: x, Y: y},
+		D:         new(big.Int).Set(extKey.key),
+	}
+}
+
+func (extKey *ExtendedKey) ChainCode() []byte {
+	chainCode := make([]byte, len(extKey.chainCode))
+	copy(chainCode, extKey.chainCode)
+	return chainCode
+}
+
+// ParsePath parses a derivation path of the form "m/0'/1/2h". Hardened
+// indexes are marked with ' or h.
+func ParsePath(path string) ([]uint32, error) {
+	parts := strings.Split(path, "/")
+	if parts[0] != "m" {
+		return nil, fmt.Errorf("derivation path (%s) must start with m", path)
+	}
+
+	indexes := make([]uint32, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		hardened := strings.HasSuffix(part, "'") || strings.HasSuffix(part, "h")
+		if hardened {
+			part = part[:len(part)-1]
+		}
+		index, err := strconv.ParseUint(part, 10, 31)
+		if err != nil {
+			return nil, fmt.Errorf("derivation path (%s) has invalid index (%s)", path, part)
+		}
+		if hardened {
+			index += uint64(HardenedOffset)
+		}
+		indexes = append(indexes, uint32(index))
+	}
+	return indexes, nil
+}
+
+func curveOrder() *big.Int {
+	return elliptic.P256().Params().N
+}
+
+func hmacSha512(key, data []byte) []byte {
+	mac := hmac.New(sha512.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,68 @@
+package keystore
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+)
+
+// Test vector 1 for nist256p1 from SLIP-0010.
+func TestDerivePath(t *testing.T) {
+	seed, err := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	assert.Nil(t, err)
+	master, err := NewMasterKey(seed)
+	assert.Nil(t, err)
+
+	vectors := []struct {
+		path, chainCode, privateKey string
+	}{
+		{
+			"m",
+			"beeb672fe4621673f722f38529c07392fecaa61015c80c34f29ce8b41b3cb6ea",
+			"612091aaa12e22dd2abef664f8a01a82cae99ad7441b7ef8110424915c268bc2",
+		},
+		{
+			"m/0'",
+			"3460cea53e6a6bb5fb391eeef3237ffd8724bf0a40e94943c98b83825342ee11",
+			"6939694369114c67917a182c59ddb8cafc3004e63ca5d3b84403ba8613debc0c",
+		},
+	}
+	for _, vector := range vectors {
+		key, err := master.DerivePath(vector.path)
+		assert.Nil(t, err)
+		assert.Equal(t, vector.chainCode, hex.EncodeToString(key.ChainCode()))
+		assert.Equal(t, vector.privateKey, hex.EncodeToString(key.PrivateKey().D.FillBytes(make([]byte, 32))))
+	}
+}
+
+func TestDerivedKeys(t *testing.T) {
+	seed, err := NewSeed()
+	assert.Nil(t, err)
+	master, err := NewMasterKey(seed)
+	assert.Nil(t, err)
+
+	// Derivation is deterministic and children differ
+	first, err := master.DerivePath("m/44'/0'/0")
+	assert.Nil(t, err)
+	again, err := master.DerivePath("m/44h/0h/0")
+	assert.Nil(t, err)
+	second, err := master.DerivePath("m/44'/0'/1")
+	assert.Nil(t, err)
+	assert.Equal(t, first.PrivateKey(), again.PrivateKey())
+	assert.NotEqual(t, first.PrivateKey().D, second.PrivateKey().D)
+
+	// Derived keys sign like generated ones
+	privKey := first.PrivateKey()
+	sig, err := crypto.SignBytes(privKey, []byte("data"))
+	assert.Nil(t, err)
+	assert.True(t, sig.Verify(&privKey.PublicKey, []byte("data")))
+
+	_, err = NewMasterKey(make([]byte, MinSeedSize-1))
+	assert.NotNil(t, err)
+	for _, path := range []string{"", "44'/0'", "m/x", "m/2147483648", "m//1"} {
+		_, err := master.DerivePath(path)
+		assert.NotNil(t, err, path)
+	}
+}
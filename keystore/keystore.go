@@ -0,0 +1,366 @@
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+)
+
+const keyFileVersion = 1
+
+const keyFileExtension = ".json"
+
+const (
+	KindKey  = "key"
+	KindSeed = "seed"
+)
+
+const (
+	kdfScrypt         = "scrypt"
+	cipherAesGcm      = "aes-256-gcm"
+	encryptionKeySize = 32
+	saltSize          = 32
+)
+
+// keyFile is the stored form of a secret: a P-256 private key or an HD
+// seed, encrypted with AES-256-GCM under a key derived from the passphrase
+// with scrypt. The public key of private keys is kept in the clear.
+type keyFile struct {
+	Version   int           `json:"version"`
+	Kind      string        `json:"kind"`
+	PublicKey string        `json:"publicKey,omitempty"`
+	Crypto    keyFileCrypto `json:"crypto"`
+}
+
+type keyFileCrypto struct {
+	KDF        string `json:"kdf"`
+	N          int    `json:"n"`
+	R          int    `json:"r"`
+	P          int    `json:"p"`
+	Salt       string `json:"salt"`
+	Cipher     string `json:"cipher"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// EncryptKey returns the key file of privKey encrypted with passphrase.
+func EncryptKey(privKey *ecdsa.PrivateKey, passphrase string, params ScryptParams) ([]byte, error) {
+	publicKey := hex.EncodeToString(crypto.PublicKeyBytes(&privKey.PublicKey))
+	return encryptSecret(KindKey, publicKey, privKey.D.FillBytes(make([]byte, 32)), passphrase, params)
+}
+
+// DecryptKey decrypts a key file and checks the key against the public key
+// stored beside it, which is not covered by the encryption.
+func DecryptKey(data []byte, passphrase string) (*ecdsa.PrivateKey, error) {
+	file, err := parseKeyFile(data)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := decryptSecret(data, KindKey, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	privKey, err := privateKeyFromBytes(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey := hex.EncodeToString(crypto.PublicKeyBytes(&privKey.PublicKey))
+	if publicKey != file.PublicKey {
+		return nil, fmt.Errorf("key file public key (%s) does not match its private key", file.PublicKey)
+	}
+	return privKey, nil
+}
+
+// EncryptSeed returns the key file of an HD seed encrypted with passphrase.
+func EncryptSeed(seed []byte, passphrase string, params ScryptParams) ([]byte, error) {
+	if len(seed) < MinSeedSize || len(seed) > MaxSeedSize {
+		return nil, fmt.Errorf("seed of (%d) bytes must have (%d) to (%d) bytes", len(seed), MinSeedSize, MaxSeedSize)
+	}
+	return encryptSecret(KindSeed, "", seed, passphrase, params)
+}
+
+func DecryptSeed(data []byte, passphrase string) ([]byte, error) {
+	return decryptSecret(data, KindSeed, passphrase)
+}
+
+func encryptSecret(kind string, publicKey string, secret []byte, passphrase string, params ScryptParams) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	aead, err := newCipher(passphrase, salt, params)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	// The kind is authenticated so that a seed cannot pass as a key
+	ciphertext := aead.Seal(nil, nonce, secret, []byte(kind))
+	return json.MarshalIndent(&keyFile{
+		Version:   keyFileVersion,
+		Kind:      kind,
+		PublicKey: publicKey,
+		Crypto: keyFileCrypto{
+			KDF:        kdfScrypt,
+			N:          params.N,
+			R:          params.R,
+			P:          params.P,
+			Salt:       hex.EncodeToString(salt),
+			Cipher:     cipherAesGcm,
+			Nonce:      hex.EncodeToString(nonce),
+			Ciphertext: hex.EncodeToString(ciphertext),
+		},
+	}, "", "  ")
+}
+
+func decryptSecret(data []byte, kind string, passphrase string) ([]byte, error) {
+	file, err := parseKeyFile(data)
+	if err != nil {
+		return nil, err
+	}
+	if file.Kind != kind {
+		return nil, fmt.Errorf("key file holds a (%s) instead of a (%s)", file.Kind, kind)
+	}
+
+	salt, err := hex.DecodeString(file.Crypto.Salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hex.DecodeString(file.Crypto.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := hex.DecodeString(file.Crypto.Ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	params := ScryptParams{N: file.Crypto.N, R: file.Crypto.R, P: file.Crypto.P}
+	aead, err := newCipher(passphrase, salt, params)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("key file nonce of (%d) bytes must have (%d) bytes", len(nonce), aead.NonceSize())
+	}
+	secret, err := aead.Open(nil, nonce, ciphertext, []byte(kind))
+	if err != nil {
+		return nil, fmt.Errorf("wrong passphrase or corrupted key file")
+	}
+	return secret, nil
+}
+
+func parseKeyFile(data []byte) (*keyFile, error) {
+	file := &keyFile{}
+	if err := json.Unmarshal(data, file); err != nil {
+		return nil, err
+	}
+	if file.Version != keyFileVersion {
+		return nil, fmt.Errorf("key file version (%d) is not supported", file.Version)
+	}
+	if file.Kind != KindKey && file.Kind != KindSeed {
+		return nil, fmt.Errorf("key file kind (%s) is not supported", file.Kind)
+	}
+	if file.Crypto.KDF != kdfScrypt || file.Crypto.Cipher != cipherAesGcm {
+		return nil, fmt.Errorf("key file encryption (%s, %s) is not supported", file.Crypto.KDF, file.Crypto.Cipher)
+	}
+	return file, nil
+}
+
+func newCipher(passphrase string, salt []byte, params ScryptParams) (cipher.AEAD, error) {
+	key, err := deriveKey([]byte(passphrase), salt, params, encryptionKeySize)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func privateKeyFromBytes(data []byte) (*ecdsa.PrivateKey, error) {
+	curve := elliptic.P256()
+	d := new(big.Int).SetBytes(data)
+	if len(data) != 32 || d.Sign() == 0 || d.Cmp(curve.Params().N) >= 0 {
+		return nil, fmt.Errorf("invalid P-256 private key")
+	}
+	return (&ExtendedKey{key: d}).PrivateKey(), nil
+}
+
+// Keystore keeps encrypted keys and seeds as files named after them in a
+// directory.
+type Keystore struct {
+	dir    string
+	Params ScryptParams
+}
+
+// NewKeystore opens the keystore in dir, creating the directory if needed.
+func NewKeystore(dir string) (*Keystore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &Keystore{dir: dir, Params: StandardScryptParams}, nil
+}
+
+func (ks *Keystore) StoreKey(name string, privKey *ecdsa.PrivateKey, passphrase string) error {
+	data, err := EncryptKey(privKey, passphrase, ks.Params)
+	if err != nil {
+		return err
+	}
+	return ks.write(name, data)
+}
+
+func (ks *Keystore) LoadKey(name string, passphrase string) (*ecdsa.PrivateKey, error) {
+	data, err := ks.Export(name)
+	if err != nil {
+		return nil, err
+	}
+	return DecryptKey(data, passphrase)
+}
+
+// LoadOrCreateKey loads the key stored under name, generating and storing
+// a new one on first use.
+func (ks *Keystore) LoadOrCreateKey(name string, passphrase string) (*ecdsa.PrivateKey, error) {
+	if ks.Has(name) {
+		return ks.LoadKey(name, passphrase)
+	}
+	privKey := crypto.GeneratePrivateKey()
+	if err := ks.StoreKey(name, privKey, passphrase); err != nil {
+		return nil, err
+	}
+	return privKey, nil
+}
+
+func (ks *Keystore) StoreSeed(name string, seed []byte, passphrase string) error {
+	data, err := EncryptSeed(seed, passphrase, ks.Params)
+	if err != nil {
+		return err
+	}
+	return ks.write(name, data)
+}
+
+func (ks *Keystore) LoadSeed(name string, passphrase string) ([]byte, error) {
+	data, err := ks.Export(name)
+	if err != nil {
+		return nil, err
+	}
+	return DecryptSeed(data, passphrase)
+}
+
+// DeriveKey derives the wallet key at path from the seed stored under
+// seedName.
+func (ks *Keystore) DeriveKey(seedName string, path string, passphrase string) (*ecdsa.PrivateKey, error) {
+	seed, err := ks.LoadSeed(seedName, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	master, err := NewMasterKey(seed)
+	if err != nil {
+		return nil, err
+	}
+	key, err := master.DerivePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return key.PrivateKey(), nil
+}
+
+// Export returns the encrypted key file stored under name.
+func (ks *Keystore) Export(name string) ([]byte, error) {
+	path, err := ks.path(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("keystore has no entry (%s)", name)
+	}
+	return data, err
+}
+
+// Import stores an encrypted key file exported from another keystore
+// under name.
+func (ks *Keystore) Import(name string, data []byte) error {
+	if _, err := parseKeyFile(data); err != nil {
+		return err
+	}
+	return ks.write(name, data)
+}
+
+func (ks *Keystore) Has(name string) bool {
+	path, err := ks.path(name)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+// Names returns the names of the stored entries in order.
+func (ks *Keystore) Names() ([]string, error) {
+	entries, err := os.ReadDir(ks.dir)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if name, ok := strings.CutSuffix(entry.Name(), keyFileExtension); ok && !entry.IsDir() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// write stores data under name; existing entries are never overwritten.
+func (ks *Keystore) write(name string, data []byte) error {
+	path, err := ks.path(name)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("keystore already has entry (%s)", name)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// path is the file of the entry name; names may only use letters, digits,
+// '-', '_' and '.' so that they stay inside the keystore directory.
+func (ks *Keystore) path(name string) (string, error) {
+	if name == "" || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("invalid keystore entry name (%s)", name)
+	}
+	for _, c := range name {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return "", fmt.Errorf("invalid keystore entry name (%s)", name)
+		}
+	}
+	return filepath.Join(ks.dir, name+keyFileExtension), nil
+}
@@ -0,0 +1,106 @@
+package keystore
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+)
+
+func TestEncryptKey(t *testing.T) {
+	privKey := crypto.GeneratePrivateKey()
+	data, err := EncryptKey(privKey, "secret", LightScryptParams)
+	assert.Nil(t, err)
+
+	decrypted, err := DecryptKey(data, "secret")
+	assert.Nil(t, err)
+	assert.Equal(t, privKey.D, decrypted.D)
+	assert.True(t, privKey.PublicKey.Equal(&decrypted.PublicKey))
+
+	_, err = DecryptKey(data, "wrong")
+	assert.NotNil(t, err)
+	_, err = DecryptSeed(data, "secret")
+	assert.NotNil(t, err)
+
+	// Tampering with the file fails authentication
+	file := &keyFile{}
+	assert.Nil(t, json.Unmarshal(data, file))
+	file.Kind = KindSeed
+	tampered, err := json.Marshal(file)
+	assert.Nil(t, err)
+	_, err = DecryptSeed(tampered, "secret")
+	assert.NotNil(t, err)
+
+	// The public key in the clear must belong to the encrypted key
+	file.Kind = KindKey
+	file.PublicKey = hex.EncodeToString(crypto.PublicKeyBytes(&crypto.GeneratePrivateKey().PublicKey))
+	tampered, err = json.Marshal(file)
+	assert.Nil(t, err)
+	_, err = DecryptKey(tampered, "secret")
+	assert.NotNil(t, err)
+
+	// Key files cannot demand unbounded work
+	for _, params := range []ScryptParams{
+		{N: 2 * maxScryptN, R: 8, P: 1},
+		{N: maxScryptN, R: 1 << 20, P: 1},
+		{N: maxScryptN, R: 8, P: 1 << 20},
+	} {
+		file.Crypto.N, file.Crypto.R, file.Crypto.P = params.N, params.R, params.P
+		tampered, err = json.Marshal(file)
+		assert.Nil(t, err)
+		_, err = DecryptKey(tampered, "secret")
+		assert.NotNil(t, err)
+	}
+}
+
+func TestKeystore(t *testing.T) {
+	ks, err := NewKeystore(t.TempDir())
+	assert.Nil(t, err)
+	ks.Params = LightScryptParams
+
+	// The node key survives a restart
+	nodeKey, err := ks.LoadOrCreateKey("node", "secret")
+	assert.Nil(t, err)
+	reloaded, err := ks.LoadOrCreateKey("node", "secret")
+	assert.Nil(t, err)
+	assert.Equal(t, nodeKey.D, reloaded.D)
+	_, err = ks.LoadOrCreateKey("node", "wrong")
+	assert.NotNil(t, err)
+
+	assert.NotNil(t, ks.StoreKey("node", crypto.GeneratePrivateKey(), "secret"))
+	assert.NotNil(t, ks.StoreKey("../node", crypto.GeneratePrivateKey(), "secret"))
+	_, err = ks.LoadKey("missing", "secret")
+	assert.NotNil(t, err)
+
+	// Wallet keys are derived from a stored seed
+	seed, err := NewSeed()
+	assert.Nil(t, err)
+	assert.Nil(t, ks.StoreSeed("wallets", seed, "secret"))
+	walletKey, err := ks.DeriveKey("wallets", "m/44'/0'/0", "secret")
+	assert.Nil(t, err)
+	master, err := NewMasterKey(seed)
+	assert.Nil(t, err)
+	expected, err := master.DerivePath("m/44'/0'/0")
+	assert.Nil(t, err)
+	assert.Equal(t, expected.PrivateKey().D, walletKey.D)
+	_, err = ks.DeriveKey("node", "m/0", "secret")
+	assert.NotNil(t, err)
+
+	names, err := ks.Names()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"node", "wallets"}, names)
+
+	// Exported files can be imported into another keystore
+	exported, err := ks.Export("node")
+	assert.Nil(t, err)
+	other, err := NewKeystore(t.TempDir())
+	assert.Nil(t, err)
+	assert.Nil(t, other.Import("imported", exported))
+	imported, err := other.LoadKey("imported", "secret")
+	assert.Nil(t, err)
+	assert.Equal(t, nodeKey.D, imported.D)
+	assert.NotNil(t, other.Import("invalid", []byte("{}")))
+	assert.False(t, other.Has("invalid"))
+}
@@ -0,0 +1,53 @@
+package keystore
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Bounds on the cost parameters read from key files. Derivation needs
+// 128*N*R bytes of memory and repeats its work P times, so a crafted file
+// can make decryption use at most 256 MiB for sixteen passes.
+const (
+	maxScryptN = 1 << 18
+	maxScryptR = 8
+	maxScryptP = 16
+)
+
+// ScryptParams are the cost parameters of the scrypt key derivation: N is
+// the CPU and memory cost, R the block size and P the parallelization.
+type ScryptParams struct {
+	N int
+	R int
+	P int
+}
+
+var (
+	// StandardScryptParams takes about 32 MB and a fraction of a second.
+	StandardScryptParams = ScryptParams{N: 1 << 15, R: 8, P: 1}
+	// LightScryptParams is cheaper for tests and low powered devices.
+	LightScryptParams = ScryptParams{N: 1 << 12, R: 8, P: 1}
+)
+
+func (params ScryptParams) validate() error {
+	if params.N <= 1 || params.N&(params.N-1) != 0 || params.N > maxScryptN {
+		return fmt.Errorf("scrypt N (%d) must be a power of two between 2 and (%d)", params.N, maxScryptN)
+	}
+	if params.R <= 0 || params.R > maxScryptR {
+		return fmt.Errorf("scrypt r (%d) must be between 1 and (%d)", params.R, maxScryptR)
+	}
+	if params.P <= 0 || params.P > maxScryptP {
+		return fmt.Errorf("scrypt p (%d) must be between 1 and (%d)", params.P, maxScryptP)
+	}
+	return nil
+}
+
+// deriveKey derives a key of keyLen bytes from password and salt with
+// scrypt once params are within bounds.
+func deriveKey(password, salt []byte, params ScryptParams, keyLen int) ([]byte, error) {
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+	return scrypt.Key(password, salt, params.N, params.R, params.P, keyLen)
+}
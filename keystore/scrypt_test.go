@@ -0,0 +1,50 @@
+package keystore
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test vectors from RFC 7914.
+
+func TestScrypt(t *testing.T) {
+	vectors := []struct {
+		password, salt string
+		params         ScryptParams
+		expected       string
+	}{
+		{
+			"", "",
+			ScryptParams{N: 16, R: 1, P: 1},
+			"77d6576238657b203b19ca42c18a0497f16b4844e3074ae8dfdffa3fede21442" +
+				"fcd0069ded0948f8326a753a0fc81f17e8d3e0fb2e0d3628cf35e20c38d18906",
+		},
+		{
+			"password", "NaCl",
+			ScryptParams{N: 1024, R: 8, P: 16},
+			"fdbabe1c9d3472007856e7190d01e9fe7c6ad7cbc8237830e77376634b373162" +
+				"2eaf30d92e22a3886ff109279d9830dac727afb94a83ee6d8360cbdfa2cc0640",
+		},
+	}
+	for _, vector := range vectors {
+		key, err := deriveKey([]byte(vector.password), []byte(vector.salt), vector.params, 64)
+		assert.Nil(t, err)
+		assert.Equal(t, vector.expected, hex.EncodeToString(key))
+	}
+
+	for _, params := range []ScryptParams{
+		{N: 0, R: 8, P: 1},
+		{N: 1000, R: 8, P: 1},
+		{N: 2 * maxScryptN, R: 8, P: 1},
+		{N: 16, R: 0, P: 1},
+		{N: 16, R: 8, P: 0},
+		{N: 16, R: maxScryptR + 1, P: 1},
+		{N: 16, R: 8, P: maxScryptP + 1},
+		{N: maxScryptN, R: 1 << 20, P: 1},
+	} {
+		_, err := deriveKey([]byte("password"), nil, params, 32)
+		assert.NotNil(t, err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/gob"
 	"fmt"
 	"io"
+	"sort"
 	"sync"
 
 	"github.com/tusharjoshi4531/block-chain.git/util"
@@ -46,6 +47,7 @@ type Transport interface {
 	ReadChan() <-chan Message
 	WriteChan() chan<- Message
 	Connect(TransportInterface) error
+	Peers() []string
 }
 
 type DefaultTransport struct {
@@ -80,6 +82,18 @@ func (t *DefaultTransport) Connect(otherTransport TransportInterface) error {
 	return nil
 }
 
+func (t *DefaultTransport) Peers() []string {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	peers := make([]string, 0, len(t.peers))
+	for address := range t.peers {
+		peers = append(peers, address)
+	}
+	sort.Strings(peers)
+	return peers
+}
+
 func (t *DefaultTransport) SendMessageTo(to string, msg *Message) error {
 	t.lock.RLock()
 	peer, ok := t.peers[to]
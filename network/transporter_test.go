@@ -16,6 +16,10 @@ func TestConnect(t *testing.T) {
 
 	assert.Equal(t, ta.peers["B"], tb)
 	assert.Equal(t, tb.peers["A"], ta)
+
+	tc := NewLocalTransport("C")
+	assert.Nil(t, ta.Connect(tc))
+	assert.Equal(t, []string{"B", "C"}, ta.Peers())
 }
 
 func TestSendMessageTo(t *testing.T) {
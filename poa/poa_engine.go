@@ -0,0 +1,160 @@
+package poa
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"sync"
+
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/prot"
+	"github.com/tusharjoshi4531/block-chain.git/types"
+)
+
+// PoaEngine is a proof-of-authority engine: authorities take turns signing
+// blocks in round-robin order by height, and the authority set changes
+// through vote transactions included in blocks.
+type PoaEngine struct {
+	blockChain      core.BlockChain
+	transactionPool core.TransactionPool
+	privateKey      *ecdsa.PrivateKey
+	rewarder        prot.Rewarder
+	genesis         *Snapshot
+	snapshots       map[types.Hash]*Snapshot
+	mu              sync.Mutex
+}
+
+func NewPoaEngine(
+	authorities []*ecdsa.PublicKey,
+	bc core.BlockChain,
+	txPool core.TransactionPool,
+	privKey *ecdsa.PrivateKey,
+	rewarder prot.Rewarder,
+) *PoaEngine {
+	return &PoaEngine{
+		blockChain:      bc,
+		transactionPool: txPool,
+		privateKey:      privKey,
+		rewarder:        rewarder,
+		genesis:         newSnapshot(authorities),
+		snapshots:       make(map[types.Hash]*Snapshot),
+	}
+}
+
+// SnapshotAt returns the authority set in effect after the block with the
+// given hash, replaying votes from the nearest known snapshot.
+func (engine *PoaEngine) SnapshotAt(blockHash types.Hash) (*Snapshot, error) {
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+
+	path := make([]*core.Block, 0)
+	hash := blockHash
+	var snapshot *Snapshot
+	for {
+		if cached, ok := engine.snapshots[hash]; ok {
+			snapshot = cached
+			break
+		}
+
+		block, err := engine.blockChain.GetBlockWithHash(hash)
+		if err != nil {
+			return nil, err
+		}
+		if block.Header.Height == 0 {
+			snapshot = engine.genesis
+			break
+		}
+
+		path = append(path, block)
+		hash = block.Header.PrevBlockHash
+	}
+
+	for i := len(path) - 1; i >= 0; i-- {
+		snapshot = snapshot.copy()
+		snapshot.apply(path[i])
+
+		hash, err := path[i].Hash()
+		if err != nil {
+			return nil, err
+		}
+		engine.snapshots[hash] = snapshot
+	}
+
+	return snapshot, nil
+}
+
+func (engine *PoaEngine) MineBlock(transactionsLimit uint32, minerWalletId string) (*core.Block, error) {
+	bc := engine.blockChain
+
+	prevBlock := bc.GetHeighestBlock()
+	prevHash, err := prevBlock.Hash()
+	if err != nil {
+		return nil, err
+	}
+	height := prevBlock.Header.Height + 1
+
+	snapshot, err := engine.SnapshotAt(prevHash)
+	if err != nil {
+		return nil, err
+	}
+	proposer, err := snapshot.Proposer(height)
+	if err != nil {
+		return nil, err
+	}
+	if proposer != keyId(&engine.privateKey.PublicKey) {
+		return nil, fmt.Errorf("node is not the proposer for height (%d)", height)
+	}
+
+	block := core.NewBlockWithHeaderInfo(height, prevHash)
+	block.Header.Timestamp = core.NextBlockTimestamp(prevBlock)
+
+	numTx := uint32(0)
+	for _, transaction := range engine.transactionPool.Transactions() {
+		if numTx == transactionsLimit {
+			break
+		}
+
+		if bc.HasTransactionInChain(transaction.Hash(), prevHash) == nil {
+			continue
+		}
+		if core.CheckTransactionLock(transaction, block.Header.Height, block.Header.Timestamp) != nil {
+			continue
+		}
+
+		numTx++
+		block.AddTransaction(transaction)
+	}
+	core.FilterTransactions(bc, block)
+
+	reward, err := engine.rewarder.GenerateReward(minerWalletId, block)
+	if err != nil {
+		return nil, err
+	}
+	block.PrependTransaction(reward)
+
+	if err := block.Sign(engine.privateKey); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// CheckProducer requires the block to be signed by the authority scheduled
+// for its height.
+func (engine *PoaEngine) CheckProducer(block *core.Block) error {
+	snapshot, err := engine.SnapshotAt(block.Header.PrevBlockHash)
+	if err != nil {
+		return err
+	}
+
+	proposer, err := snapshot.Proposer(block.Header.Height)
+	if err != nil {
+		return err
+	}
+	if producer := keyId(block.Validator); producer != proposer {
+		return fmt.Errorf("block at height (%d) signed by (%s); expected proposer (%s)", block.Header.Height, producer, proposer)
+	}
+	return nil
+}
+
+func (engine *PoaEngine) ValidateBlock(block *core.Block) error {
+	return prot.VerifyProducer(block, engine)
+}
@@ -0,0 +1,162 @@
+package poa
+
+import (
+	"crypto/ecdsa"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+	"github.com/tusharjoshi4531/block-chain.git/prot"
+)
+
+func TestRoundRobinProposer(t *testing.T) {
+	bc, txPool, privKeys, engines := createAuthorityNetwork(t, 3)
+
+	for height := uint32(1); height <= 6; height++ {
+		proposer := int(height % 3)
+		for i, engine := range engines {
+			if i == proposer {
+				continue
+			}
+			_, err := engine.MineBlock(10, "")
+			assert.NotNil(t, err)
+		}
+
+		block, err := engines[proposer].MineBlock(10, "")
+		assert.Nil(t, err)
+		for _, engine := range engines {
+			assert.Nil(t, engine.ValidateBlock(block))
+		}
+
+		// Same block signed out of turn
+		forged := core.NewBlockWithHeaderInfo(block.Header.Height, block.Header.PrevBlockHash)
+		forged.Transactions = block.Transactions
+		assert.Nil(t, forged.Sign(privKeys[(proposer+1)%3]))
+		assert.NotNil(t, engines[proposer].ValidateBlock(forged))
+
+		// Keys outside the authority set
+		assert.Nil(t, forged.Sign(crypto.GeneratePrivateKey()))
+		assert.NotNil(t, engines[proposer].ValidateBlock(forged))
+
+		assert.Nil(t, bc.AddBlock(block))
+	}
+	assert.Equal(t, 0, len(txPool.Transactions()))
+}
+
+func TestAuthorityVoting(t *testing.T) {
+	bc, txPool, privKeys, engines := createAuthorityNetwork(t, 3)
+	newKey := crypto.GeneratePrivateKey()
+
+	// A single vote is not a majority
+	vote, err := NewVoteTransaction(VoteAdd, &newKey.PublicKey, privKeys[0])
+	assert.Nil(t, err)
+	assert.Nil(t, txPool.AddTransaction(vote))
+	mineNext(t, bc, engines)
+	assert.Equal(t, 3, len(tipSnapshot(t, bc, engines[0]).Authorities()))
+
+	// Votes from outside the authority set are ignored
+	vote, err = NewVoteTransaction(VoteAdd, &newKey.PublicKey, newKey)
+	assert.Nil(t, err)
+	assert.Nil(t, txPool.AddTransaction(vote))
+	mineNext(t, bc, engines)
+	assert.Equal(t, 3, len(tipSnapshot(t, bc, engines[0]).Authorities()))
+
+	vote, err = NewVoteTransaction(VoteAdd, &newKey.PublicKey, privKeys[1])
+	assert.Nil(t, err)
+	assert.Nil(t, txPool.AddTransaction(vote))
+	mineNext(t, bc, engines)
+
+	snapshot := tipSnapshot(t, bc, engines[0])
+	assert.Equal(t, 4, len(snapshot.Authorities()))
+	assert.True(t, snapshot.IsAuthority(keyId(&newKey.PublicKey)))
+
+	// The new authority takes its turn in the rotation
+	newEngine := NewPoaEngine(publicKeys(privKeys), bc, txPool, newKey, prot.NewSimpleRewarder(newKey))
+	engines = append(engines, newEngine)
+	for bc.Height()%4 != 2 {
+		mineNext(t, bc, engines)
+	}
+	block, err := newEngine.MineBlock(10, "")
+	assert.Nil(t, err)
+	assert.Nil(t, engines[0].ValidateBlock(block))
+	assert.Nil(t, bc.AddBlock(block))
+
+	// Removal needs three of the four authorities
+	for i := 0; i < 3; i++ {
+		vote, err = NewVoteTransaction(VoteRemove, &privKeys[2].PublicKey, privKeys[i])
+		assert.Nil(t, err)
+		assert.Nil(t, txPool.AddTransaction(vote))
+	}
+	mineNext(t, bc, engines)
+
+	snapshot = tipSnapshot(t, bc, engines[0])
+	assert.Equal(t, 3, len(snapshot.Authorities()))
+	assert.False(t, snapshot.IsAuthority(keyId(&privKeys[2].PublicKey)))
+}
+
+func TestVoteEncoding(t *testing.T) {
+	privKey := crypto.GeneratePrivateKey()
+	candidate := crypto.GeneratePrivateKey()
+
+	tx, err := NewVoteTransaction(VoteRemove, &candidate.PublicKey, privKey)
+	assert.Nil(t, err)
+	assert.True(t, IsVoteTransaction(tx))
+
+	vote, err := DecodeVote(tx)
+	assert.Nil(t, err)
+	assert.Equal(t, VoteRemove, vote.Action)
+	assert.Equal(t, &candidate.PublicKey, vote.Candidate)
+	assert.Equal(t, &privKey.PublicKey, vote.Voter)
+
+	_, err = NewVoteTransaction(VoteAction(7), &candidate.PublicKey, privKey)
+	assert.NotNil(t, err)
+
+	_, err = DecodeVote(core.NewTransaction([]byte("FOO")))
+	assert.NotNil(t, err)
+}
+
+func createAuthorityNetwork(t *testing.T, numAuthorities int) (*core.DefaultBlockChain, *core.DefaultTransactionPool, []*ecdsa.PrivateKey, []*PoaEngine) {
+	bc := core.NewDefaultBlockChain()
+	txPool := core.NewDefaultTransactionPool()
+
+	privKeys := make([]*ecdsa.PrivateKey, numAuthorities)
+	for i := range privKeys {
+		privKeys[i] = crypto.GeneratePrivateKey()
+	}
+
+	engines := make([]*PoaEngine, numAuthorities)
+	for i := range engines {
+		engines[i] = NewPoaEngine(publicKeys(privKeys), bc, txPool, privKeys[i], prot.NewSimpleRewarder(privKeys[i]))
+	}
+	return bc, txPool, privKeys, engines
+}
+
+func publicKeys(privKeys []*ecdsa.PrivateKey) []*ecdsa.PublicKey {
+	keys := make([]*ecdsa.PublicKey, len(privKeys))
+	for i, privKey := range privKeys {
+		keys[i] = &privKey.PublicKey
+	}
+	return keys
+}
+
+// mineNext adds a block from whichever engine is the scheduled proposer.
+func mineNext(t *testing.T, bc core.BlockChain, engines []*PoaEngine) {
+	for _, engine := range engines {
+		block, err := engine.MineBlock(10, "")
+		if err != nil {
+			continue
+		}
+		assert.Nil(t, bc.AddBlock(block))
+		return
+	}
+	t.Fatalf("no engine could propose block at height (%d)", bc.Height()+1)
+}
+
+func tipSnapshot(t *testing.T, bc core.BlockChain, engine *PoaEngine) *Snapshot {
+	tipHash, err := bc.GetHeighestBlock().Hash()
+	assert.Nil(t, err)
+	snapshot, err := engine.SnapshotAt(tipHash)
+	assert.Nil(t, err)
+	return snapshot
+}
@@ -0,0 +1,153 @@
+package poa
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+)
+
+type tallyKey struct {
+	action    VoteAction
+	candidate string
+}
+
+// Snapshot is the authority set and pending vote tallies after a block.
+// Authorities are identified by the hex of their uncompressed public key and
+// keep the order in which they were added, which fixes the proposer
+// rotation.
+type Snapshot struct {
+	authorities []string
+	tallies     map[tallyKey]map[string]struct{}
+}
+
+func newSnapshot(authorities []*ecdsa.PublicKey) *Snapshot {
+	snapshot := &Snapshot{
+		authorities: make([]string, 0, len(authorities)),
+		tallies:     make(map[tallyKey]map[string]struct{}),
+	}
+	for _, authority := range authorities {
+		id := keyId(authority)
+		if !snapshot.IsAuthority(id) {
+			snapshot.authorities = append(snapshot.authorities, id)
+		}
+	}
+	return snapshot
+}
+
+func (snapshot *Snapshot) copy() *Snapshot {
+	authorities := make([]string, len(snapshot.authorities))
+	copy(authorities, snapshot.authorities)
+
+	tallies := make(map[tallyKey]map[string]struct{}, len(snapshot.tallies))
+	for key, voters := range snapshot.tallies {
+		tallies[key] = make(map[string]struct{}, len(voters))
+		for voter := range voters {
+			tallies[key][voter] = struct{}{}
+		}
+	}
+
+	return &Snapshot{
+		authorities: authorities,
+		tallies:     tallies,
+	}
+}
+
+func (snapshot *Snapshot) Authorities() []string {
+	authorities := make([]string, len(snapshot.authorities))
+	copy(authorities, snapshot.authorities)
+	return authorities
+}
+
+func (snapshot *Snapshot) IsAuthority(id string) bool {
+	for _, authority := range snapshot.authorities {
+		if authority == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Proposer returns the authority whose turn it is to sign the block at the
+// given height.
+func (snapshot *Snapshot) Proposer(height uint32) (string, error) {
+	if len(snapshot.authorities) == 0 {
+		return "", fmt.Errorf("authority set is empty")
+	}
+	return snapshot.authorities[height%uint32(len(snapshot.authorities))], nil
+}
+
+// apply counts the votes cast in the block. A vote passes once more than
+// half of the current authorities have cast it; votes not signed by their
+// voter, votes from keys outside the authority set and votes that would not
+// change the set are ignored.
+func (snapshot *Snapshot) apply(block *core.Block) {
+	for _, tx := range block.Transactions {
+		vote, err := DecodeVote(tx)
+		if err != nil || vote.Candidate.X == nil {
+			continue
+		}
+
+		voter := keyId(tx.From)
+		candidate := keyId(vote.Candidate)
+		if voter != keyId(vote.Voter) || !snapshot.IsAuthority(voter) {
+			continue
+		}
+		if vote.Action == VoteAdd && snapshot.IsAuthority(candidate) {
+			continue
+		}
+		if vote.Action == VoteRemove && (!snapshot.IsAuthority(candidate) || len(snapshot.authorities) == 1) {
+			continue
+		}
+
+		key := tallyKey{action: vote.Action, candidate: candidate}
+		if _, ok := snapshot.tallies[key]; !ok {
+			snapshot.tallies[key] = make(map[string]struct{})
+		}
+		snapshot.tallies[key][voter] = struct{}{}
+
+		if snapshot.countVotes(key)*2 <= len(snapshot.authorities) {
+			continue
+		}
+
+		delete(snapshot.tallies, key)
+		if vote.Action == VoteAdd {
+			snapshot.authorities = append(snapshot.authorities, candidate)
+		} else {
+			snapshot.removeAuthority(candidate)
+		}
+	}
+}
+
+// countVotes counts only voters that are still authorities.
+func (snapshot *Snapshot) countVotes(key tallyKey) int {
+	count := 0
+	for voter := range snapshot.tallies[key] {
+		if snapshot.IsAuthority(voter) {
+			count++
+		}
+	}
+	return count
+}
+
+func (snapshot *Snapshot) removeAuthority(id string) {
+	for i, authority := range snapshot.authorities {
+		if authority == id {
+			snapshot.authorities = append(snapshot.authorities[:i], snapshot.authorities[i+1:]...)
+			break
+		}
+	}
+
+	// Tallies for or against the removed authority no longer apply
+	for key := range snapshot.tallies {
+		if key.candidate == id {
+			delete(snapshot.tallies, key)
+		}
+	}
+}
+
+func keyId(key *ecdsa.PublicKey) string {
+	return hex.EncodeToString(crypto.PublicKeyBytes(key))
+}
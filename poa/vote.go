@@ -0,0 +1,128 @@
+package poa
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"fmt"
+
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+	"github.com/tusharjoshi4531/block-chain.git/util"
+)
+
+type VoteAction uint8
+
+const (
+	VoteAdd VoteAction = iota + 1
+	VoteRemove
+)
+
+const voteEncodingVersion uint8 = 1
+
+func init() {
+	core.RegisterTxType(core.TxTypeVote, core.TxTypeHandler{
+		Name: "Vote",
+		Decode: func(payload []byte) (any, error) {
+			return decodeVotePayload(payload)
+		},
+		Validate: func(tx *core.Transaction, decoded any) error {
+			vote := decoded.(*Vote)
+			if !vote.Voter.Equal(tx.From) {
+				return fmt.Errorf("vote is not signed by its voter")
+			}
+			return nil
+		},
+	})
+}
+
+// Vote names its voter so that identical votes from different authorities
+// have distinct transaction hashes.
+type Vote struct {
+	Action    VoteAction
+	Candidate *ecdsa.PublicKey
+	Voter     *ecdsa.PublicKey
+}
+
+func NewVote(action VoteAction, candidate *ecdsa.PublicKey, voter *ecdsa.PublicKey) *Vote {
+	return &Vote{
+		Action:    action,
+		Candidate: candidate,
+		Voter:     voter,
+	}
+}
+
+func (vote *Vote) Bytes() ([]byte, error) {
+	if vote.Action != VoteAdd && vote.Action != VoteRemove {
+		return nil, fmt.Errorf("invalid vote action (%d)", vote.Action)
+	}
+
+	buf := &bytes.Buffer{}
+	cw := util.NewCanonicalWriter(buf)
+	cw.WriteUint8(voteEncodingVersion)
+	cw.WriteUint8(uint8(vote.Action))
+	cw.WriteBytes(crypto.PublicKeyBytes(vote.Candidate))
+	cw.WriteBytes(crypto.PublicKeyBytes(vote.Voter))
+	if err := cw.Err(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// NewVoteTransaction builds a vote transaction signed by the voting
+// authority.
+func NewVoteTransaction(action VoteAction, candidate *ecdsa.PublicKey, privKey *ecdsa.PrivateKey) (*core.Transaction, error) {
+	data, err := NewVote(action, candidate, &privKey.PublicKey).Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := core.NewTypedTransaction(core.TxTypeVote, data)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Sign(privKey); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+func DecodeVote(tx *core.Transaction) (*Vote, error) {
+	txType, decoded, err := core.DecodeTransaction(tx)
+	if err != nil {
+		return nil, err
+	}
+	if txType != core.TxTypeVote {
+		return nil, fmt.Errorf("transaction is not a vote")
+	}
+	return decoded.(*Vote), nil
+}
+
+func decodeVotePayload(payload []byte) (*Vote, error) {
+	cr := util.NewCanonicalReader(bytes.NewReader(payload))
+	cr.ReadVersion(voteEncodingVersion)
+	action := VoteAction(cr.ReadUint8())
+	candidateBytes := cr.ReadBytes()
+	voterBytes := cr.ReadBytes()
+	cr.ReadEnd()
+	if err := cr.Err(); err != nil {
+		return nil, err
+	}
+	if action != VoteAdd && action != VoteRemove {
+		return nil, fmt.Errorf("invalid vote action (%d)", action)
+	}
+
+	candidate, err := crypto.PublicKeyFromBytes(candidateBytes)
+	if err != nil {
+		return nil, err
+	}
+	voter, err := crypto.PublicKeyFromBytes(voterBytes)
+	if err != nil {
+		return nil, err
+	}
+	return NewVote(action, candidate, voter), nil
+}
+
+func IsVoteTransaction(tx *core.Transaction) bool {
+	envelope, err := core.DecodeEnvelope(tx.Data)
+	return err == nil && envelope.Type == core.TxTypeVote
+}
@@ -0,0 +1,124 @@
+package pos
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"fmt"
+	"io"
+
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+	"github.com/tusharjoshi4531/block-chain.git/currency"
+	"github.com/tusharjoshi4531/block-chain.git/util"
+)
+
+// evidenceEncodingVersion is shared with the ledger, which reads the
+// headers of the evidence a slash carries.
+const evidenceEncodingVersion = currency.SlashProofVersion
+
+// DoubleSignEvidence proves that a validator signed two different blocks
+// at the same height.
+type DoubleSignEvidence struct {
+	HeaderA    core.BlockHeader
+	SignatureA *crypto.Signature
+	HeaderB    core.BlockHeader
+	SignatureB *crypto.Signature
+	Validator  *ecdsa.PublicKey
+}
+
+func NewDoubleSignEvidence(blockA, blockB *core.Block) (*DoubleSignEvidence, error) {
+	if !bytes.Equal(crypto.PublicKeyBytes(blockA.Validator), crypto.PublicKeyBytes(blockB.Validator)) {
+		return nil, fmt.Errorf("blocks are signed by different validators")
+	}
+
+	evidence := &DoubleSignEvidence{
+		HeaderA:    blockA.Header,
+		SignatureA: blockA.Signature,
+		HeaderB:    blockB.Header,
+		SignatureB: blockB.Signature,
+		Validator:  blockA.Validator,
+	}
+	if err := evidence.Verify(); err != nil {
+		return nil, err
+	}
+	return evidence, nil
+}
+
+func (evidence *DoubleSignEvidence) Verify() error {
+	if evidence.HeaderA.Height != evidence.HeaderB.Height {
+		return fmt.Errorf("headers are at different heights (%d) and (%d)", evidence.HeaderA.Height, evidence.HeaderB.Height)
+	}
+
+	hashA, err := evidence.HeaderA.Hash()
+	if err != nil {
+		return err
+	}
+	hashB, err := evidence.HeaderB.Hash()
+	if err != nil {
+		return err
+	}
+	if hashA == hashB {
+		return fmt.Errorf("headers are identical")
+	}
+
+	if !evidence.SignatureA.Verify(evidence.Validator, hashA[:]) || !evidence.SignatureB.Verify(evidence.Validator, hashB[:]) {
+		return fmt.Errorf("headers are not both signed by the validator")
+	}
+	return nil
+}
+
+func (evidence *DoubleSignEvidence) Encode(w io.Writer) error {
+	cw := util.NewCanonicalWriter(w)
+	cw.WriteUint8(evidenceEncodingVersion)
+	if err := cw.Err(); err != nil {
+		return err
+	}
+
+	if err := evidence.HeaderA.Encode(w); err != nil {
+		return err
+	}
+	if err := evidence.SignatureA.Encode(w); err != nil {
+		return err
+	}
+	if err := evidence.HeaderB.Encode(w); err != nil {
+		return err
+	}
+	if err := evidence.SignatureB.Encode(w); err != nil {
+		return err
+	}
+	return crypto.SerializePublicKey(evidence.Validator).Encode(w)
+}
+
+func (evidence *DoubleSignEvidence) Decode(r io.Reader) error {
+	cr := util.NewCanonicalReader(r)
+	cr.ReadVersion(evidenceEncodingVersion)
+	if err := cr.Err(); err != nil {
+		return err
+	}
+
+	evidence.SignatureA = crypto.NewNilSignature()
+	evidence.SignatureB = crypto.NewNilSignature()
+	if err := evidence.HeaderA.Decode(r); err != nil {
+		return err
+	}
+	if err := evidence.SignatureA.Decode(r); err != nil {
+		return err
+	}
+	if err := evidence.HeaderB.Decode(r); err != nil {
+		return err
+	}
+	if err := evidence.SignatureB.Decode(r); err != nil {
+		return err
+	}
+
+	validator := &crypto.SerializablePublicKey{}
+	if err := validator.Decode(r); err != nil {
+		return err
+	}
+	evidence.Validator = crypto.DecodePublicKey(validator)
+	return nil
+}
+
+func (evidence *DoubleSignEvidence) Bytes() ([]byte, error) {
+	return util.EncodeToBytes(evidence)
+}
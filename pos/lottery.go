@@ -0,0 +1,47 @@
+package pos
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/tusharjoshi4531/block-chain.git/types"
+)
+
+// SlotSeed derives the lottery seed of a slot from the hash of the block it
+// builds on.
+func SlotSeed(prevHash types.Hash, height uint32) types.Hash {
+	data := binary.BigEndian.AppendUint32(prevHash[:], height)
+	return sha256.Sum256(data)
+}
+
+// SelectProposer draws a validator with probability proportional to its
+// stake. Validators below minStake do not take part. The draw only depends
+// on its inputs, so every node agrees on the proposer.
+func SelectProposer(stakes map[string]float64, seed types.Hash, minStake float64) (string, error) {
+	validators := make([]string, 0, len(stakes))
+	total := 0.0
+	for validator, stake := range stakes {
+		if stake <= 0 || stake < minStake {
+			continue
+		}
+		validators = append(validators, validator)
+		total += stake
+	}
+	if len(validators) == 0 {
+		return "", fmt.Errorf("no validator holds the minimum stake (%f)", minStake)
+	}
+	sort.Strings(validators)
+
+	target := float64(binary.BigEndian.Uint64(seed[:8])) / math.Pow(2, 64) * total
+	cumulative := 0.0
+	for _, validator := range validators {
+		cumulative += stakes[validator]
+		if target < cumulative {
+			return validator, nil
+		}
+	}
+	return validators[len(validators)-1], nil
+}
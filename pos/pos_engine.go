@@ -0,0 +1,342 @@
+package pos
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+	"github.com/tusharjoshi4531/block-chain.git/currency"
+	"github.com/tusharjoshi4531/block-chain.git/prot"
+	"github.com/tusharjoshi4531/block-chain.git/types"
+)
+
+type signedSlot struct {
+	validator string
+	height    uint32
+}
+
+// PosEngine is a proof-of-stake engine. Validators lock stake in the ledger
+// and each slot's proposer is drawn by a stake-weighted lottery seeded from
+// the previous block hash. Stakes are read from the ledger, which follows
+// the current main chain, and adjusted to the block being proposed on.
+type PosEngine struct {
+	ledger          currency.LedgerState
+	blockChain      core.BlockChain
+	transactionPool core.TransactionPool
+	privateKey      *ecdsa.PrivateKey
+	rewarder        prot.Rewarder
+	minStake        float64
+	signed          map[signedSlot]*core.Block
+	// signedFloor is the lowest height still kept in signed.
+	signedFloor uint32
+	mu          sync.Mutex
+}
+
+func NewPosEngine(
+	ledger currency.LedgerState,
+	bc core.BlockChain,
+	txPool core.TransactionPool,
+	privKey *ecdsa.PrivateKey,
+	rewarder prot.Rewarder,
+	minStake float64,
+) *PosEngine {
+	return &PosEngine{
+		ledger:          ledger,
+		blockChain:      bc,
+		transactionPool: txPool,
+		privateKey:      privKey,
+		rewarder:        rewarder,
+		minStake:        minStake,
+		signed:          make(map[signedSlot]*core.Block),
+	}
+}
+
+// ValidatorId identifies a validator's stake in the ledger.
+func ValidatorId(key *ecdsa.PublicKey) string {
+	return hex.EncodeToString(crypto.PublicKeyBytes(key))
+}
+
+func (engine *PosEngine) Proposer(prevHash types.Hash, height uint32) (string, error) {
+	stakes, err := engine.StakesAt(prevHash)
+	if err != nil {
+		return "", err
+	}
+	return SelectProposer(stakes, SlotSeed(prevHash, height), engine.minStake)
+}
+
+// StakesAt returns the stakes in effect after the block with the given
+// hash. The ledger holds the stakes at the tip, so the stake changes of
+// the blocks from the tip back to the common ancestor are undone and those
+// from the ancestor up to the block are applied.
+func (engine *PosEngine) StakesAt(blockHash types.Hash) (map[string]float64, error) {
+	bc := engine.blockChain
+	block, err := bc.GetBlockWithHash(blockHash)
+	if err != nil {
+		return nil, err
+	}
+
+	stakes := engine.ledger.GetStakes()
+	tip := bc.GetHeighestBlock()
+	for tip != block {
+		if tip.Header.Height >= block.Header.Height {
+			applyStakeChanges(stakes, tip, -1)
+			if tip, err = bc.GetPrevBlock(tip); err != nil {
+				return nil, err
+			}
+		} else {
+			applyStakeChanges(stakes, block, 1)
+			if block, err = bc.GetPrevBlock(block); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for validator, stake := range stakes {
+		if stake <= 0 {
+			delete(stakes, validator)
+		}
+	}
+	return stakes, nil
+}
+
+// applyStakeChanges adds the stake moved by the block's transactions to
+// stakes, or takes it away when sign is -1.
+func applyStakeChanges(stakes map[string]float64, block *core.Block, sign float64) {
+	for _, tx := range block.Transactions {
+		transfer, err := currency.NewTransactionFromCoreTransaction(tx)
+		if err != nil {
+			continue
+		}
+		switch transfer.Type {
+		case core.TxTypeStake:
+			stakes[transfer.Validator] += sign * transfer.Amount
+		case core.TxTypeUnstake, core.TxTypeSlash:
+			stakes[transfer.Validator] -= sign * transfer.Amount
+		}
+	}
+}
+
+func (engine *PosEngine) MineBlock(transactionsLimit uint32, minerWalletId string) (*core.Block, error) {
+	bc := engine.blockChain
+
+	prevBlock := bc.GetHeighestBlock()
+	prevHash, err := prevBlock.Hash()
+	if err != nil {
+		return nil, err
+	}
+	height := prevBlock.Header.Height + 1
+
+	proposer, err := engine.Proposer(prevHash, height)
+	if err != nil {
+		return nil, err
+	}
+	if proposer != ValidatorId(&engine.privateKey.PublicKey) {
+		return nil, fmt.Errorf("node is not entitled to the slot at height (%d)", height)
+	}
+
+	block := core.NewBlockWithHeaderInfo(height, prevHash)
+	block.Header.Timestamp = core.NextBlockTimestamp(prevBlock)
+
+	numTx := uint32(0)
+	for _, transaction := range engine.transactionPool.Transactions() {
+		if numTx == transactionsLimit {
+			break
+		}
+
+		if bc.HasTransactionInChain(transaction.Hash(), prevHash) == nil {
+			continue
+		}
+		if core.CheckTransactionLock(transaction, block.Header.Height, block.Header.Timestamp) != nil {
+			continue
+		}
+
+		numTx++
+		block.AddTransaction(transaction)
+	}
+	core.FilterTransactions(bc, block)
+
+	reward, err := engine.rewarder.GenerateReward(minerWalletId, block)
+	if err != nil {
+		return nil, err
+	}
+	block.PrependTransaction(reward)
+
+	if err := block.Sign(engine.privateKey); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// CheckProducer requires the block to be signed by the winner of its slot,
+// drawn from the stakes at the block's parent.
+func (engine *PosEngine) CheckProducer(block *core.Block) error {
+	proposer, err := engine.Proposer(block.Header.PrevBlockHash, block.Header.Height)
+	if err != nil {
+		return err
+	}
+	if producer := ValidatorId(block.Validator); producer != proposer {
+		return fmt.Errorf("block at height (%d) signed by (%s); slot belongs to (%s)", block.Header.Height, producer, proposer)
+	}
+	return nil
+}
+
+// ValidateBlock checks the producer and any slashes in the block. A second
+// block signed by the same validator at the same height is rejected and a
+// slash for the double sign is submitted to the transaction pool.
+func (engine *PosEngine) ValidateBlock(block *core.Block) error {
+	if err := prot.VerifyProducer(block, engine); err != nil {
+		return err
+	}
+	if err := engine.validateSlashes(block); err != nil {
+		return err
+	}
+
+	evidence, err := engine.recordSignedBlock(block)
+	if err != nil {
+		return err
+	}
+	if evidence != nil {
+		if err := engine.ReportDoubleSign(evidence); err != nil {
+			return err
+		}
+		return fmt.Errorf("validator (%s) signed two blocks at height (%d)", ValidatorId(block.Validator), block.Header.Height)
+	}
+	return nil
+}
+
+func (engine *PosEngine) recordSignedBlock(block *core.Block) (*DoubleSignEvidence, error) {
+	blockHash, err := block.Hash()
+	if err != nil {
+		return nil, err
+	}
+
+	engine.mu.Lock()
+	defer engine.mu.Unlock()
+
+	engine.pruneSigned()
+	if block.Header.Height < engine.signedFloor {
+		return nil, nil
+	}
+
+	slot := signedSlot{validator: ValidatorId(block.Validator), height: block.Header.Height}
+	previous, ok := engine.signed[slot]
+	if !ok {
+		engine.signed[slot] = block
+		return nil, nil
+	}
+
+	previousHash, err := previous.Hash()
+	if err != nil {
+		return nil, err
+	}
+	if previousHash == blockHash {
+		return nil, nil
+	}
+	return NewDoubleSignEvidence(previous, block)
+}
+
+// pruneSigned forgets the blocks signed at heights the chain no longer
+// takes blocks at: the finalized height and below, and more than the
+// chain's max reorg depth under the tip. The caller holds engine.mu.
+func (engine *PosEngine) pruneSigned() {
+	bc := engine.blockChain
+	floor := bc.GetFinalizedBlock().Header.Height + 1
+	if chain, ok := bc.(interface{ MaxReorgDepth() uint32 }); ok {
+		if depth, height := chain.MaxReorgDepth(), bc.Height(); depth > 0 && height > depth {
+			floor = max(floor, height-depth)
+		}
+	}
+	if floor <= engine.signedFloor {
+		return
+	}
+
+	for slot := range engine.signed {
+		if slot.height < floor {
+			delete(engine.signed, slot)
+		}
+	}
+	engine.signedFloor = floor
+}
+
+// ReportDoubleSign submits a transaction burning the offender's whole stake,
+// carrying the evidence as its proof.
+func (engine *PosEngine) ReportDoubleSign(evidence *DoubleSignEvidence) error {
+	if err := evidence.Verify(); err != nil {
+		return err
+	}
+
+	validator := ValidatorId(evidence.Validator)
+	stake := engine.ledger.GetStake(validator)
+	if stake <= 0 {
+		return fmt.Errorf("validator (%s) has no stake to slash", validator)
+	}
+
+	proof, err := evidence.Bytes()
+	if err != nil {
+		return err
+	}
+	key, err := currency.SlashEvidenceKey(proof)
+	if err != nil {
+		return err
+	}
+	if engine.ledger.HasUsedSlashEvidence(key) {
+		return fmt.Errorf("double sign of validator (%s) is already slashed", validator)
+	}
+	tx, err := currency.NewSlashTransaction(validator, stake, proof).ToCoreTransaction()
+	if err != nil {
+		return err
+	}
+	if err := tx.Sign(engine.privateKey); err != nil {
+		return err
+	}
+	return engine.transactionPool.AddTransaction(tx)
+}
+
+// validateSlashes requires every slash to carry valid double sign evidence
+// against the slashed validator and to burn the validator's whole stake at
+// the block's parent. Evidence may back only one slash; the ledger refuses
+// evidence already used earlier in the chain.
+func (engine *PosEngine) validateSlashes(block *core.Block) error {
+	var stakes map[string]float64
+	used := make(map[string]bool)
+	for _, tx := range block.Transactions {
+		transfer, err := currency.NewTransactionFromCoreTransaction(tx)
+		if err != nil || transfer.Type != core.TxTypeSlash {
+			continue
+		}
+
+		evidence := &DoubleSignEvidence{}
+		if err := evidence.Decode(bytes.NewReader(transfer.Proof)); err != nil {
+			return fmt.Errorf("slash of validator (%s) has malformed evidence: %s", transfer.Validator, err)
+		}
+		if err := evidence.Verify(); err != nil {
+			return fmt.Errorf("slash of validator (%s) has invalid evidence: %s", transfer.Validator, err)
+		}
+		if ValidatorId(evidence.Validator) != transfer.Validator {
+			return fmt.Errorf("slash evidence does not implicate validator (%s)", transfer.Validator)
+		}
+
+		key, err := currency.SlashEvidenceKey(transfer.Proof)
+		if err != nil {
+			return err
+		}
+		if used[key] {
+			return fmt.Errorf("slash of validator (%s) reuses evidence (%s)", transfer.Validator, key)
+		}
+		used[key] = true
+
+		if stakes == nil {
+			if stakes, err = engine.StakesAt(block.Header.PrevBlockHash); err != nil {
+				return err
+			}
+		}
+		if stake := stakes[transfer.Validator]; stake <= 0 || transfer.Amount != stake {
+			return fmt.Errorf("slash of (%f) does not match stake (%f) of validator (%s)", transfer.Amount, stake, transfer.Validator)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,284 @@
+package pos
+
+import (
+	"crypto/ecdsa"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+	"github.com/tusharjoshi4531/block-chain.git/currency"
+	"github.com/tusharjoshi4531/block-chain.git/types"
+)
+
+func TestSelectProposer(t *testing.T) {
+	stakes := map[string]float64{"A": 300, "B": 100, "C": 5}
+
+	seed := SlotSeed(types.Hash{1}, 1)
+	proposer, err := SelectProposer(stakes, seed, 10)
+	assert.Nil(t, err)
+	for i := 0; i < 10; i++ {
+		again, err := SelectProposer(stakes, seed, 10)
+		assert.Nil(t, err)
+		assert.Equal(t, proposer, again)
+	}
+
+	counts := make(map[string]int)
+	for height := uint32(0); height < 4000; height++ {
+		proposer, err := SelectProposer(stakes, SlotSeed(types.Hash{1}, height), 10)
+		assert.Nil(t, err)
+		counts[proposer]++
+	}
+	assert.Equal(t, 0, counts["C"])
+	assert.InDelta(t, 3000, counts["A"], 150)
+	assert.InDelta(t, 1000, counts["B"], 150)
+
+	_, err = SelectProposer(map[string]float64{"C": 5}, seed, 10)
+	assert.NotNil(t, err)
+}
+
+func TestStakeLedger(t *testing.T) {
+	ledger := currency.NewMemoryLedgerState()
+	assert.Nil(t, ledger.AddWallet("A", 100))
+	assert.Nil(t, ledger.AddWallet("B", 100))
+
+	lock := currency.NewStakeTransaction("A", "V", 60)
+	assert.Nil(t, ledger.CommitTransaciton(lock))
+	assert.Equal(t, 60.0, ledger.GetStake("V"))
+	balance, err := ledger.GetBalance("A")
+	assert.Nil(t, err)
+	assert.Equal(t, 40.0, balance)
+
+	// Stake cannot exceed balance or be taken over by another wallet
+	assert.NotNil(t, ledger.CommitTransaciton(currency.NewStakeTransaction("A", "W", 50)))
+	assert.NotNil(t, ledger.CommitTransaciton(currency.NewStakeTransaction("B", "V", 10)))
+	assert.NotNil(t, ledger.CommitTransaciton(currency.NewUnstakeTransaction("B", "V", 10)))
+
+	unlock := currency.NewUnstakeTransaction("A", "V", 20)
+	assert.Nil(t, ledger.CommitTransaciton(unlock))
+	assert.Equal(t, 40.0, ledger.GetStake("V"))
+
+	privKey := crypto.GeneratePrivateKey()
+	blockA := core.NewBlockWithHeaderInfo(1, types.Hash{1})
+	blockB := core.NewBlockWithHeaderInfo(1, types.Hash{2})
+	assert.Nil(t, blockA.Sign(privKey))
+	assert.Nil(t, blockB.Sign(privKey))
+	evidence, err := NewDoubleSignEvidence(blockA, blockB)
+	assert.Nil(t, err)
+	proof, err := evidence.Bytes()
+	assert.Nil(t, err)
+	slash := currency.NewSlashTransaction("V", 40, proof)
+	assert.Nil(t, ledger.CommitTransaciton(slash))
+	assert.Equal(t, 0.0, ledger.GetStake("V"))
+	assert.Equal(t, 0, len(ledger.GetStakes()))
+
+	assert.Nil(t, ledger.RevertTransaction(slash))
+	assert.Nil(t, ledger.RevertTransaction(unlock))
+	assert.Nil(t, ledger.RevertTransaction(lock))
+	assert.Equal(t, 0.0, ledger.GetStake("V"))
+	balance, err = ledger.GetBalance("A")
+	assert.Nil(t, err)
+	assert.Equal(t, 100.0, balance)
+}
+
+func TestStakeWeightedMining(t *testing.T) {
+	bc, _, _, engines := createStakedNetwork(t, []float64{300, 100})
+
+	for i := 0; i < 10; i++ {
+		prevHash, err := bc.GetHeighestBlock().Hash()
+		assert.Nil(t, err)
+		proposer, err := engines[0].Proposer(prevHash, bc.Height()+1)
+		assert.Nil(t, err)
+
+		var block *core.Block
+		for _, engine := range engines {
+			mined, err := engine.MineBlock(10, "A")
+			if ValidatorId(&engine.privateKey.PublicKey) != proposer {
+				assert.NotNil(t, err)
+				continue
+			}
+			assert.Nil(t, err)
+			block = mined
+		}
+
+		for _, engine := range engines {
+			assert.Nil(t, engine.ValidateBlock(block))
+		}
+
+		// Signed by a validator not entitled to the slot
+		for _, engine := range engines {
+			if ValidatorId(&engine.privateKey.PublicKey) == proposer {
+				continue
+			}
+			forged := core.NewBlockWithHeaderInfo(block.Header.Height, block.Header.PrevBlockHash)
+			forged.Transactions = block.Transactions
+			assert.Nil(t, forged.Sign(engine.privateKey))
+			assert.NotNil(t, engines[0].ValidateBlock(forged))
+		}
+
+		assert.Nil(t, bc.AddBlock(block))
+	}
+}
+
+func TestDoubleSignSlashing(t *testing.T) {
+	bc, ledger, txPool, engines := createStakedNetwork(t, []float64{300, 100})
+	observer := engines[0]
+
+	block := mineNext(t, bc, engines)
+	assert.Nil(t, observer.ValidateBlock(block))
+
+	// The same proposer signs a second block for the slot
+	offender := engineForKey(engines, block.Validator)
+	conflicting := core.NewBlockWithHeaderInfo(block.Header.Height, block.Header.PrevBlockHash)
+	conflicting.Transactions = block.Transactions
+	assert.Nil(t, conflicting.Sign(offender.privateKey))
+	assert.NotNil(t, observer.ValidateBlock(conflicting))
+	assert.Nil(t, bc.AddBlock(block))
+
+	offenderId := ValidatorId(block.Validator)
+	stake := ledger.GetStake(offenderId)
+	assert.True(t, stake > 0)
+	assert.Equal(t, 1, len(txPool.Transactions()))
+
+	// A slash with forged evidence is rejected
+	evidence, err := NewDoubleSignEvidence(block, conflicting)
+	assert.Nil(t, err)
+	evidence.HeaderB = evidence.HeaderA
+	proof, err := evidence.Bytes()
+	assert.Nil(t, err)
+	forgedSlash, err := currency.NewSlashTransaction(offenderId, stake, proof).ToCoreTransaction()
+	assert.Nil(t, err)
+	assert.Nil(t, forgedSlash.Sign(observer.privateKey))
+	forgedBlock := core.NewBlockWithHeaderInfo(block.Header.Height+1, block.Header.PrevBlockHash)
+	forgedBlock.AddTransaction(forgedSlash)
+	assert.NotNil(t, observer.validateSlashes(forgedBlock))
+
+	slashBlock := mineNext(t, bc, engines)
+	assert.Equal(t, 2, len(slashBlock.Transactions))
+	assert.Equal(t, 0.0, ledger.GetStake(offenderId))
+
+	// Only the remaining validator can propose
+	for i := 0; i < 3; i++ {
+		next := mineNext(t, bc, engines)
+		assert.NotEqual(t, offenderId, ValidatorId(next.Validator))
+	}
+
+	// The evidence cannot slash the validator again after it re-stakes,
+	// even with its headers swapped
+	assert.Nil(t, ledger.CommitTransaciton(currency.NewStakeTransaction("A", offenderId, stake)))
+	swapped, err := NewDoubleSignEvidence(conflicting, block)
+	assert.Nil(t, err)
+	assert.NotNil(t, observer.ReportDoubleSign(swapped))
+
+	proof, err = swapped.Bytes()
+	assert.Nil(t, err)
+	replay := currency.NewSlashTransaction(offenderId, stake, proof)
+	assert.NotNil(t, ledger.CommitTransaciton(replay))
+
+	replayTx, err := replay.ToCoreTransaction()
+	assert.Nil(t, err)
+	assert.Nil(t, replayTx.Sign(observer.privateKey))
+	assert.Nil(t, txPool.AddTransaction(replayTx))
+	next := mineNext(t, bc, engines)
+	assert.Equal(t, 1, len(next.Transactions))
+	assert.Equal(t, stake, ledger.GetStake(offenderId))
+}
+
+func TestProducerUsesParentStakes(t *testing.T) {
+	bc, ledger, txPool, engines := createStakedNetwork(t, []float64{300, 100})
+	genesisHash, err := bc.GetGenesis().Hash()
+	assert.Nil(t, err)
+	genesisStakes := ledger.GetStakes()
+
+	// The first block moves most of the stake to the second validator
+	second := ValidatorId(&engines[1].privateKey.PublicKey)
+	stakeTx, err := currency.NewStakeTransaction("A", second, 600).ToCoreTransaction()
+	assert.Nil(t, err)
+	assert.Nil(t, stakeTx.Sign(engines[0].privateKey))
+	assert.Nil(t, txPool.AddTransaction(stakeTx))
+	mineNext(t, bc, engines)
+	assert.Equal(t, 700.0, ledger.GetStake(second))
+
+	stakes, err := engines[0].StakesAt(genesisHash)
+	assert.Nil(t, err)
+	assert.Equal(t, genesisStakes, stakes)
+
+	// A competing block at the same height is checked against the stakes
+	// of its parent, not those of the tip
+	proposer, err := SelectProposer(genesisStakes, SlotSeed(genesisHash, 1), 1)
+	assert.Nil(t, err)
+	sibling := core.NewBlockWithHeaderInfo(1, genesisHash)
+	assert.Nil(t, sibling.Sign(engineForKeyId(engines, proposer).privateKey))
+	assert.Nil(t, engines[0].CheckProducer(sibling))
+}
+
+func TestSignedBlocksPruned(t *testing.T) {
+	bc, _, _, engines := createStakedNetwork(t, []float64{300, 100})
+	observer := engines[0]
+
+	blocks := make([]*core.Block, 0)
+	for i := 0; i < 4; i++ {
+		block := mineNext(t, bc, engines)
+		assert.Nil(t, observer.ValidateBlock(block))
+		blocks = append(blocks, block)
+	}
+	assert.Equal(t, 4, len(observer.signed))
+
+	finalHash, err := blocks[1].Hash()
+	assert.Nil(t, err)
+	assert.Nil(t, bc.Finalize(finalHash))
+	assert.Nil(t, observer.ValidateBlock(mineNext(t, bc, engines)))
+
+	for slot := range observer.signed {
+		assert.True(t, slot.height > blocks[1].Header.Height)
+	}
+	assert.Equal(t, 3, len(observer.signed))
+}
+
+func createStakedNetwork(t *testing.T, stakes []float64) (*currency.BlockChain, *currency.MemoryLedgerState, *core.DefaultTransactionPool, []*PosEngine) {
+	ledger := currency.NewMemoryLedgerState()
+	bc := currency.NewBlockChain(ledger, 1000)
+	txPool := core.NewDefaultTransactionPool()
+	assert.Nil(t, ledger.AddWallet("A", 1000))
+
+	engines := make([]*PosEngine, len(stakes))
+	for i, stake := range stakes {
+		privKey := crypto.GeneratePrivateKey()
+		assert.Nil(t, ledger.CommitTransaciton(currency.NewStakeTransaction("A", ValidatorId(&privKey.PublicKey), stake)))
+		engines[i] = NewPosEngine(ledger, bc, txPool, privKey, currency.NewRewarder(privKey, 10, 10), 1)
+	}
+	return bc, ledger, txPool, engines
+}
+
+// mineNext adds a block from whichever engine won the slot.
+func mineNext(t *testing.T, bc core.BlockChain, engines []*PosEngine) *core.Block {
+	for _, engine := range engines {
+		block, err := engine.MineBlock(10, "A")
+		if err != nil {
+			continue
+		}
+		assert.Nil(t, engine.ValidateBlock(block))
+		assert.Nil(t, bc.AddBlock(block))
+		return block
+	}
+	t.Fatalf("no engine won the slot at height (%d)", bc.Height()+1)
+	return nil
+}
+
+func engineForKeyId(engines []*PosEngine, id string) *PosEngine {
+	for _, engine := range engines {
+		if ValidatorId(&engine.privateKey.PublicKey) == id {
+			return engine
+		}
+	}
+	return nil
+}
+
+func engineForKey(engines []*PosEngine, key *ecdsa.PublicKey) *PosEngine {
+	for _, engine := range engines {
+		if ValidatorId(&engine.privateKey.PublicKey) == ValidatorId(key) {
+			return engine
+		}
+	}
+	return nil
+}
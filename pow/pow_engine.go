@@ -0,0 +1,29 @@
+package pow
+
+import (
+	"crypto/ecdsa"
+
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/prot"
+)
+
+type PowEngine struct {
+	*PowMiner
+	*PowValidator
+}
+
+func NewPowEngine(prefixZerosInHex uint8, bc core.BlockChain, txPool core.TransactionPool, privKey *ecdsa.PrivateKey, rewarder prot.Rewarder) *PowEngine {
+	return &PowEngine{
+		PowMiner:     NewPowMiner(prefixZerosInHex, bc, txPool, privKey, rewarder),
+		PowValidator: NewPowValidator(prefixZerosInHex),
+	}
+}
+
+// ValidateBlock checks the proof of work and that the block is signed by
+// the key that claimed its coinbase.
+func (engine *PowEngine) ValidateBlock(block *core.Block) error {
+	if err := engine.PowValidator.ValidateBlock(block); err != nil {
+		return err
+	}
+	return prot.VerifyProducer(block, prot.CoinbaseProducer{})
+}
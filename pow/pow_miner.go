@@ -1,19 +1,35 @@
 package pow
 
 import (
+	"context"
 	"crypto/ecdsa"
+	"encoding/binary"
 	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/tusharjoshi4531/block-chain.git/core"
 	"github.com/tusharjoshi4531/block-chain.git/prot"
 )
 
+// hashCheckInterval is how many nonces a worker tries between cancellation
+// checks and hash counter updates.
+const hashCheckInterval = 1024
+
 type PowMiner struct {
 	RequiredPrefixZerosInHex uint8
 	blockChain               core.BlockChain
 	transactionPool          core.TransactionPool
 	privateKey               *ecdsa.PrivateKey
 	rewarder                 prot.Rewarder
+	workers                  int
+	startNonce               func() uint64
+
+	totalHashes atomic.Uint64
+	mu          sync.RWMutex
+	hashRate    float64
 }
 
 func NewPowMiner(prefixZerosInHex uint8, bc core.BlockChain, txPool core.TransactionPool, privKey *ecdsa.PrivateKey, rewarder prot.Rewarder) *PowMiner {
@@ -23,10 +39,78 @@ func NewPowMiner(prefixZerosInHex uint8, bc core.BlockChain, txPool core.Transac
 		transactionPool:          txPool,
 		privateKey:               privKey,
 		rewarder:                 rewarder,
+		workers:                  runtime.NumCPU(),
+		startNonce:               rand.Uint64,
 	}
 }
 
+// SetWorkers sets how many goroutines search for a nonce in parallel.
+func (miner *PowMiner) SetWorkers(workers int) {
+	miner.workers = max(workers, 1)
+}
+
+func (miner *PowMiner) Workers() int {
+	return miner.workers
+}
+
+// HashRate returns the hashes per second of the last mining run.
+func (miner *PowMiner) HashRate() float64 {
+	miner.mu.RLock()
+	defer miner.mu.RUnlock()
+	return miner.hashRate
+}
+
+// TotalHashes returns the number of header hashes computed by the miner.
+func (miner *PowMiner) TotalHashes() uint64 {
+	return miner.totalHashes.Load()
+}
+
 func (miner *PowMiner) MineBlock(transactionsLimit uint32, minerWalletId string) (*core.Block, error) {
+	return miner.MineBlockContext(context.Background(), transactionsLimit, minerWalletId)
+}
+
+// MineBlockContext mines a block on the current tip. It stops with the
+// context's error when ctx is done and with prot.ErrTipChanged when
+// another block becomes the tip first.
+func (miner *PowMiner) MineBlockContext(ctx context.Context, transactionsLimit uint32, minerWalletId string) (*core.Block, error) {
+	tipEvents := miner.blockChain.Subscribe(16, core.EventTipChanged)
+	defer tipEvents.Unsubscribe()
+
+	block, err := miner.BlockTemplate(transactionsLimit, minerWalletId)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := prot.WatchTip(ctx, tipEvents, block.Header.PrevBlockHash)
+	defer cancel()
+
+	if err := miner.SealBlockContext(ctx, block); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// SealBlockContext searches for a seal for a template from BlockTemplate
+// and signs the block once it is found. It only reads the block, never the
+// chain or the pool.
+func (miner *PowMiner) SealBlockContext(ctx context.Context, block *core.Block) error {
+	seal, err := miner.searchSeal(ctx, block.Header)
+	if err != nil {
+		return err
+	}
+
+	block.SetSeal(seal)
+	return block.Sign(miner.privateKey)
+}
+
+// Difficulty is the number of leading zero hex digits a block hash needs.
+func (miner *PowMiner) Difficulty() uint8 {
+	return miner.RequiredPrefixZerosInHex
+}
+
+// BlockTemplate builds an unsealed, unsigned block on the current tip with
+// the reward followed by pooled transactions.
+func (miner *PowMiner) BlockTemplate(transactionsLimit uint32, minerWalletId string) (*core.Block, error) {
 	bc := miner.blockChain
 	txPool := miner.transactionPool
 
@@ -36,7 +120,8 @@ func (miner *PowMiner) MineBlock(transactionsLimit uint32, minerWalletId string)
 		return nil, err
 	}
 
-	block := core.NewBlockWithHeaderInfo(bc.Height()+1, prevHash)
+	block := core.NewBlockWithHeaderInfo(prevBloack.Header.Height+1, prevHash)
+	block.Header.Timestamp = core.NextBlockTimestamp(prevBloack)
 
 	transactions := txPool.Transactions()
 	numTx := uint32(0)
@@ -48,31 +133,129 @@ func (miner *PowMiner) MineBlock(transactionsLimit uint32, minerWalletId string)
 		if bc.HasTransactionInChain(transaction.Hash(), prevHash) == nil {
 			continue
 		}
+		// Locked transactions wait in the pool until they can be mined
+		if core.CheckTransactionLock(transaction, block.Header.Height, block.Header.Timestamp) != nil {
+			continue
+		}
 
 		numTx++
 		block.AddTransaction(transaction)
 	}
-	reward, err := miner.rewarder.GenerateReward(minerWalletId)
+	core.FilterTransactions(bc, block)
+	reward, err := miner.rewarder.GenerateReward(minerWalletId, block)
 	if err != nil {
 		return nil, err
 	}
 
-	block.AddTransaction(reward)
+	block.PrependTransaction(reward)
 
-	currNonceVal := rand.Uint64()
-	for {
-		block.SetNonce(NewPowNonce(currNonceVal))
+	// Fills in DataHash so workers only have to hash the header.
+	if _, err := block.Hash(); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
 
-		hash, err := block.Hash()
-		if err != nil {
-			return nil, err
+// SealBlock applies a seal found outside the miner to a template and signs
+// the block once the seal meets the difficulty.
+func (miner *PowMiner) SealBlock(block *core.Block, seal core.Seal) error {
+	block.SetSeal(seal)
+	if err := NewPowValidator(miner.RequiredPrefixZerosInHex).ValidateBlock(block); err != nil {
+		return err
+	}
+	return block.Sign(miner.privateKey)
+}
+
+// searchSeal splits the search between the workers by extra nonce: worker i
+// tries every nonce with extra nonces i, i+workers, i+2*workers and so on.
+func (miner *PowMiner) searchSeal(ctx context.Context, header core.BlockHeader) (core.Seal, error) {
+	workers := miner.workers
+	start := miner.startNonce()
+	found := make(chan core.Seal, workers)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	begin := time.Now()
+	hashesBefore := miner.totalHashes.Load()
+	defer func() {
+		elapsed := time.Since(begin).Seconds()
+		if elapsed > 0 {
+			miner.mu.Lock()
+			miner.hashRate = float64(miner.totalHashes.Load()-hashesBefore) / elapsed
+			miner.mu.Unlock()
 		}
+	}()
 
-		if validateHash(hash, miner.RequiredPrefixZerosInHex) {
-			break
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(header core.BlockHeader) {
+			defer wg.Done()
+			header.Seal = newWorkerSeal(start, uint64(i))
+			if seal, ok := miner.work(ctx, header, start, uint64(workers)); ok {
+				found <- seal
+			}
+		}(header)
+	}
+
+	select {
+	case seal := <-found:
+		cancel()
+		wg.Wait()
+		return seal, nil
+	case <-ctx.Done():
+		wg.Wait()
+		select {
+		case seal := <-found:
+			return seal, nil
+		default:
+			return core.Seal{}, context.Cause(ctx)
 		}
-		currNonceVal++
 	}
+}
 
-	return block, nil
+func (miner *PowMiner) work(ctx context.Context, header core.BlockHeader, start, step uint64) (core.Seal, bool) {
+	for {
+		for i := 0; i < hashCheckInterval; i++ {
+			hash, err := header.Hash()
+			if err != nil {
+				return core.Seal{}, false
+			}
+			if validateHash(hash, miner.RequiredPrefixZerosInHex) {
+				miner.totalHashes.Add(uint64(i + 1))
+				return header.Seal, true
+			}
+			header.Seal = nextSeal(header.Seal, start, step)
+		}
+		miner.totalHashes.Add(hashCheckInterval)
+
+		select {
+		case <-ctx.Done():
+			return core.Seal{}, false
+		default:
+		}
+	}
+}
+
+func newWorkerSeal(start, extraNonce uint64) core.Seal {
+	seal := core.NewSeal(start)
+	binary.BigEndian.PutUint64(seal.Extra[:8], extraNonce)
+	return seal
+}
+
+// ExtraNonce returns the extra nonce the miner stores in the first bytes of
+// Seal.Extra.
+func ExtraNonce(seal core.Seal) uint64 {
+	return binary.BigEndian.Uint64(seal.Extra[:8])
+}
+
+// nextSeal moves to the next nonce and, once the whole 64-bit nonce space
+// has been tried, rolls over to the worker's next extra nonce.
+func nextSeal(seal core.Seal, start, step uint64) core.Seal {
+	seal.Nonce++
+	if seal.Nonce == start {
+		binary.BigEndian.PutUint64(seal.Extra[:8], ExtraNonce(seal)+step)
+	}
+	return seal
 }
@@ -1,12 +1,16 @@
 package pow
 
 import (
+	"context"
 	"fmt"
+	"math"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/tusharjoshi4531/block-chain.git/core"
 	"github.com/tusharjoshi4531/block-chain.git/crypto"
+	"github.com/tusharjoshi4531/block-chain.git/currency"
 	"github.com/tusharjoshi4531/block-chain.git/prot"
 	"github.com/tusharjoshi4531/block-chain.git/types"
 )
@@ -45,3 +49,111 @@ func TestMineBlock(t *testing.T) {
 		assert.Nil(t, validator.ValidateBlock(block))
 	}
 }
+
+func TestMineBlockWorkers(t *testing.T) {
+	bc := core.NewDefaultBlockChain()
+	privKey := crypto.GeneratePrivateKey()
+
+	prefZeros := uint8(3)
+	miner := NewPowMiner(prefZeros, bc, core.NewDefaultTransactionPool(), privKey, prot.NewSimpleRewarder(privKey))
+	miner.SetWorkers(4)
+	assert.Equal(t, 4, miner.Workers())
+
+	block, err := miner.MineBlockContext(context.Background(), 10, "")
+	assert.Nil(t, err)
+	assert.Nil(t, NewPowValidator(prefZeros).ValidateBlock(block))
+	assert.Nil(t, block.Verify())
+	assert.Less(t, ExtraNonce(block.Header.Seal), uint64(4))
+
+	assert.Greater(t, miner.TotalHashes(), uint64(0))
+	assert.Greater(t, miner.HashRate(), float64(0))
+}
+
+func TestMineBlockCancel(t *testing.T) {
+	bc := core.NewDefaultBlockChain()
+	privKey := crypto.GeneratePrivateKey()
+
+	// No hash has 64 leading zeros, so only cancellation ends the search.
+	miner := NewPowMiner(64, bc, core.NewDefaultTransactionPool(), privKey, prot.NewSimpleRewarder(privKey))
+	miner.SetWorkers(2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := miner.MineBlockContext(ctx, 10, "")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Greater(t, miner.HashRate(), float64(0))
+}
+
+func TestMineBlockTipChanged(t *testing.T) {
+	bc := core.NewDefaultBlockChain()
+	privKey := crypto.GeneratePrivateKey()
+
+	miner := NewPowMiner(64, bc, core.NewDefaultTransactionPool(), privKey, prot.NewSimpleRewarder(privKey))
+	miner.SetWorkers(2)
+
+	errs := make(chan error)
+	go func() {
+		_, err := miner.MineBlockContext(context.Background(), 10, "")
+		errs <- err
+	}()
+
+	// Wait for the miner to start hashing before moving the tip.
+	for miner.TotalHashes() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	genesisHash, err := bc.GetGenesis().Hash()
+	assert.Nil(t, err)
+	block := core.NewBlockWithHeaderInfo(1, genesisHash)
+	assert.Nil(t, block.Sign(privKey))
+	assert.Nil(t, bc.AddBlock(block))
+
+	select {
+	case err := <-errs:
+		assert.ErrorIs(t, err, prot.ErrTipChanged)
+	case <-time.After(5 * time.Second):
+		t.Fatal("miner did not stop after the tip changed")
+	}
+}
+
+func TestBlockTemplateSkipsLockedTransactions(t *testing.T) {
+	bc := core.NewDefaultBlockChain()
+	txPool := core.NewDefaultTransactionPool()
+	privKey := crypto.GeneratePrivateKey()
+	miner := NewPowMiner(1, bc, txPool, privKey, prot.NewSimpleRewarder(privKey))
+
+	tx, err := currency.NewLockedTransaction("A", "B", 10, core.TxLock{Height: 2}).ToCoreTransaction()
+	assert.Nil(t, err)
+	assert.Nil(t, tx.Sign(privKey))
+	assert.Nil(t, txPool.AddTransaction(tx))
+
+	block, err := miner.MineBlock(10, "")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(block.Transactions))
+	assert.Nil(t, bc.AddBlock(block))
+
+	template, err := miner.BlockTemplate(10, "")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(template.Transactions))
+	assert.Equal(t, tx.Hash(), template.Transactions[1].Hash())
+}
+
+func TestExtraNonceRollover(t *testing.T) {
+	start := uint64(math.MaxUint64 - 1)
+	seal := newWorkerSeal(start, 1)
+
+	seal = nextSeal(seal, start, 4)
+	assert.Equal(t, uint64(math.MaxUint64), seal.Nonce)
+	assert.Equal(t, uint64(1), ExtraNonce(seal))
+
+	// Wrapping past the end of the nonce space keeps the extra nonce.
+	seal = nextSeal(seal, start, 4)
+	assert.Equal(t, uint64(0), seal.Nonce)
+	assert.Equal(t, uint64(1), ExtraNonce(seal))
+
+	// Coming back to the start nonce moves to the next extra nonce.
+	seal.Nonce = start - 1
+	seal = nextSeal(seal, start, 4)
+	assert.Equal(t, start, seal.Nonce)
+	assert.Equal(t, uint64(5), ExtraNonce(seal))
+}
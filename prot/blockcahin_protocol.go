@@ -1,19 +1,89 @@
 package prot
 
-import "github.com/tusharjoshi4531/block-chain.git/core"
+import (
+	"context"
+	"errors"
 
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/types"
+)
+
+// Rewarder builds the coinbase of a block whose other transactions have
+// already been added. Miners put the coinbase first.
 type Rewarder interface {
-	GenerateReward(winner string) (*core.Transaction, error)
+	GenerateReward(winner string, block *core.Block) (*core.Transaction, error)
 }
 
 type Validator interface {
 	ValidateBlock(block *core.Block) error
 }
 
+// ProducerRule decides which keys may sign blocks under a consensus engine.
+type ProducerRule interface {
+	CheckProducer(block *core.Block) error
+}
+
 type Miner interface {
 	MineBlock(transactionsLimit uint32, minerWalletId string) (*core.Block, error)
 }
 
+// ErrTipChanged is returned by a ContextMiner when the chain tip moves while
+// it is mining on top of the old one.
+var ErrTipChanged = errors.New("chain tip changed while mining")
+
+// ContextMiner is a miner whose work can be cancelled.
+type ContextMiner interface {
+	Miner
+	MineBlockContext(ctx context.Context, transactionsLimit uint32, minerWalletId string) (*core.Block, error)
+}
+
+// SealMiner is a ContextMiner that builds its block and searches for the
+// seal in separate steps, so a node only has to hold its chain still while
+// the block is built.
+type SealMiner interface {
+	ContextMiner
+	BlockTemplate(transactionsLimit uint32, minerWalletId string) (*core.Block, error)
+	SealBlockContext(ctx context.Context, block *core.Block) error
+}
+
+// WatchTip returns a context that is cancelled with ErrTipChanged once
+// tipEvents reports a tip other than prevHash.
+func WatchTip(ctx context.Context, tipEvents *core.Subscription, prevHash types.Hash) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-tipEvents.Events():
+				if !ok {
+					return
+				}
+				if hash, err := event.Block.Hash(); err == nil && hash != prevHash {
+					cancel(ErrTipChanged)
+					return
+				}
+			}
+		}
+	}()
+	return ctx, func() { cancel(nil) }
+}
+
+// Engine is a consensus engine: it produces blocks on this node and decides
+// whether blocks produced elsewhere are valid.
+type Engine interface {
+	Miner
+	Validator
+}
+
+// TemplateMiner hands out unsealed blocks for mining outside the node and
+// signs them once a valid seal comes back.
+type TemplateMiner interface {
+	BlockTemplate(transactionsLimit uint32, minerWalletId string) (*core.Block, error)
+	SealBlock(block *core.Block, seal core.Seal) error
+	Difficulty() uint8
+}
+
 type Comsumer interface {
 	AddTransaction(transaction *core.Transaction) error
 	GetTransactions() ([]*core.Transaction, error)
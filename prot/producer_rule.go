@@ -0,0 +1,63 @@
+package prot
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+)
+
+// AuthorizedProducers accepts blocks signed by a fixed set of keys.
+type AuthorizedProducers struct {
+	keys map[string]struct{}
+}
+
+func NewAuthorizedProducers(keys ...*ecdsa.PublicKey) *AuthorizedProducers {
+	rule := &AuthorizedProducers{
+		keys: make(map[string]struct{}),
+	}
+	for _, key := range keys {
+		rule.Authorize(key)
+	}
+	return rule
+}
+
+func (rule *AuthorizedProducers) Authorize(key *ecdsa.PublicKey) {
+	rule.keys[hex.EncodeToString(crypto.PublicKeyBytes(key))] = struct{}{}
+}
+
+func (rule *AuthorizedProducers) CheckProducer(block *core.Block) error {
+	producer := hex.EncodeToString(crypto.PublicKeyBytes(block.Validator))
+	if _, ok := rule.keys[producer]; !ok {
+		return fmt.Errorf("block producer (%s) is not authorized", producer)
+	}
+	return nil
+}
+
+// CoinbaseProducer requires the block to be signed by the key that signed
+// its coinbase, the reward transaction miners put first.
+type CoinbaseProducer struct{}
+
+func (CoinbaseProducer) CheckProducer(block *core.Block) error {
+	if len(block.Transactions) == 0 {
+		return fmt.Errorf("block has no coinbase transaction")
+	}
+
+	coinbase := block.Transactions[0]
+	if !bytes.Equal(crypto.PublicKeyBytes(coinbase.From), crypto.PublicKeyBytes(block.Validator)) {
+		return fmt.Errorf("block producer does not match coinbase signer")
+	}
+	return nil
+}
+
+// VerifyProducer checks the block signature and that its signer satisfies
+// the consensus producer rule.
+func VerifyProducer(block *core.Block, rule ProducerRule) error {
+	if err := block.Verify(); err != nil {
+		return err
+	}
+	return rule.CheckProducer(block)
+}
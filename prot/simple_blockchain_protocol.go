@@ -6,6 +6,7 @@ import (
 
 	bcnetwork "github.com/tusharjoshi4531/block-chain.git/bc_network"
 	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/currency"
 )
 
 type SimpleRewarder struct {
@@ -18,21 +19,26 @@ func NewSimpleRewarder(privKey *ecdsa.PrivateKey) *SimpleRewarder {
 	}
 }
 
-func (rewarder *SimpleRewarder) GenerateReward(winner string) (*core.Transaction, error) {
-	tx := core.NewTransaction([]byte("REWARD"))
-	err := tx.Sign(rewarder.privateKey)
+// GenerateReward returns a coinbase that names the winner without minting
+// anything.
+func (rewarder *SimpleRewarder) GenerateReward(winner string, block *core.Block) (*core.Transaction, error) {
+	tx, err := currency.NewCoinbaseTransaction(winner, 0, block.Header.Height).ToCoreTransaction()
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Sign(rewarder.privateKey)
 	return tx, err
 }
 
 type SimpleValidator struct {
-	blockChain core.BlockChain
-	privateKey *ecdsa.PrivateKey
+	blockChain   core.BlockChain
+	producerRule ProducerRule
 }
 
-func NewSimpleValidator(blockChain core.BlockChain, privateKey *ecdsa.PrivateKey) *SimpleValidator {
+func NewSimpleValidator(blockChain core.BlockChain, producerRule ProducerRule) *SimpleValidator {
 	return &SimpleValidator{
-		blockChain: blockChain,
-		privateKey: privateKey,
+		blockChain:   blockChain,
+		producerRule: producerRule,
 	}
 }
 
@@ -49,10 +55,7 @@ func (valdator *SimpleValidator) ValidateBlock(block *core.Block) error {
 		return fmt.Errorf("previous block height (%d) does not match header height (%d)", prevBlock.Header.Height, block.Header.Height)
 	}
 
-	if err := block.Sign(valdator.privateKey); err != nil {
-		return err
-	}
-	return nil
+	return VerifyProducer(block, valdator.producerRule)
 }
 
 type SimpleMiner struct {
@@ -84,6 +87,7 @@ func (miner *SimpleMiner) MineBlock(transactionsLimit uint32, minerWalletId stri
 	block := core.NewBlock()
 	block.Header.PrevBlockHash = prevHash
 	block.Header.Height = bc.Height() + 1
+	block.Header.Timestamp = core.NextBlockTimestamp(prevBloack)
 
 	transactions := txPool.Transactions()
 	numTx := uint32(0)
@@ -95,17 +99,24 @@ func (miner *SimpleMiner) MineBlock(transactionsLimit uint32, minerWalletId stri
 		if bc.HasTransactionInChain(transaction.Hash(), prevHash) == nil {
 			continue
 		}
+		if core.CheckTransactionLock(transaction, block.Header.Height, block.Header.Timestamp) != nil {
+			continue
+		}
 
 		numTx++
 		block.AddTransaction(transaction)
 	}
-	reward, err := miner.rewarder.GenerateReward("")
+	core.FilterTransactions(bc, block)
+	reward, err := miner.rewarder.GenerateReward(minerWalletId, block)
 	if err != nil {
 		return nil, err
 	}
 
-	block.AddTransaction(reward)
+	block.PrependTransaction(reward)
 
+	if err := block.Sign(miner.privateKey); err != nil {
+		return nil, err
+	}
 	return block, nil
 }
 
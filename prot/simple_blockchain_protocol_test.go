@@ -18,7 +18,7 @@ func TestSimpleMining(t *testing.T) {
 	privKey := crypto.GeneratePrivateKey()
 
 	miner := NewSimpleMiner(bc, txPool, privKey)
-	validator := NewSimpleValidator(bc, privKey)
+	validator := NewSimpleValidator(bc, CoinbaseProducer{})
 
 	numTx := 100
 	for i := 0; i < numTx; i++ {
@@ -108,7 +108,7 @@ func TestGetTransactions(t *testing.T) {
 	assert.Equal(t, numBlocks*(int(blockSz)+1), len(txx2))
 	j := 0
 	for i := 0; i < len(txx2); i++ {
-		if i%11 == 10 {
+		if i%11 == 0 {
 			j++
 		} else {
 			fmt.Println(i, j, string(txx[i-j].Data), string(txx2[i].Data))
@@ -122,7 +122,37 @@ func createSimpleParticipant(address string, txPool core.TransactionPool, bc cor
 
 	privKey := crypto.GeneratePrivateKey()
 	miner := NewSimpleMiner(bc, txPool, privKey)
-	validator := NewSimpleValidator(bc, privKey)
+	validator := NewSimpleValidator(bc, CoinbaseProducer{})
 	consumer := NewSimpleConsumer(bc, txPool, transport)
 	return consumer, validator, miner, privKey
 }
+
+func TestProducerRules(t *testing.T) {
+	bc := core.NewDefaultBlockChain()
+	txPool := core.NewDefaultTransactionPool()
+	privKey := crypto.GeneratePrivateKey()
+	otherPrivKey := crypto.GeneratePrivateKey()
+
+	miner := NewSimpleMiner(bc, txPool, privKey)
+	block, err := miner.MineBlock(10, "")
+	assert.Nil(t, err)
+
+	// Coinbase rule
+	assert.Nil(t, VerifyProducer(block, CoinbaseProducer{}))
+	assert.Nil(t, NewSimpleValidator(bc, CoinbaseProducer{}).ValidateBlock(block))
+
+	// Authorized set rule
+	assert.Nil(t, VerifyProducer(block, NewAuthorizedProducers(&privKey.PublicKey)))
+	assert.NotNil(t, VerifyProducer(block, NewAuthorizedProducers(&otherPrivKey.PublicKey)))
+
+	// Validator does not overwrite foreign signatures
+	assert.Nil(t, block.Sign(otherPrivKey))
+	validator := NewSimpleValidator(bc, CoinbaseProducer{})
+	assert.NotNil(t, validator.ValidateBlock(block))
+	assert.Equal(t, &otherPrivKey.PublicKey, block.Validator)
+
+	// Unsigned blocks are rejected
+	unsigned := core.NewBlockWithHeaderInfo(block.Header.Height, block.Header.PrevBlockHash)
+	unsigned.Transactions = block.Transactions
+	assert.NotNil(t, validator.ValidateBlock(unsigned))
+}
@@ -0,0 +1,127 @@
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/currency"
+)
+
+const EventBufferSize = 256
+
+type StreamEvent struct {
+	Type        string             `json:"type"`
+	Block       *BlockResult       `json:"block,omitempty"`
+	Transaction *TransactionResult `json:"transaction,omitempty"`
+	Ancestor    string             `json:"ancestor,omitempty"`
+	ReorgDepth  uint32             `json:"reorgDepth,omitempty"`
+}
+
+func NewStreamEvent(event core.Event) (*StreamEvent, error) {
+	streamEvent := &StreamEvent{
+		Type: core.EventTypeToString(event.Type),
+	}
+
+	if event.Block != nil {
+		block, err := NewBlockResult(event.Block)
+		if err != nil {
+			return nil, err
+		}
+		streamEvent.Block = block
+	}
+	if event.Transaction != nil {
+		streamEvent.Transaction = NewTransactionResult(event.Transaction)
+	}
+	if event.Type == core.EventTipChanged {
+		streamEvent.Ancestor = event.Ancestor.String()
+		streamEvent.ReorgDepth = event.ReorgDepth
+	}
+
+	return streamEvent, nil
+}
+
+// handleEvents streams chain and mempool events as server-sent events. The
+// optional "wallet" query parameter limits block and transaction events to
+// those involving the given wallet; tip changes are always sent. UTXO spends
+// match the wallets they spend from only when the explorer indexer is
+// enabled, since it keeps the owners of spent outputs.
+func (server *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	walletId := r.URL.Query().Get("wallet")
+	var owner func(currency.OutPoint) (string, bool)
+	if server.indexer != nil {
+		owner = server.indexer.GetOutputOwner
+	}
+
+	chainEvents := server.node.BlockChain.Subscribe(
+		EventBufferSize,
+		core.EventTipChanged,
+		core.EventBlockConnected,
+		core.EventBlockDisconnected,
+	)
+	defer chainEvents.Unsubscribe()
+	poolEvents := server.node.TxPool.Subscribe(EventBufferSize, core.EventTransactionAdded)
+	defer poolEvents.Unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	for {
+		var event core.Event
+		select {
+		case <-r.Context().Done():
+			return
+		case event = <-chainEvents.Events():
+		case event = <-poolEvents.Events():
+		}
+
+		if walletId != "" && !eventInvolvesWallet(event, walletId, owner) {
+			continue
+		}
+
+		streamEvent, err := NewStreamEvent(event)
+		if err != nil {
+			continue
+		}
+		data, err := json.Marshal(streamEvent)
+		if err != nil {
+			continue
+		}
+
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", streamEvent.Type, data); err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+func eventInvolvesWallet(event core.Event, walletId string, owner func(currency.OutPoint) (string, bool)) bool {
+	switch event.Type {
+	case core.EventTipChanged:
+		return true
+	case core.EventTransactionAdded:
+		return transactionInvolvesWallet(event.Transaction, walletId, owner)
+	default:
+		for _, tx := range event.Block.Transactions {
+			if transactionInvolvesWallet(tx, walletId, owner) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func transactionInvolvesWallet(tx *core.Transaction, walletId string, owner func(currency.OutPoint) (string, bool)) bool {
+	return slices.Contains(currency.TransactionWallets(tx, owner), walletId)
+}
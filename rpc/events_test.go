@@ -0,0 +1,79 @@
+package rpc
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+	"github.com/tusharjoshi4531/block-chain.git/currency"
+)
+
+func TestEventStream(t *testing.T) {
+	node := createNode(t, "node")
+	assert.Nil(t, node.AddWallet("C"))
+
+	httpServer := httptest.NewServer(NewServer("", node).Handler())
+	defer httpServer.Close()
+
+	resp, err := http.Get(httpServer.URL + "/events?wallet=B")
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	assert.Nil(t, err)
+	assert.Equal(t, ": connected\n", line)
+
+	privKey := crypto.GeneratePrivateKey()
+	ignored := newTransfer(t, "A", "C", 10, privKey)
+	assert.Nil(t, node.AddTransaction(ignored))
+	watched := newTransfer(t, "A", "B", 20, privKey)
+	assert.Nil(t, node.AddTransaction(watched))
+
+	event := readEvent(t, reader)
+	assert.Equal(t, core.EventTypeToString(core.EventTransactionAdded), event.Type)
+	watchedHash := watched.Hash()
+	assert.Equal(t, watchedHash.String(), event.Transaction.Hash)
+
+	block, err := node.MineAndAnnounce(10, "A")
+	assert.Nil(t, err)
+	blockHash, err := block.Hash()
+	assert.Nil(t, err)
+
+	event = readEvent(t, reader)
+	assert.Equal(t, core.EventTypeToString(core.EventBlockConnected), event.Type)
+	assert.Equal(t, blockHash.String(), event.Block.Hash)
+
+	event = readEvent(t, reader)
+	assert.Equal(t, core.EventTypeToString(core.EventTipChanged), event.Type)
+	assert.Equal(t, uint32(1), event.Block.Height)
+}
+
+func newTransfer(t *testing.T, from, to string, amount float64, privKey *ecdsa.PrivateKey) *core.Transaction {
+	tx, err := currency.NewTransaction(from, to, amount).ToCoreTransaction()
+	assert.Nil(t, err)
+	assert.Nil(t, tx.Sign(privKey))
+	tx.SetFirstSeen(time.Now().UnixNano())
+	return tx
+}
+
+func readEvent(t *testing.T, reader *bufio.Reader) *StreamEvent {
+	event := &StreamEvent{}
+	for {
+		line, err := reader.ReadString('\n')
+		assert.Nil(t, err)
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			assert.Nil(t, json.Unmarshal([]byte(data), event))
+			return event
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/indexer"
+	"github.com/tusharjoshi4531/block-chain.git/types"
+)
+
+const (
+	DefaultExplorerPageSize = 20
+	MaxExplorerPageSize     = 100
+)
+
+type BlockSummary struct {
+	Hash             string `json:"hash"`
+	Height           uint32 `json:"height"`
+	Timestamp        int64  `json:"timestamp"`
+	TransactionCount int    `json:"transactionCount"`
+}
+
+type LocatedTransactionResult struct {
+	TransactionResult
+	BlockHash string `json:"blockHash"`
+	Height    uint32 `json:"height"`
+	Index     int    `json:"index"`
+}
+
+type WalletHistoryResult struct {
+	Wallet       string                     `json:"wallet"`
+	Balance      float64                    `json:"balance"`
+	Transactions []LocatedTransactionResult `json:"transactions"`
+}
+
+type AnchorHistoryResult struct {
+	Hash         string                     `json:"hash"`
+	Transactions []LocatedTransactionResult `json:"transactions"`
+}
+
+type ErrorResult struct {
+	Error string `json:"error"`
+}
+
+// EnableExplorer serves block, transaction, wallet history and document
+// anchor endpoints backed by the given indexer.
+func (server *Server) EnableExplorer(ix *indexer.Indexer) {
+	server.indexer = ix
+	server.mux.HandleFunc("GET /explorer/blocks", server.lockedHttp(server.handleExplorerBlocks))
+	server.mux.HandleFunc("GET /explorer/blocks/{id}", server.lockedHttp(server.handleExplorerBlock))
+	server.mux.HandleFunc("GET /explorer/transactions/{hash}", server.lockedHttp(server.handleExplorerTransaction))
+	server.mux.HandleFunc("GET /explorer/wallets/{id}", server.lockedHttp(server.handleExplorerWallet))
+	server.mux.HandleFunc("GET /explorer/anchors/{hash}", server.lockedHttp(server.handleExplorerAnchors))
+}
+
+// lockedHttp is locked for the explorer's http handlers.
+func (server *Server) lockedHttp(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		lock := server.node.ChainLock()
+		lock.Lock()
+		defer lock.Unlock()
+		handler(w, r)
+	}
+}
+
+func (server *Server) handleExplorerBlocks(w http.ResponseWriter, r *http.Request) {
+	bc := server.node.BlockChain
+
+	limit := DefaultExplorerPageSize
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 {
+			writeJsonError(w, http.StatusBadRequest, fmt.Errorf("invalid limit (%s)", limitStr))
+			return
+		}
+		limit = min(parsed, MaxExplorerPageSize)
+	}
+
+	block := bc.GetHeighestBlock()
+	if beforeStr := r.URL.Query().Get("before"); beforeStr != "" {
+		before, err := strconv.ParseUint(beforeStr, 10, 32)
+		if err != nil || before == 0 {
+			writeJsonError(w, http.StatusBadRequest, fmt.Errorf("invalid height (%s)", beforeStr))
+			return
+		}
+		if block, err = bc.GetBlockAtHeight(min(uint32(before-1), bc.Height())); err != nil {
+			writeJsonError(w, http.StatusNotFound, err)
+			return
+		}
+	}
+
+	summaries := make([]BlockSummary, 0, limit)
+	for len(summaries) < limit {
+		hash, err := block.Hash()
+		if err != nil {
+			writeJsonError(w, http.StatusInternalServerError, err)
+			return
+		}
+		summaries = append(summaries, BlockSummary{
+			Hash:             hash.String(),
+			Height:           block.Header.Height,
+			Timestamp:        block.Header.Timestamp,
+			TransactionCount: len(block.Transactions),
+		})
+
+		if block.Header.Height == 0 {
+			break
+		}
+		if block, err = bc.GetPrevBlock(block); err != nil {
+			writeJsonError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	writeJson(w, summaries)
+}
+
+func (server *Server) handleExplorerBlock(w http.ResponseWriter, r *http.Request) {
+	bc := server.node.BlockChain
+	id := r.PathValue("id")
+
+	var block *core.Block
+	var err error
+	if height, parseErr := strconv.ParseUint(id, 10, 32); parseErr == nil {
+		block, err = bc.GetBlockAtHeight(uint32(height))
+	} else {
+		hash, hashErr := types.HashFromString(id)
+		if hashErr != nil {
+			writeJsonError(w, http.StatusBadRequest, hashErr)
+			return
+		}
+		block, err = bc.GetBlockWithHash(hash)
+	}
+	if err != nil {
+		writeJsonError(w, http.StatusNotFound, err)
+		return
+	}
+
+	result, err := NewBlockResult(block)
+	if err != nil {
+		writeJsonError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJson(w, result)
+}
+
+func (server *Server) handleExplorerTransaction(w http.ResponseWriter, r *http.Request) {
+	hash, err := types.HashFromString(r.PathValue("hash"))
+	if err != nil {
+		writeJsonError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	result, err := server.locatedTransaction(hash)
+	if err != nil {
+		writeJsonError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJson(w, result)
+}
+
+func (server *Server) handleExplorerWallet(w http.ResponseWriter, r *http.Request) {
+	walletId := r.PathValue("id")
+
+	balance, err := server.node.Ledger.GetBalance(walletId)
+	if err != nil {
+		writeJsonError(w, http.StatusNotFound, err)
+		return
+	}
+
+	hashes := server.indexer.GetWalletTransactions(walletId)
+	transactions := make([]LocatedTransactionResult, 0, len(hashes))
+	for _, hash := range hashes {
+		result, err := server.locatedTransaction(hash)
+		if err != nil {
+			continue
+		}
+		transactions = append(transactions, *result)
+	}
+
+	writeJson(w, &WalletHistoryResult{
+		Wallet:       walletId,
+		Balance:      balance,
+		Transactions: transactions,
+	})
+}
+
+// handleExplorerAnchors lists the main chain transactions anchoring a
+// document hash, oldest first; the first one dates the document.
+func (server *Server) handleExplorerAnchors(w http.ResponseWriter, r *http.Request) {
+	hash, err := types.HashFromString(r.PathValue("hash"))
+	if err != nil {
+		writeJsonError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	hashes := server.indexer.GetAnchorTransactions(hash)
+	if len(hashes) == 0 {
+		writeJsonError(w, http.StatusNotFound, fmt.Errorf("document with hash (%s) is not anchored in the main chain", hash.String()))
+		return
+	}
+
+	transactions := make([]LocatedTransactionResult, 0, len(hashes))
+	for _, txHash := range hashes {
+		result, err := server.locatedTransaction(txHash)
+		if err != nil {
+			continue
+		}
+		transactions = append(transactions, *result)
+	}
+
+	writeJson(w, &AnchorHistoryResult{
+		Hash:         hash.String(),
+		Transactions: transactions,
+	})
+}
+
+func (server *Server) locatedTransaction(hash types.Hash) (*LocatedTransactionResult, error) {
+	tx, location, err := server.indexer.GetTransaction(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LocatedTransactionResult{
+		TransactionResult: *NewTransactionResult(tx),
+		BlockHash:         location.BlockHash.String(),
+		Height:            location.Height,
+		Index:             location.Index,
+	}, nil
+}
+
+func writeJsonError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(&ErrorResult{Error: err.Error()})
+}
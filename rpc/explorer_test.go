@@ -0,0 +1,94 @@
+package rpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tusharjoshi4531/block-chain.git/anchor"
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+	"github.com/tusharjoshi4531/block-chain.git/indexer"
+	"github.com/tusharjoshi4531/block-chain.git/types"
+)
+
+func TestExplorer(t *testing.T) {
+	node := createNode(t, "node")
+	ix := indexer.NewIndexer(node.BlockChain)
+	assert.Nil(t, ix.Start())
+	defer ix.Stop()
+
+	server := NewServer("", node)
+	server.EnableExplorer(ix)
+	httpServer := httptest.NewServer(server.Handler())
+	defer httpServer.Close()
+
+	privKey := crypto.GeneratePrivateKey()
+	tx := newTransfer(t, "A", "B", 25, privKey)
+	assert.Nil(t, node.AddTransaction(tx))
+	document := types.Hash{1}
+	anchorTx, err := anchor.NewAnchorTransaction(document, "notes.txt", privKey)
+	assert.Nil(t, err)
+	assert.Nil(t, node.AddTransaction(anchorTx))
+	block, err := node.MineAndAnnounce(10, "A")
+	assert.Nil(t, err)
+	blockHash, err := block.Hash()
+	assert.Nil(t, err)
+	_, err = node.MineAndAnnounce(10, "B")
+	assert.Nil(t, err)
+
+	txHash := tx.Hash()
+	assert.Eventually(t, func() bool {
+		return len(ix.GetWalletTransactions("B")) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	summaries := []BlockSummary{}
+	assert.Equal(t, http.StatusOK, get(t, httpServer.URL+"/explorer/blocks?limit=2", &summaries))
+	assert.Equal(t, 2, len(summaries))
+	assert.Equal(t, uint32(2), summaries[0].Height)
+	assert.Equal(t, blockHash.String(), summaries[1].Hash)
+
+	summaries = []BlockSummary{}
+	assert.Equal(t, http.StatusOK, get(t, httpServer.URL+"/explorer/blocks?before=2", &summaries))
+	assert.Equal(t, 2, len(summaries))
+	assert.Equal(t, uint32(0), summaries[1].Height)
+
+	blockResult := &BlockResult{}
+	assert.Equal(t, http.StatusOK, get(t, httpServer.URL+"/explorer/blocks/1", blockResult))
+	assert.Equal(t, blockHash.String(), blockResult.Hash)
+	assert.Equal(t, http.StatusOK, get(t, httpServer.URL+"/explorer/blocks/"+blockHash.String(), blockResult))
+	assert.Equal(t, uint32(1), blockResult.Height)
+	assert.Equal(t, http.StatusNotFound, get(t, httpServer.URL+"/explorer/blocks/7", &ErrorResult{}))
+
+	located := &LocatedTransactionResult{}
+	assert.Equal(t, http.StatusOK, get(t, httpServer.URL+"/explorer/transactions/"+txHash.String(), located))
+	assert.Equal(t, blockHash.String(), located.BlockHash)
+	assert.Equal(t, "B", located.Transfer.To)
+
+	history := &WalletHistoryResult{}
+	assert.Equal(t, http.StatusOK, get(t, httpServer.URL+"/explorer/wallets/B", history))
+	// Transfer from A plus the reward for mining block 2
+	assert.Equal(t, 2, len(history.Transactions))
+	assert.Equal(t, txHash.String(), history.Transactions[0].Hash)
+	assert.Equal(t, http.StatusNotFound, get(t, httpServer.URL+"/explorer/wallets/Z", &ErrorResult{}))
+
+	anchors := &AnchorHistoryResult{}
+	assert.Equal(t, http.StatusOK, get(t, httpServer.URL+"/explorer/anchors/"+document.String(), anchors))
+	assert.Equal(t, 1, len(anchors.Transactions))
+	assert.Equal(t, blockHash.String(), anchors.Transactions[0].BlockHash)
+	assert.Equal(t, "notes.txt", anchors.Transactions[0].Anchor.Metadata)
+	unknown := types.Hash{2}
+	assert.Equal(t, http.StatusNotFound, get(t, httpServer.URL+"/explorer/anchors/"+unknown.String(), &ErrorResult{}))
+	assert.Equal(t, http.StatusBadRequest, get(t, httpServer.URL+"/explorer/anchors/xyz", &ErrorResult{}))
+}
+
+func get(t *testing.T, url string, result any) int {
+	resp, err := http.Get(url)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(result))
+	return resp.StatusCode
+}
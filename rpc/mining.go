@@ -0,0 +1,232 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/prot"
+)
+
+const (
+	MethodGetBlockTemplate = "getBlockTemplate"
+	MethodSubmitBlock      = "submitBlock"
+)
+
+// MaxBlockTemplates bounds how many outstanding templates the server keeps.
+const MaxBlockTemplates = 64
+
+// sealSize is the encoded size of core.Seal at the end of a header.
+const sealSize = 8 + core.SealExtraSize
+
+type BlockTemplateParams struct {
+	Wallet            string `json:"wallet"`
+	TransactionsLimit uint32 `json:"transactionsLimit,omitempty"`
+}
+
+type TemplateTransactionResult struct {
+	TransactionResult
+	Raw string `json:"raw"`
+}
+
+// BlockTemplateResult describes a block for an external miner. Header is
+// the canonical header with an empty seal; the miner replaces its last
+// SealSize bytes with a big-endian nonce and extra data until the sha256 of
+// the header has PrefixZeros leading zero hex digits, that is, until it is
+// at most Target.
+type BlockTemplateResult struct {
+	TemplateId    string                      `json:"templateId"`
+	Version       uint32                      `json:"version"`
+	Height        uint32                      `json:"height"`
+	PrevBlockHash string                      `json:"prevBlockHash"`
+	DataHash      string                      `json:"dataHash"`
+	Timestamp     int64                       `json:"timestamp"`
+	PrefixZeros   uint8                       `json:"prefixZeros"`
+	Target        string                      `json:"target"`
+	Header        string                      `json:"header"`
+	SealSize      int                         `json:"sealSize"`
+	Coinbase      TemplateTransactionResult   `json:"coinbase"`
+	Transactions  []TemplateTransactionResult `json:"transactions"`
+}
+
+// SubmitBlockParams carries either the seal found for a template or a
+// complete signed block.
+type SubmitBlockParams struct {
+	TemplateId string `json:"templateId,omitempty"`
+	Nonce      uint64 `json:"nonce,omitempty"`
+	Extra      string `json:"extra,omitempty"`
+	Block      string `json:"block,omitempty"`
+}
+
+type blockTemplates struct {
+	mu     sync.Mutex
+	blocks map[string]*core.Block
+}
+
+func (server *Server) templateMiner() (prot.TemplateMiner, *Error) {
+	miner, ok := server.node.Miner.(prot.TemplateMiner)
+	if !ok {
+		return nil, newError(ErrCodeServer, fmt.Errorf("node miner does not support block templates"))
+	}
+	return miner, nil
+}
+
+func (server *Server) getBlockTemplate(params json.RawMessage) (any, *Error) {
+	templateParams := &BlockTemplateParams{TransactionsLimit: DefaultMineTransactionsLimit}
+	if err := decodeParams(params, templateParams); err != nil {
+		return nil, err
+	}
+
+	miner, rpcErr := server.templateMiner()
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	block, err := miner.BlockTemplate(templateParams.TransactionsLimit, templateParams.Wallet)
+	if err != nil {
+		return nil, newError(ErrCodeServer, err)
+	}
+	result, err := newBlockTemplateResult(block, miner.Difficulty())
+	if err != nil {
+		return nil, newError(ErrCodeServer, err)
+	}
+
+	server.templates.add(result.TemplateId, block)
+	return result, nil
+}
+
+func (server *Server) submitBlock(params json.RawMessage) (any, *Error) {
+	submitParams := &SubmitBlockParams{}
+	if err := decodeParams(params, submitParams); err != nil {
+		return nil, err
+	}
+
+	if submitParams.Block != "" {
+		blockBytes, err := hex.DecodeString(submitParams.Block)
+		if err != nil {
+			return nil, newError(ErrCodeInvalidParams, err)
+		}
+		block := core.NewBlock()
+		if err := block.Decode(bytes.NewBuffer(blockBytes)); err != nil {
+			return nil, newError(ErrCodeInvalidParams, err)
+		}
+		if err := server.node.SubmitBlock(block); err != nil {
+			return nil, newError(ErrCodeServer, err)
+		}
+		return blockResult(block)
+	}
+
+	seal := core.NewSeal(submitParams.Nonce)
+	extra, err := hex.DecodeString(submitParams.Extra)
+	if err != nil || len(extra) > core.SealExtraSize {
+		return nil, newError(ErrCodeInvalidParams, fmt.Errorf("extra must be at most (%d) hex encoded bytes", core.SealExtraSize))
+	}
+	copy(seal.Extra[:], extra)
+
+	miner, rpcErr := server.templateMiner()
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	block, rpcErr := server.sealTemplate(miner, submitParams.TemplateId, seal)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	// SubmitBlock takes the chain lock again, so a peer's block may become
+	// the tip in between; ours is then added as a fork like any other.
+	if err := server.node.SubmitBlock(block); err != nil {
+		return nil, newError(ErrCodeServer, err)
+	}
+	return blockResult(block)
+}
+
+// sealTemplate takes a template out of the server and seals it as long as
+// it still builds on the tip.
+func (server *Server) sealTemplate(miner prot.TemplateMiner, templateId string, seal core.Seal) (*core.Block, *Error) {
+	lock := server.node.ChainLock()
+	lock.Lock()
+	defer lock.Unlock()
+
+	server.templates.mu.Lock()
+	defer server.templates.mu.Unlock()
+
+	block, ok := server.templates.blocks[templateId]
+	if !ok {
+		return nil, newError(ErrCodeInvalidParams, fmt.Errorf("unknown template (%s)", templateId))
+	}
+	tipHash, err := server.node.BlockChain.GetHeighestBlock().Hash()
+	if err != nil {
+		return nil, newError(ErrCodeServer, err)
+	}
+	if block.Header.PrevBlockHash != tipHash {
+		delete(server.templates.blocks, templateId)
+		return nil, newError(ErrCodeServer, fmt.Errorf("template (%s) is stale", templateId))
+	}
+
+	if err := miner.SealBlock(block, seal); err != nil {
+		return nil, newError(ErrCodeServer, err)
+	}
+	delete(server.templates.blocks, templateId)
+	return block, nil
+}
+
+func (templates *blockTemplates) add(id string, block *core.Block) {
+	templates.mu.Lock()
+	defer templates.mu.Unlock()
+
+	// Templates on older tips can no longer be submitted.
+	for templateId, template := range templates.blocks {
+		if template.Header.PrevBlockHash != block.Header.PrevBlockHash {
+			delete(templates.blocks, templateId)
+		}
+	}
+	if len(templates.blocks) >= MaxBlockTemplates {
+		for templateId := range templates.blocks {
+			delete(templates.blocks, templateId)
+			break
+		}
+	}
+	templates.blocks[id] = block
+}
+
+func newBlockTemplateResult(block *core.Block, prefixZeros uint8) (*BlockTemplateResult, error) {
+	templateId, err := block.Hash()
+	if err != nil {
+		return nil, err
+	}
+	header, err := block.Header.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	transactions := make([]TemplateTransactionResult, 0, len(block.Transactions))
+	for _, tx := range block.Transactions {
+		raw, err := tx.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, TemplateTransactionResult{
+			TransactionResult: *NewTransactionResult(tx),
+			Raw:               hex.EncodeToString(raw),
+		})
+	}
+
+	return &BlockTemplateResult{
+		TemplateId:    templateId.String(),
+		Version:       block.Header.Version,
+		Height:        block.Header.Height,
+		PrevBlockHash: block.Header.PrevBlockHash.String(),
+		DataHash:      block.Header.DataHash.String(),
+		Timestamp:     block.Header.Timestamp,
+		PrefixZeros:   prefixZeros,
+		Target:        strings.Repeat("0", int(prefixZeros)) + strings.Repeat("f", 64-int(prefixZeros)),
+		Header:        hex.EncodeToString(header),
+		SealSize:      sealSize,
+		Coinbase:      transactions[0],
+		Transactions:  transactions[1:],
+	}, nil
+}
@@ -0,0 +1,99 @@
+package rpc
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+	"github.com/tusharjoshi4531/block-chain.git/currency"
+)
+
+func TestBlockTemplate(t *testing.T) {
+	node := createNode(t, "node")
+
+	httpServer := httptest.NewServer(NewServer("", node).Handler())
+	defer httpServer.Close()
+
+	transfer := currency.NewTransaction("A", "B", 150)
+	tx, err := transfer.ToCoreTransaction()
+	assert.Nil(t, err)
+	assert.Nil(t, tx.Sign(crypto.GeneratePrivateKey()))
+	assert.Nil(t, node.AddTransaction(tx))
+
+	template := &BlockTemplateResult{}
+	assert.Nil(t, call(t, httpServer.URL, MethodGetBlockTemplate, BlockTemplateParams{Wallet: "A"}, template))
+	assert.Equal(t, uint32(1), template.Height)
+	assert.Equal(t, 1, len(template.Transactions))
+	assert.Equal(t, "B", template.Transactions[0].Transfer.To)
+	assert.Equal(t, "A", template.Coinbase.Transfer.To)
+	assert.True(t, strings.HasPrefix(template.Target, strings.Repeat("0", int(template.PrefixZeros))))
+
+	nonce, extra := solveTemplate(t, template)
+
+	// Seal that does not meet the target
+	badNonce := nonce + 1
+	for ; ; badNonce++ {
+		if !meetsTarget(t, template, badNonce, extra) {
+			break
+		}
+	}
+	rpcErr := call(t, httpServer.URL, MethodSubmitBlock, SubmitBlockParams{TemplateId: template.TemplateId, Nonce: badNonce, Extra: extra}, nil)
+	assert.NotNil(t, rpcErr)
+
+	rpcErr = call(t, httpServer.URL, MethodSubmitBlock, SubmitBlockParams{TemplateId: "unknown", Nonce: nonce, Extra: extra}, nil)
+	assert.NotNil(t, rpcErr)
+	assert.Equal(t, ErrCodeInvalidParams, rpcErr.Code)
+
+	// Template on the same tip that goes stale once the first one is accepted
+	stale := &BlockTemplateResult{}
+	assert.Nil(t, call(t, httpServer.URL, MethodGetBlockTemplate, BlockTemplateParams{Wallet: "B"}, stale))
+
+	submitted := &BlockResult{}
+	assert.Nil(t, call(t, httpServer.URL, MethodSubmitBlock, SubmitBlockParams{TemplateId: template.TemplateId, Nonce: nonce, Extra: extra}, submitted))
+	assert.Equal(t, uint32(1), submitted.Height)
+	assert.True(t, strings.HasPrefix(submitted.Hash, strings.Repeat("0", int(template.PrefixZeros))))
+
+	tip := &BlockResult{}
+	assert.Nil(t, call(t, httpServer.URL, MethodGetTip, nil, tip))
+	assert.Equal(t, submitted.Hash, tip.Hash)
+
+	balance := &BalanceResult{}
+	assert.Nil(t, call(t, httpServer.URL, MethodGetBalance, WalletParams{Wallet: "B"}, balance))
+	assert.Equal(t, float64(1000+150), balance.Balance)
+
+	// The template cannot be submitted twice
+	assert.NotNil(t, call(t, httpServer.URL, MethodSubmitBlock, SubmitBlockParams{TemplateId: template.TemplateId, Nonce: nonce, Extra: extra}, nil))
+
+	staleNonce, staleExtra := solveTemplate(t, stale)
+	rpcErr = call(t, httpServer.URL, MethodSubmitBlock, SubmitBlockParams{TemplateId: stale.TemplateId, Nonce: staleNonce, Extra: staleExtra}, nil)
+	assert.NotNil(t, rpcErr)
+	assert.Equal(t, ErrCodeServer, rpcErr.Code)
+}
+
+func solveTemplate(t *testing.T, template *BlockTemplateResult) (uint64, string) {
+	extra := hex.EncodeToString([]byte("external"))
+	for nonce := uint64(0); ; nonce++ {
+		if meetsTarget(t, template, nonce, extra) {
+			return nonce, extra
+		}
+	}
+}
+
+func meetsTarget(t *testing.T, template *BlockTemplateResult, nonce uint64, extra string) bool {
+	header, err := hex.DecodeString(template.Header)
+	assert.Nil(t, err)
+	extraBytes, err := hex.DecodeString(extra)
+	assert.Nil(t, err)
+
+	seal := header[len(header)-template.SealSize:]
+	binary.BigEndian.PutUint64(seal, nonce)
+	copy(seal[8:], extraBytes)
+
+	hash := sha256.Sum256(header)
+	return hex.EncodeToString(hash[:]) <= template.Target
+}
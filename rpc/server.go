@@ -0,0 +1,351 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/currency"
+	"github.com/tusharjoshi4531/block-chain.git/indexer"
+	"github.com/tusharjoshi4531/block-chain.git/tcp"
+	"github.com/tusharjoshi4531/block-chain.git/types"
+)
+
+const (
+	MethodGetBlock        = "getBlock"
+	MethodGetTip          = "getTip"
+	MethodGetBalance      = "getBalance"
+	MethodListWallets     = "listWallets"
+	MethodSendTransaction = "sendTransaction"
+	MethodMine            = "mine"
+	MethodPeers           = "peers"
+	MethodMempool         = "mempool"
+	MethodListUTXOs       = "listUTXOs"
+	MethodListAssets      = "listAssets"
+	MethodGetAssets       = "getAssets"
+)
+
+const DefaultMineTransactionsLimit = 10
+
+type handlerFunc func(params json.RawMessage) (any, *Error)
+
+type Server struct {
+	address    string
+	node       *tcp.TCPServer
+	methods    map[string]handlerFunc
+	mux        *http.ServeMux
+	httpServer *http.Server
+	indexer    *indexer.Indexer
+	templates  blockTemplates
+}
+
+func NewServer(address string, node *tcp.TCPServer) *Server {
+	server := &Server{
+		address: address,
+		node:    node,
+		mux:     http.NewServeMux(),
+		templates: blockTemplates{
+			blocks: make(map[string]*core.Block),
+		},
+	}
+
+	// mine and submitBlock go through the node, which takes its chain lock
+	// itself; every other method runs under that lock.
+	server.methods = map[string]handlerFunc{
+		MethodGetBlock:        server.locked(server.getBlock),
+		MethodGetTip:          server.locked(server.getTip),
+		MethodGetBalance:      server.locked(server.getBalance),
+		MethodListWallets:     server.locked(server.listWallets),
+		MethodSendTransaction: server.locked(server.sendTransaction),
+		MethodMine:            server.mine,
+		MethodPeers:           server.peers,
+		MethodMempool:         server.locked(server.mempool),
+		MethodListUTXOs:       server.locked(server.listUTXOs),
+		MethodListAssets:      server.locked(server.listAssets),
+		MethodGetAssets:       server.locked(server.getAssets),
+
+		MethodGetBlockTemplate: server.locked(server.getBlockTemplate),
+		MethodSubmitBlock:      server.submitBlock,
+	}
+	server.mux.HandleFunc("POST /{$}", server.handleRpc)
+	server.mux.HandleFunc("GET /events", server.handleEvents)
+
+	return server
+}
+
+func (server *Server) Address() string {
+	return server.address
+}
+
+func (server *Server) Handler() http.Handler {
+	return server.mux
+}
+
+func (server *Server) Listen() error {
+	listener, err := net.Listen("tcp", server.address)
+	if err != nil {
+		return err
+	}
+
+	server.httpServer = &http.Server{
+		Handler:           server.mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	fmt.Printf("RPC server running at -> %s\n", listener.Addr())
+	go func() {
+		if err := server.httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Println(err)
+		}
+	}()
+	return nil
+}
+
+func (server *Server) Close() error {
+	if server.httpServer == nil {
+		return nil
+	}
+	return server.httpServer.Close()
+}
+
+func (server *Server) handleRpc(w http.ResponseWriter, r *http.Request) {
+	req := &Request{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		writeJson(w, &Response{JsonRpc: JsonRpcVersion, Error: newError(ErrCodeParse, err)})
+		return
+	}
+
+	resp := &Response{
+		JsonRpc: JsonRpcVersion,
+		Id:      req.Id,
+	}
+
+	if req.JsonRpc != JsonRpcVersion {
+		resp.Error = newError(ErrCodeInvalidRequest, fmt.Errorf("unsupported jsonrpc version (%s)", req.JsonRpc))
+		writeJson(w, resp)
+		return
+	}
+
+	method, ok := server.methods[req.Method]
+	if !ok {
+		resp.Error = newError(ErrCodeMethodNotFound, fmt.Errorf("method (%s) not found", req.Method))
+		writeJson(w, resp)
+		return
+	}
+
+	result, rpcErr := method(req.Params)
+	if rpcErr != nil {
+		resp.Error = rpcErr
+	} else {
+		resp.Result = result
+	}
+	writeJson(w, resp)
+}
+
+// locked runs a handler while holding the node's chain lock, so it never
+// sees the chain, the ledger or the pool halfway through a change.
+func (server *Server) locked(handler handlerFunc) handlerFunc {
+	return func(params json.RawMessage) (any, *Error) {
+		lock := server.node.ChainLock()
+		lock.Lock()
+		defer lock.Unlock()
+		return handler(params)
+	}
+}
+
+func (server *Server) getBlock(params json.RawMessage) (any, *Error) {
+	blockParams := &BlockParams{}
+	if err := decodeParams(params, blockParams); err != nil {
+		return nil, err
+	}
+
+	bc := server.node.BlockChain
+	var block *core.Block
+	var err error
+	switch {
+	case blockParams.Hash != "":
+		hash, hashErr := types.HashFromString(blockParams.Hash)
+		if hashErr != nil {
+			return nil, newError(ErrCodeInvalidParams, hashErr)
+		}
+		block, err = bc.GetBlockWithHash(hash)
+	case blockParams.Height != nil:
+		block, err = bc.GetBlockAtHeight(*blockParams.Height)
+	default:
+		return nil, newError(ErrCodeInvalidParams, fmt.Errorf("either hash or height is required"))
+	}
+	if err != nil {
+		return nil, newError(ErrCodeServer, err)
+	}
+
+	return blockResult(block)
+}
+
+func (server *Server) getTip(params json.RawMessage) (any, *Error) {
+	return blockResult(server.node.BlockChain.GetHeighestBlock())
+}
+
+func (server *Server) getBalance(params json.RawMessage) (any, *Error) {
+	walletParams := &WalletParams{}
+	if err := decodeParams(params, walletParams); err != nil {
+		return nil, err
+	}
+
+	balance, err := server.node.Ledger.GetBalance(walletParams.Wallet)
+	if err != nil {
+		return nil, newError(ErrCodeServer, err)
+	}
+	return &BalanceResult{Wallet: walletParams.Wallet, Balance: balance}, nil
+}
+
+func (server *Server) listWallets(params json.RawMessage) (any, *Error) {
+	wallets := server.node.Ledger.GetWallets()
+	sort.Strings(wallets)
+	return wallets, nil
+}
+
+// listUTXOs returns the unspent outputs of a wallet; it needs a node running
+// a UTXO ledger.
+func (server *Server) listUTXOs(params json.RawMessage) (any, *Error) {
+	walletParams := &WalletParams{}
+	if err := decodeParams(params, walletParams); err != nil {
+		return nil, err
+	}
+
+	ledger, ok := server.node.Ledger.(currency.UTXOLedger)
+	if !ok {
+		return nil, newError(ErrCodeServer, fmt.Errorf("ledger does not keep unspent outputs"))
+	}
+	utxos := ledger.GetUTXOs(walletParams.Wallet)
+	results := make([]UTXOResult, 0, len(utxos))
+	for _, utxo := range utxos {
+		results = append(results, UTXOResult{
+			TxHash: utxo.OutPoint.TxHash.String(),
+			Index:  utxo.OutPoint.Index,
+			Owner:  utxo.Output.Owner,
+			Amount: utxo.Output.Amount,
+			Script: hex.EncodeToString(utxo.Output.Script),
+		})
+	}
+	return results, nil
+}
+
+func (server *Server) listAssets(params json.RawMessage) (any, *Error) {
+	assets := server.node.Ledger.GetAssets()
+	results := make([]AssetResult, 0, len(assets))
+	for _, asset := range assets {
+		results = append(results, AssetResult{
+			Id:     asset.Id,
+			Issuer: asset.Issuer,
+			Supply: asset.Supply,
+		})
+	}
+	return results, nil
+}
+
+// getAssets returns the asset balances of a wallet ordered by asset id.
+func (server *Server) getAssets(params json.RawMessage) (any, *Error) {
+	walletParams := &WalletParams{}
+	if err := decodeParams(params, walletParams); err != nil {
+		return nil, err
+	}
+	if !server.node.Ledger.HasWallet(walletParams.Wallet) {
+		return nil, newError(ErrCodeServer, fmt.Errorf("member with id (%s) is not present in the ledger", walletParams.Wallet))
+	}
+
+	balances := server.node.Ledger.GetAssetBalances(walletParams.Wallet)
+	results := make([]AssetBalanceResult, 0, len(balances))
+	for asset, balance := range balances {
+		results = append(results, AssetBalanceResult{Asset: asset, Balance: balance})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Asset < results[j].Asset
+	})
+	return results, nil
+}
+
+func (server *Server) sendTransaction(params json.RawMessage) (any, *Error) {
+	sendParams := &SendTransactionParams{}
+	if err := decodeParams(params, sendParams); err != nil {
+		return nil, err
+	}
+
+	txBytes, err := hex.DecodeString(sendParams.Transaction)
+	if err != nil {
+		return nil, newError(ErrCodeInvalidParams, err)
+	}
+
+	tx := core.NewTransaction([]byte{})
+	if err := tx.Decode(bytes.NewBuffer(txBytes)); err != nil {
+		return nil, newError(ErrCodeInvalidParams, err)
+	}
+	if err := core.ValidateTransaction(tx); err != nil {
+		return nil, newError(ErrCodeInvalidParams, err)
+	}
+
+	tx.SetFirstSeen(time.Now().UnixNano())
+	if err := server.node.AddTransaction(tx); err != nil {
+		return nil, newError(ErrCodeServer, err)
+	}
+
+	hash := tx.Hash()
+	return &SendTransactionResult{Hash: hash.String()}, nil
+}
+
+func (server *Server) mine(params json.RawMessage) (any, *Error) {
+	mineParams := &MineParams{TransactionsLimit: DefaultMineTransactionsLimit}
+	if err := decodeParams(params, mineParams); err != nil {
+		return nil, err
+	}
+
+	block, err := server.node.MineAndAnnounce(mineParams.TransactionsLimit, mineParams.Wallet)
+	if err != nil {
+		return nil, newError(ErrCodeServer, err)
+	}
+	return blockResult(block)
+}
+
+func (server *Server) peers(params json.RawMessage) (any, *Error) {
+	return server.node.Peers(), nil
+}
+
+func (server *Server) mempool(params json.RawMessage) (any, *Error) {
+	transactions := server.node.TxPool.Transactions()
+	results := make([]TransactionResult, 0, len(transactions))
+	for _, tx := range transactions {
+		results = append(results, *NewTransactionResult(tx))
+	}
+	return results, nil
+}
+
+func blockResult(block *core.Block) (any, *Error) {
+	result, err := NewBlockResult(block)
+	if err != nil {
+		return nil, newError(ErrCodeServer, err)
+	}
+	return result, nil
+}
+
+func decodeParams(params json.RawMessage, v any) *Error {
+	if len(params) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return newError(ErrCodeInvalidParams, err)
+	}
+	return nil
+}
+
+func writeJson(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println(err)
+	}
+}
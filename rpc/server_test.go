@@ -0,0 +1,210 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	bcnetwork "github.com/tusharjoshi4531/block-chain.git/bc_network"
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+	"github.com/tusharjoshi4531/block-chain.git/currency"
+	"github.com/tusharjoshi4531/block-chain.git/network"
+	"github.com/tusharjoshi4531/block-chain.git/tcp"
+)
+
+func TestRpcQueries(t *testing.T) {
+	node := createNode(t, "node")
+	assert.Nil(t, node.ConnectPeer(network.NewLocalTransport("peer")))
+
+	httpServer := httptest.NewServer(NewServer("", node).Handler())
+	defer httpServer.Close()
+
+	wallets := []string{}
+	assert.Nil(t, call(t, httpServer.URL, MethodListWallets, nil, &wallets))
+	assert.Equal(t, []string{"A", "B"}, wallets)
+
+	peers := []string{}
+	assert.Nil(t, call(t, httpServer.URL, MethodPeers, nil, &peers))
+	assert.Equal(t, []string{"peer"}, peers)
+
+	balance := &BalanceResult{}
+	assert.Nil(t, call(t, httpServer.URL, MethodGetBalance, WalletParams{Wallet: "A"}, balance))
+	assert.Equal(t, float64(1000), balance.Balance)
+
+	err := call(t, httpServer.URL, MethodGetBalance, WalletParams{Wallet: "C"}, balance)
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrCodeServer, err.Code)
+
+	tip := &BlockResult{}
+	assert.Nil(t, call(t, httpServer.URL, MethodGetTip, nil, tip))
+	assert.Equal(t, uint32(0), tip.Height)
+
+	err = call(t, httpServer.URL, "unknown", nil, nil)
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrCodeMethodNotFound, err.Code)
+}
+
+func TestRpcTransactAndMine(t *testing.T) {
+	node := createNode(t, "node")
+
+	httpServer := httptest.NewServer(NewServer("", node).Handler())
+	defer httpServer.Close()
+
+	// Pre-signed transaction from an external wallet key
+	transfer := currency.NewTransaction("A", "B", 150)
+	tx, err := transfer.ToCoreTransaction()
+	assert.Nil(t, err)
+	assert.Nil(t, tx.Sign(crypto.GeneratePrivateKey()))
+	txBytes, err := tx.Bytes()
+	assert.Nil(t, err)
+
+	sendResult := &SendTransactionResult{}
+	assert.Nil(t, call(t, httpServer.URL, MethodSendTransaction, SendTransactionParams{Transaction: hex.EncodeToString(txBytes)}, sendResult))
+	txHash := tx.Hash()
+	assert.Equal(t, txHash.String(), sendResult.Hash)
+
+	mempool := []TransactionResult{}
+	assert.Nil(t, call(t, httpServer.URL, MethodMempool, nil, &mempool))
+	assert.Equal(t, 1, len(mempool))
+	assert.Equal(t, "A", mempool[0].Transfer.From)
+
+	// Transaction claiming a different signer
+	tx.From = &crypto.GeneratePrivateKey().PublicKey
+	txBytes, err = tx.Bytes()
+	assert.Nil(t, err)
+	rpcErr := call(t, httpServer.URL, MethodSendTransaction, SendTransactionParams{Transaction: hex.EncodeToString(txBytes)}, nil)
+	assert.NotNil(t, rpcErr)
+	assert.Equal(t, ErrCodeInvalidParams, rpcErr.Code)
+
+	mined := &BlockResult{}
+	assert.Nil(t, call(t, httpServer.URL, MethodMine, MineParams{Wallet: "A"}, mined))
+	assert.Equal(t, uint32(1), mined.Height)
+	assert.Equal(t, 2, len(mined.Transactions))
+
+	tip := &BlockResult{}
+	assert.Nil(t, call(t, httpServer.URL, MethodGetTip, nil, tip))
+	assert.Equal(t, mined.Hash, tip.Hash)
+
+	byHash := &BlockResult{}
+	assert.Nil(t, call(t, httpServer.URL, MethodGetBlock, BlockParams{Hash: mined.Hash}, byHash))
+	assert.Equal(t, mined, byHash)
+
+	height := uint32(1)
+	byHeight := &BlockResult{}
+	assert.Nil(t, call(t, httpServer.URL, MethodGetBlock, BlockParams{Height: &height}, byHeight))
+	assert.Equal(t, mined, byHeight)
+
+	balance := &BalanceResult{}
+	assert.Nil(t, call(t, httpServer.URL, MethodGetBalance, WalletParams{Wallet: "B"}, balance))
+	assert.Equal(t, float64(1000+150), balance.Balance)
+}
+
+func TestRpcListUTXOs(t *testing.T) {
+	node := createNodeWithLedger(t, "node", currency.NewUTXOLedgerState())
+
+	httpServer := httptest.NewServer(NewServer("", node).Handler())
+	defer httpServer.Close()
+
+	utxos := []UTXOResult{}
+	assert.Nil(t, call(t, httpServer.URL, MethodListUTXOs, WalletParams{Wallet: "A"}, &utxos))
+	assert.Equal(t, 1, len(utxos))
+	assert.Equal(t, "A", utxos[0].Owner)
+	assert.Equal(t, float64(1000), utxos[0].Amount)
+
+	accountNode := createNode(t, "account")
+	accountServer := httptest.NewServer(NewServer("", accountNode).Handler())
+	defer accountServer.Close()
+
+	err := call(t, accountServer.URL, MethodListUTXOs, WalletParams{Wallet: "A"}, &utxos)
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrCodeServer, err.Code)
+}
+
+func TestRpcAssets(t *testing.T) {
+	node := createNode(t, "node")
+
+	httpServer := httptest.NewServer(NewServer("", node).Handler())
+	defer httpServer.Close()
+
+	send := func(transaction *currency.Transaction) {
+		tx, err := transaction.ToCoreTransaction()
+		assert.Nil(t, err)
+		assert.Nil(t, tx.Sign(crypto.GeneratePrivateKey()))
+		txBytes, err := tx.Bytes()
+		assert.Nil(t, err)
+		assert.Nil(t, call(t, httpServer.URL, MethodSendTransaction, SendTransactionParams{Transaction: hex.EncodeToString(txBytes)}, nil))
+		assert.Nil(t, call(t, httpServer.URL, MethodMine, MineParams{Wallet: "A"}, nil))
+	}
+	send(currency.NewIssueAssetTransaction("A", "GOLD", 500))
+	send(currency.NewAssetTransaction("A", "B", "GOLD", 200))
+
+	assets := []AssetResult{}
+	assert.Nil(t, call(t, httpServer.URL, MethodListAssets, nil, &assets))
+	assert.Equal(t, []AssetResult{{Id: "GOLD", Issuer: "A", Supply: 500}}, assets)
+
+	balances := []AssetBalanceResult{}
+	assert.Nil(t, call(t, httpServer.URL, MethodGetAssets, WalletParams{Wallet: "B"}, &balances))
+	assert.Equal(t, []AssetBalanceResult{{Asset: "GOLD", Balance: 200}}, balances)
+
+	tip := &BlockResult{}
+	assert.Nil(t, call(t, httpServer.URL, MethodGetTip, nil, tip))
+	assert.Equal(t, "GOLD", tip.Transactions[1].Transfer.Asset)
+
+	err := call(t, httpServer.URL, MethodGetAssets, WalletParams{Wallet: "C"}, &balances)
+	assert.NotNil(t, err)
+	assert.Equal(t, ErrCodeServer, err.Code)
+}
+
+func createNode(t *testing.T, address string) *tcp.TCPServer {
+	return createNodeWithLedger(t, address, currency.NewMemoryLedgerState())
+}
+
+func createNodeWithLedger(t *testing.T, address string, ledger currency.LedgerState) *tcp.TCPServer {
+	assert.Nil(t, ledger.AddWallet("A", 1000))
+	assert.Nil(t, ledger.AddWallet("B", 1000))
+	bc := currency.NewBlockChain(ledger, 1000)
+	txPool := core.NewDefaultTransactionPool()
+	privKey := crypto.GeneratePrivateKey()
+	bcTransport := bcnetwork.NewDefaultBlockChainTransport(
+		network.NewDefaultTransport(address),
+		bc,
+		txPool,
+	)
+
+	return tcp.NewTcpServer(ledger, bc, txPool, privKey, bcTransport)
+}
+
+func call(t *testing.T, url, method string, params any, result any) *Error {
+	req := map[string]any{
+		"jsonrpc": JsonRpcVersion,
+		"method":  method,
+		"id":      1,
+	}
+	if params != nil {
+		req["params"] = params
+	}
+	body, err := json.Marshal(req)
+	assert.Nil(t, err)
+
+	httpResp, err := http.Post(url, "application/json", bytes.NewBuffer(body))
+	assert.Nil(t, err)
+	defer httpResp.Body.Close()
+
+	resp := &struct {
+		Result json.RawMessage `json:"result"`
+		Error  *Error          `json:"error"`
+	}{}
+	assert.Nil(t, json.NewDecoder(httpResp.Body).Decode(resp))
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if result != nil {
+		assert.Nil(t, json.Unmarshal(resp.Result, result))
+	}
+	return nil
+}
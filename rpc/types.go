@@ -0,0 +1,178 @@
+package rpc
+
+import (
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/tusharjoshi4531/block-chain.git/anchor"
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+	"github.com/tusharjoshi4531/block-chain.git/currency"
+)
+
+const JsonRpcVersion = "2.0"
+
+const (
+	ErrCodeParse          = -32700
+	ErrCodeInvalidRequest = -32600
+	ErrCodeMethodNotFound = -32601
+	ErrCodeInvalidParams  = -32602
+	ErrCodeServer         = -32000
+)
+
+type Request struct {
+	JsonRpc string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Id      json.RawMessage `json:"id"`
+}
+
+type Response struct {
+	JsonRpc string          `json:"jsonrpc"`
+	Result  any             `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+	Id      json.RawMessage `json:"id"`
+}
+
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (err *Error) Error() string {
+	return err.Message
+}
+
+func newError(code int, err error) *Error {
+	return &Error{
+		Code:    code,
+		Message: err.Error(),
+	}
+}
+
+type BlockParams struct {
+	Hash   string  `json:"hash,omitempty"`
+	Height *uint32 `json:"height,omitempty"`
+}
+
+type WalletParams struct {
+	Wallet string `json:"wallet"`
+}
+
+type SendTransactionParams struct {
+	Transaction string `json:"transaction"`
+}
+
+type MineParams struct {
+	Wallet            string `json:"wallet"`
+	TransactionsLimit uint32 `json:"transactionsLimit,omitempty"`
+}
+
+type BlockResult struct {
+	Hash          string              `json:"hash"`
+	Height        uint32              `json:"height"`
+	PrevBlockHash string              `json:"prevBlockHash"`
+	DataHash      string              `json:"dataHash"`
+	Timestamp     int64               `json:"timestamp"`
+	Validator     string              `json:"validator"`
+	Transactions  []TransactionResult `json:"transactions"`
+}
+
+type TransactionResult struct {
+	Hash     string          `json:"hash"`
+	Data     string          `json:"data"`
+	From     string          `json:"from"`
+	Type     string          `json:"type,omitempty"`
+	Transfer *TransferResult `json:"transfer,omitempty"`
+	Anchor   *AnchorResult   `json:"anchor,omitempty"`
+}
+
+type TransferResult struct {
+	From   string  `json:"from"`
+	To     string  `json:"to"`
+	Amount float64 `json:"amount"`
+	Asset  string  `json:"asset,omitempty"`
+}
+
+type AnchorResult struct {
+	Hash     string `json:"hash"`
+	Metadata string `json:"metadata,omitempty"`
+}
+
+type BalanceResult struct {
+	Wallet  string  `json:"wallet"`
+	Balance float64 `json:"balance"`
+}
+
+type UTXOResult struct {
+	TxHash string  `json:"txHash"`
+	Index  uint32  `json:"index"`
+	Owner  string  `json:"owner"`
+	Amount float64 `json:"amount"`
+	Script string  `json:"script,omitempty"`
+}
+
+type AssetResult struct {
+	Id     string  `json:"id"`
+	Issuer string  `json:"issuer"`
+	Supply float64 `json:"supply"`
+}
+
+type AssetBalanceResult struct {
+	Asset   string  `json:"asset"`
+	Balance float64 `json:"balance"`
+}
+
+type SendTransactionResult struct {
+	Hash string `json:"hash"`
+}
+
+func NewBlockResult(block *core.Block) (*BlockResult, error) {
+	hash, err := block.Hash()
+	if err != nil {
+		return nil, err
+	}
+
+	transactions := make([]TransactionResult, 0, len(block.Transactions))
+	for _, tx := range block.Transactions {
+		transactions = append(transactions, *NewTransactionResult(tx))
+	}
+
+	return &BlockResult{
+		Hash:          hash.String(),
+		Height:        block.Header.Height,
+		PrevBlockHash: block.Header.PrevBlockHash.String(),
+		DataHash:      block.Header.DataHash.String(),
+		Timestamp:     block.Header.Timestamp,
+		Validator:     hex.EncodeToString(crypto.PublicKeyBytes(block.Validator)),
+		Transactions:  transactions,
+	}, nil
+}
+
+func NewTransactionResult(tx *core.Transaction) *TransactionResult {
+	hash := tx.Hash()
+	result := &TransactionResult{
+		Hash: hash.String(),
+		Data: hex.EncodeToString(tx.Data),
+		From: hex.EncodeToString(crypto.PublicKeyBytes(tx.From)),
+	}
+
+	if envelope, err := core.DecodeEnvelope(tx.Data); err == nil {
+		result.Type = core.TxTypeToString(envelope.Type)
+	}
+	if transfer, err := currency.NewTransactionFromCoreTransaction(tx); err == nil {
+		result.Transfer = &TransferResult{
+			From:   transfer.From,
+			To:     transfer.To,
+			Amount: transfer.Amount,
+			Asset:  transfer.Asset,
+		}
+	}
+	if docAnchor, err := anchor.DecodeAnchor(tx); err == nil {
+		result.Anchor = &AnchorResult{
+			Hash:     docAnchor.Hash.String(),
+			Metadata: docAnchor.Metadata,
+		}
+	}
+	return result
+}
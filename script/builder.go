@@ -0,0 +1,190 @@
+package script
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// MaxScriptSize is the largest script the interpreter accepts.
+const MaxScriptSize = 10000
+
+// maxNumberSize is the largest encoding of a number operand.
+const maxNumberSize = 8
+
+type instruction struct {
+	op   Opcode
+	data []byte
+}
+
+// Builder assembles a script, choosing the shortest push for each operand.
+type Builder struct {
+	script []byte
+}
+
+func NewBuilder() *Builder {
+	return &Builder{script: make([]byte, 0)}
+}
+
+func (builder *Builder) AddOp(op Opcode) *Builder {
+	builder.script = append(builder.script, byte(op))
+	return builder
+}
+
+func (builder *Builder) AddData(data []byte) *Builder {
+	switch {
+	case len(data) == 0:
+		builder.script = append(builder.script, byte(OpFalse))
+	case len(data) < int(OpPushData1):
+		builder.script = append(builder.script, byte(len(data)))
+	case len(data) <= 0xff:
+		builder.script = append(builder.script, byte(OpPushData1), byte(len(data)))
+	default:
+		builder.script = append(builder.script, byte(OpPushData2))
+		builder.script = binary.LittleEndian.AppendUint16(builder.script, uint16(len(data)))
+	}
+	builder.script = append(builder.script, data...)
+	return builder
+}
+
+func (builder *Builder) AddInt(n int64) *Builder {
+	if n == 0 {
+		return builder.AddOp(OpFalse)
+	}
+	if n >= 1 && n <= 16 {
+		return builder.AddOp(OpTrue + Opcode(n-1))
+	}
+	return builder.AddData(EncodeNumber(n))
+}
+
+func (builder *Builder) Script() []byte {
+	script := make([]byte, len(builder.script))
+	copy(script, builder.script)
+	return script
+}
+
+// EncodeNumber encodes n as a minimal little-endian magnitude whose last
+// byte carries the sign in its top bit. Zero is empty.
+func EncodeNumber(n int64) []byte {
+	if n == 0 {
+		return []byte{}
+	}
+
+	negative := n < 0
+	magnitude := uint64(n)
+	if negative {
+		magnitude = uint64(-n)
+	}
+
+	data := make([]byte, 0, maxNumberSize+1)
+	for magnitude > 0 {
+		data = append(data, byte(magnitude))
+		magnitude >>= 8
+	}
+	if data[len(data)-1]&0x80 != 0 {
+		data = append(data, 0)
+	}
+	if negative {
+		data[len(data)-1] |= 0x80
+	}
+	return data
+}
+
+func DecodeNumber(data []byte) (int64, error) {
+	if len(data) > maxNumberSize {
+		return 0, fmt.Errorf("number of (%d) bytes exceeds (%d) bytes", len(data), maxNumberSize)
+	}
+	if len(data) == 0 {
+		return 0, nil
+	}
+
+	magnitude := uint64(0)
+	for i := len(data) - 1; i >= 0; i-- {
+		b := data[i]
+		if i == len(data)-1 {
+			b &= 0x7f
+		}
+		magnitude = magnitude<<8 | uint64(b)
+	}
+	if data[len(data)-1]&0x80 != 0 {
+		return -int64(magnitude), nil
+	}
+	return int64(magnitude), nil
+}
+
+func parse(script []byte) ([]instruction, error) {
+	if len(script) > MaxScriptSize {
+		return nil, fmt.Errorf("script of (%d) bytes exceeds (%d) bytes", len(script), MaxScriptSize)
+	}
+
+	instructions := make([]instruction, 0)
+	for i := 0; i < len(script); {
+		op := Opcode(script[i])
+		i++
+
+		size := 0
+		switch {
+		case op.isDirectPush():
+			size = int(op)
+		case op == OpPushData1:
+			if i+1 > len(script) {
+				return nil, fmt.Errorf("truncated %s", op)
+			}
+			size = int(script[i])
+			i++
+		case op == OpPushData2:
+			if i+2 > len(script) {
+				return nil, fmt.Errorf("truncated %s", op)
+			}
+			size = int(binary.LittleEndian.Uint16(script[i:]))
+			i += 2
+		}
+		if i+size > len(script) {
+			return nil, fmt.Errorf("%s needs (%d) bytes; found (%d)", op, size, len(script)-i)
+		}
+
+		instructions = append(instructions, instruction{op: op, data: script[i : i+size]})
+		i += size
+	}
+	return instructions, nil
+}
+
+// Check fails if script cannot be parsed.
+func Check(script []byte) error {
+	_, err := parse(script)
+	return err
+}
+
+// IsPushOnly reports whether script parses and only pushes data, as unlock
+// scripts must.
+func IsPushOnly(script []byte) bool {
+	instructions, err := parse(script)
+	if err != nil {
+		return false
+	}
+	for _, inst := range instructions {
+		if !inst.op.isPush() {
+			return false
+		}
+	}
+	return true
+}
+
+// Disassemble renders script as opcode names with pushed data in hex.
+func Disassemble(script []byte) (string, error) {
+	instructions, err := parse(script)
+	if err != nil {
+		return "", err
+	}
+
+	words := make([]string, 0, len(instructions))
+	for _, inst := range instructions {
+		if inst.op.isDirectPush() || inst.op == OpPushData1 || inst.op == OpPushData2 {
+			words = append(words, hex.EncodeToString(inst.data))
+		} else {
+			words = append(words, inst.op.String())
+		}
+	}
+	return strings.Join(words, " "), nil
+}
@@ -0,0 +1,72 @@
+package script
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNumberEncoding(t *testing.T) {
+	numbers := map[int64][]byte{
+		0:    {},
+		1:    {0x01},
+		-1:   {0x81},
+		127:  {0x7f},
+		128:  {0x80, 0x00},
+		-128: {0x80, 0x80},
+		256:  {0x00, 0x01},
+	}
+	for n, encoding := range numbers {
+		assert.Equal(t, encoding, EncodeNumber(n))
+		decoded, err := DecodeNumber(encoding)
+		assert.Nil(t, err)
+		assert.Equal(t, n, decoded)
+	}
+
+	decoded, err := DecodeNumber(EncodeNumber(math.MaxInt64))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(math.MaxInt64), decoded)
+
+	_, err = DecodeNumber(make([]byte, maxNumberSize+1))
+	assert.NotNil(t, err)
+}
+
+func TestBuilder(t *testing.T) {
+	script := NewBuilder().
+		AddInt(0).
+		AddInt(16).
+		AddInt(1000).
+		AddData([]byte{0xab}).
+		AddData(bytes.Repeat([]byte{1}, 100)).
+		AddData(bytes.Repeat([]byte{2}, 300)).
+		AddOp(OpDup).
+		Script()
+
+	assert.Equal(t, byte(OpFalse), script[0])
+	assert.Equal(t, byte(Op16), script[1])
+	assert.Equal(t, []byte{0x02, 0xe8, 0x03}, script[2:5])
+	assert.Equal(t, []byte{0x01, 0xab}, script[5:7])
+	assert.Equal(t, []byte{byte(OpPushData1), 100}, script[7:9])
+	assert.Equal(t, []byte{byte(OpPushData2), 0x2c, 0x01}, script[109:112])
+
+	instructions, err := parse(script)
+	assert.Nil(t, err)
+	assert.Equal(t, 7, len(instructions))
+	assert.Equal(t, 300, len(instructions[5].data))
+	assert.False(t, IsPushOnly(script))
+	assert.True(t, IsPushOnly(script[:len(script)-1]))
+
+	// Pushes running past the end of the script
+	assert.NotNil(t, Check([]byte{0x05, 0x01}))
+	assert.NotNil(t, Check([]byte{byte(OpPushData2), 0x01}))
+	assert.NotNil(t, Check(make([]byte, MaxScriptSize+1)))
+}
+
+func TestDisassemble(t *testing.T) {
+	script := NewBuilder().AddOp(OpSha256).AddData([]byte{0xca, 0xfe}).AddOp(OpEqual).AddInt(3).Script()
+	text, err := Disassemble(script)
+	assert.Nil(t, err)
+	assert.Equal(t, "OP_SHA256 cafe OP_EQUAL OP_3", text)
+}
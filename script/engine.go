@@ -0,0 +1,359 @@
+package script
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+	"github.com/tusharjoshi4531/block-chain.git/types"
+)
+
+var (
+	ErrStepLimit    = errors.New("script exceeds its step limit")
+	ErrGasLimit     = errors.New("script exceeds its gas limit")
+	ErrScriptFailed = errors.New("script did not end with a true value")
+)
+
+// Context is what a script can observe of the spend: the hash signatures
+// must sign, and the height and timestamp of the block including it.
+type Context struct {
+	SigHash   types.Hash
+	Height    uint32
+	Timestamp int64
+}
+
+// Limits bounds the work of one evaluation. Every opcode read counts as a
+// step, executed or not, and executed opcodes are charged gas.
+type Limits struct {
+	MaxSteps     int
+	MaxGas       uint64
+	MaxStackSize int
+}
+
+var DefaultLimits = Limits{
+	MaxSteps:     1000,
+	MaxGas:       2000,
+	MaxStackSize: 100,
+}
+
+type engine struct {
+	ctx    Context
+	limits Limits
+	stack  [][]byte
+	steps  int
+	gas    uint64
+}
+
+// Verify runs the unlock script and then the lock script on the stack it
+// leaves. The unlock script may only push data, and the spend is allowed
+// if the lock script ends with a true value on top of the stack.
+func Verify(unlock, lock []byte, ctx Context, limits Limits) error {
+	if !IsPushOnly(unlock) {
+		return fmt.Errorf("unlock script must only push data")
+	}
+
+	engine := &engine{
+		ctx:    ctx,
+		limits: limits,
+		stack:  make([][]byte, 0),
+	}
+	if err := engine.run(unlock); err != nil {
+		return err
+	}
+	if err := engine.run(lock); err != nil {
+		return err
+	}
+
+	if len(engine.stack) == 0 || !asBool(engine.stack[len(engine.stack)-1]) {
+		return ErrScriptFailed
+	}
+	return nil
+}
+
+func (engine *engine) run(script []byte) error {
+	instructions, err := parse(script)
+	if err != nil {
+		return err
+	}
+
+	conditions := make([]bool, 0)
+	for _, inst := range instructions {
+		engine.steps++
+		if engine.steps > engine.limits.MaxSteps {
+			return ErrStepLimit
+		}
+
+		executing := true
+		for _, condition := range conditions {
+			executing = executing && condition
+		}
+
+		switch inst.op {
+		case OpIf, OpNotIf:
+			value := false
+			if executing {
+				top, err := engine.pop()
+				if err != nil {
+					return err
+				}
+				value = asBool(top) == (inst.op == OpIf)
+			}
+			conditions = append(conditions, value)
+			continue
+		case OpElse:
+			if len(conditions) == 0 {
+				return fmt.Errorf("%s without %s", OpElse, OpIf)
+			}
+			conditions[len(conditions)-1] = !conditions[len(conditions)-1]
+			continue
+		case OpEndIf:
+			if len(conditions) == 0 {
+				return fmt.Errorf("%s without %s", OpEndIf, OpIf)
+			}
+			conditions = conditions[:len(conditions)-1]
+			continue
+		}
+		if !executing {
+			continue
+		}
+
+		engine.gas += inst.op.gas()
+		if engine.gas > engine.limits.MaxGas {
+			return ErrGasLimit
+		}
+		if err := engine.execute(inst); err != nil {
+			return err
+		}
+		if len(engine.stack) > engine.limits.MaxStackSize {
+			return fmt.Errorf("stack exceeds (%d) items", engine.limits.MaxStackSize)
+		}
+	}
+
+	if len(conditions) != 0 {
+		return fmt.Errorf("%s without %s", OpIf, OpEndIf)
+	}
+	return nil
+}
+
+func (engine *engine) execute(inst instruction) error {
+	switch op := inst.op; {
+	case op.isPush():
+		if op.isSmallInt() {
+			engine.push(EncodeNumber(int64(op-OpTrue) + 1))
+		} else {
+			engine.push(inst.data)
+		}
+		return nil
+
+	case op == OpNop:
+		return nil
+
+	case op == OpVerify:
+		return engine.verify(op)
+
+	case op == OpReturn:
+		return fmt.Errorf("script executed %s", op)
+
+	case op == OpDrop:
+		_, err := engine.pop()
+		return err
+
+	case op == OpDup:
+		top, err := engine.peek()
+		if err != nil {
+			return err
+		}
+		engine.push(top)
+		return nil
+
+	case op == OpSwap:
+		a, err := engine.pop()
+		if err != nil {
+			return err
+		}
+		b, err := engine.pop()
+		if err != nil {
+			return err
+		}
+		engine.push(a)
+		engine.push(b)
+		return nil
+
+	case op == OpSize:
+		top, err := engine.peek()
+		if err != nil {
+			return err
+		}
+		engine.push(EncodeNumber(int64(len(top))))
+		return nil
+
+	case op == OpEqual, op == OpEqualVerify:
+		a, err := engine.pop()
+		if err != nil {
+			return err
+		}
+		b, err := engine.pop()
+		if err != nil {
+			return err
+		}
+		engine.pushBool(bytes.Equal(a, b))
+		if op == OpEqualVerify {
+			return engine.verify(op)
+		}
+		return nil
+
+	case op == OpAdd, op == OpSub, op == OpNumEqual, op == OpLessThan, op == OpGreaterThan:
+		b, err := engine.popNumber()
+		if err != nil {
+			return err
+		}
+		a, err := engine.popNumber()
+		if err != nil {
+			return err
+		}
+		switch op {
+		case OpAdd:
+			engine.push(EncodeNumber(a + b))
+		case OpSub:
+			engine.push(EncodeNumber(a - b))
+		case OpNumEqual:
+			engine.pushBool(a == b)
+		case OpLessThan:
+			engine.pushBool(a < b)
+		case OpGreaterThan:
+			engine.pushBool(a > b)
+		}
+		return nil
+
+	case op == OpSha256:
+		top, err := engine.pop()
+		if err != nil {
+			return err
+		}
+		hash := sha256.Sum256(top)
+		engine.push(hash[:])
+		return nil
+
+	case op == OpCheckSig, op == OpCheckSigVerify:
+		keyBytes, err := engine.pop()
+		if err != nil {
+			return err
+		}
+		sigBytes, err := engine.pop()
+		if err != nil {
+			return err
+		}
+		engine.pushBool(engine.checkSig(sigBytes, keyBytes))
+		if op == OpCheckSigVerify {
+			return engine.verify(op)
+		}
+		return nil
+
+	case op == OpCheckLockHeightVerify, op == OpCheckLockTimeVerify:
+		top, err := engine.peek()
+		if err != nil {
+			return err
+		}
+		lock, err := DecodeNumber(top)
+		if err != nil {
+			return err
+		}
+		if lock < 0 {
+			return fmt.Errorf("%s with negative lock (%d)", op, lock)
+		}
+		current := int64(engine.ctx.Height)
+		if op == OpCheckLockTimeVerify {
+			current = engine.ctx.Timestamp
+		}
+		if current < lock {
+			return fmt.Errorf("%s: locked until (%d); spent at (%d)", op, lock, current)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown opcode (%s)", op)
+	}
+}
+
+// checkSig reports whether sigBytes is a valid signature of the sighash by
+// keyBytes. Malformed keys and signatures do not verify.
+func (engine *engine) checkSig(sigBytes, keyBytes []byte) bool {
+	key, err := crypto.PublicKeyFromBytes(keyBytes)
+	if err != nil {
+		return false
+	}
+	sig := &crypto.Signature{}
+	if err := sig.Decode(bytes.NewReader(sigBytes)); err != nil {
+		return false
+	}
+	return sig.Verify(key, engine.ctx.SigHash[:])
+}
+
+func (engine *engine) verify(op Opcode) error {
+	top, err := engine.pop()
+	if err != nil {
+		return err
+	}
+	if !asBool(top) {
+		return fmt.Errorf("%s failed", op)
+	}
+	return nil
+}
+
+func (engine *engine) push(data []byte) {
+	engine.stack = append(engine.stack, data)
+}
+
+func (engine *engine) pushBool(value bool) {
+	if value {
+		engine.push([]byte{1})
+	} else {
+		engine.push([]byte{})
+	}
+}
+
+func (engine *engine) peek() ([]byte, error) {
+	if len(engine.stack) == 0 {
+		return nil, fmt.Errorf("stack is empty")
+	}
+	return engine.stack[len(engine.stack)-1], nil
+}
+
+func (engine *engine) pop() ([]byte, error) {
+	top, err := engine.peek()
+	if err != nil {
+		return nil, err
+	}
+	engine.stack = engine.stack[:len(engine.stack)-1]
+	return top, nil
+}
+
+func (engine *engine) popNumber() (int64, error) {
+	top, err := engine.pop()
+	if err != nil {
+		return 0, err
+	}
+	return DecodeNumber(top)
+}
+
+// asBool is false for empty data, zeros and negative zero.
+func asBool(data []byte) bool {
+	for i, b := range data {
+		if b != 0 {
+			return !(i == len(data)-1 && b == 0x80)
+		}
+	}
+	return false
+}
+
+// SignatureBytes signs sigHash for a CheckSig in an unlock script.
+func SignatureBytes(privKey *ecdsa.PrivateKey, sigHash types.Hash) ([]byte, error) {
+	sig, err := crypto.SignBytes(privKey, sigHash[:])
+	if err != nil {
+		return nil, err
+	}
+	return sig.Bytes()
+}
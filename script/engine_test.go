@@ -0,0 +1,92 @@
+package script
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+	"github.com/tusharjoshi4531/block-chain.git/types"
+)
+
+func TestVerifyStackOps(t *testing.T) {
+	verify := func(unlock, lock []byte) error {
+		return Verify(unlock, lock, Context{}, DefaultLimits)
+	}
+
+	// 2 + 3 == 5
+	assert.Nil(t, verify(
+		NewBuilder().AddInt(2).AddInt(3).Script(),
+		NewBuilder().AddOp(OpAdd).AddInt(5).AddOp(OpNumEqual).Script(),
+	))
+	assert.Equal(t, ErrScriptFailed, verify(
+		NewBuilder().AddInt(2).AddInt(2).Script(),
+		NewBuilder().AddOp(OpAdd).AddInt(5).AddOp(OpNumEqual).Script(),
+	))
+
+	// Branches follow the value on the stack
+	branch := NewBuilder().
+		AddOp(OpIf).AddInt(7).AddOp(OpElse).AddInt(8).AddOp(OpEndIf).
+		AddInt(8).AddOp(OpEqual).
+		Script()
+	assert.Nil(t, verify(NewBuilder().AddInt(0).Script(), branch))
+	assert.NotNil(t, verify(NewBuilder().AddInt(1).Script(), branch))
+
+	assert.NotNil(t, verify(nil, NewBuilder().AddInt(1).AddOp(OpIf).Script()))
+	assert.NotNil(t, verify(nil, NewBuilder().AddOp(OpEndIf).Script()))
+	assert.NotNil(t, verify(nil, NewBuilder().AddOp(OpDrop).Script()))
+	assert.NotNil(t, verify(nil, NewBuilder().AddInt(1).AddOp(OpReturn).Script()))
+	assert.NotNil(t, verify(nil, []byte{0xff}))
+
+	// Unlock scripts may only push data
+	assert.NotNil(t, verify(NewBuilder().AddInt(1).AddOp(OpDup).Script(), NewBuilder().AddOp(OpTrue).Script()))
+}
+
+func TestVerifyLimits(t *testing.T) {
+	loop := NewBuilder()
+	for i := 0; i < 20; i++ {
+		loop.AddOp(OpNop)
+	}
+	loop.AddInt(1)
+
+	assert.Nil(t, Verify(nil, loop.Script(), Context{}, DefaultLimits))
+	assert.Equal(t, ErrStepLimit, Verify(nil, loop.Script(), Context{}, Limits{MaxSteps: 10, MaxGas: 100, MaxStackSize: 10}))
+	assert.Equal(t, ErrGasLimit, Verify(nil, loop.Script(), Context{}, Limits{MaxSteps: 100, MaxGas: 10, MaxStackSize: 10}))
+
+	// Signature checks cost more than other opcodes
+	checkSig := NewBuilder().AddData([]byte{1}).AddData([]byte{2}).AddOp(OpCheckSig).AddOp(OpDrop).AddInt(1).Script()
+	assert.Equal(t, ErrGasLimit, Verify(nil, checkSig, Context{}, Limits{MaxSteps: 100, MaxGas: 50, MaxStackSize: 10}))
+
+	deep := NewBuilder()
+	for i := 0; i < 5; i++ {
+		deep.AddInt(1)
+	}
+	assert.NotNil(t, Verify(nil, deep.Script(), Context{}, Limits{MaxSteps: 100, MaxGas: 100, MaxStackSize: 4}))
+}
+
+func TestVerifySignatureAndLocks(t *testing.T) {
+	privKey := crypto.GeneratePrivateKey()
+	ctx := Context{SigHash: types.Hash{1, 2, 3}, Height: 10, Timestamp: 5000}
+	sig, err := SignatureBytes(privKey, ctx.SigHash)
+	assert.Nil(t, err)
+
+	payToKey := NewBuilder().AddData(crypto.PublicKeyBytes(&privKey.PublicKey)).AddOp(OpCheckSig).Script()
+	assert.Nil(t, Verify(NewBuilder().AddData(sig).Script(), payToKey, ctx, DefaultLimits))
+
+	otherCtx := ctx
+	otherCtx.SigHash = types.Hash{4}
+	assert.Equal(t, ErrScriptFailed, Verify(NewBuilder().AddData(sig).Script(), payToKey, otherCtx, DefaultLimits))
+	assert.Equal(t, ErrScriptFailed, Verify(NewBuilder().AddData([]byte{1}).Script(), payToKey, ctx, DefaultLimits))
+
+	heightLock := func(height int64) []byte {
+		return NewBuilder().AddInt(height).AddOp(OpCheckLockHeightVerify).Script()
+	}
+	assert.Nil(t, Verify(nil, heightLock(10), ctx, DefaultLimits))
+	assert.NotNil(t, Verify(nil, heightLock(11), ctx, DefaultLimits))
+	assert.NotNil(t, Verify(nil, heightLock(-1), ctx, DefaultLimits))
+
+	timeLock := func(timestamp int64) []byte {
+		return NewBuilder().AddInt(timestamp).AddOp(OpCheckLockTimeVerify).Script()
+	}
+	assert.Nil(t, Verify(nil, timeLock(5000), ctx, DefaultLimits))
+	assert.NotNil(t, Verify(nil, timeLock(5001), ctx, DefaultLimits))
+}
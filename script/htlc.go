@@ -0,0 +1,42 @@
+package script
+
+import (
+	"crypto/ecdsa"
+
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+	"github.com/tusharjoshi4531/block-chain.git/types"
+)
+
+// NewHTLC builds a hash time-locked contract. The recipient can spend it
+// with a signature and the preimage of hash; from height timeout on, the
+// refund key can spend it with a signature alone.
+func NewHTLC(hash types.Hash, recipient, refund *ecdsa.PublicKey, timeout uint32) []byte {
+	return NewBuilder().
+		AddOp(OpIf).
+		AddOp(OpSha256).AddData(hash[:]).AddOp(OpEqualVerify).
+		AddData(crypto.PublicKeyBytes(recipient)).AddOp(OpCheckSig).
+		AddOp(OpElse).
+		AddInt(int64(timeout)).AddOp(OpCheckLockHeightVerify).AddOp(OpDrop).
+		AddData(crypto.PublicKeyBytes(refund)).AddOp(OpCheckSig).
+		AddOp(OpEndIf).
+		Script()
+}
+
+// HTLCClaim is the unlock script revealing preimage to spend an HTLC.
+func HTLCClaim(sig, preimage []byte) []byte {
+	return NewBuilder().AddData(sig).AddData(preimage).AddOp(OpTrue).Script()
+}
+
+// HTLCRefund is the unlock script taking an HTLC back after its timeout.
+func HTLCRefund(sig []byte) []byte {
+	return NewBuilder().AddData(sig).AddOp(OpFalse).Script()
+}
+
+// HTLCPreimage returns the preimage revealed by a claim script.
+func HTLCPreimage(claim []byte) ([]byte, bool) {
+	instructions, err := parse(claim)
+	if err != nil || len(instructions) != 3 || instructions[2].op != OpTrue {
+		return nil, false
+	}
+	return instructions[1].data, true
+}
@@ -0,0 +1,40 @@
+package script
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
+	"github.com/tusharjoshi4531/block-chain.git/types"
+)
+
+func TestHTLC(t *testing.T) {
+	recipient := crypto.GeneratePrivateKey()
+	refund := crypto.GeneratePrivateKey()
+	preimage := []byte("secret")
+	lock := NewHTLC(sha256.Sum256(preimage), &recipient.PublicKey, &refund.PublicKey, 20)
+
+	ctx := Context{SigHash: types.Hash{9}, Height: 5}
+	recipientSig, err := SignatureBytes(recipient, ctx.SigHash)
+	assert.Nil(t, err)
+	refundSig, err := SignatureBytes(refund, ctx.SigHash)
+	assert.Nil(t, err)
+
+	claim := HTLCClaim(recipientSig, preimage)
+	assert.Nil(t, Verify(claim, lock, ctx, DefaultLimits))
+	revealed, ok := HTLCPreimage(claim)
+	assert.True(t, ok)
+	assert.Equal(t, preimage, revealed)
+
+	assert.NotNil(t, Verify(HTLCClaim(recipientSig, []byte("guess")), lock, ctx, DefaultLimits))
+	assert.NotNil(t, Verify(HTLCClaim(refundSig, preimage), lock, ctx, DefaultLimits))
+
+	// The refund only opens at the timeout
+	assert.NotNil(t, Verify(HTLCRefund(refundSig), lock, ctx, DefaultLimits))
+	ctx.Height = 20
+	refundSig, err = SignatureBytes(refund, ctx.SigHash)
+	assert.Nil(t, err)
+	assert.Nil(t, Verify(HTLCRefund(refundSig), lock, ctx, DefaultLimits))
+	assert.NotNil(t, Verify(HTLCRefund(recipientSig), lock, ctx, DefaultLimits))
+}
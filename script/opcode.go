@@ -0,0 +1,117 @@
+package script
+
+import "fmt"
+
+// Opcode is a single instruction of a script. Opcodes below OpPushData1
+// push the next Opcode bytes of the script.
+type Opcode byte
+
+const (
+	OpFalse     Opcode = 0x00
+	OpPushData1 Opcode = 0x4c
+	OpPushData2 Opcode = 0x4d
+	OpTrue      Opcode = 0x51
+	Op16        Opcode = 0x60
+	OpNop       Opcode = 0x61
+
+	OpIf     Opcode = 0x63
+	OpNotIf  Opcode = 0x64
+	OpElse   Opcode = 0x67
+	OpEndIf  Opcode = 0x68
+	OpVerify Opcode = 0x69
+	OpReturn Opcode = 0x6a
+
+	OpDrop Opcode = 0x75
+	OpDup  Opcode = 0x76
+	OpSwap Opcode = 0x7c
+	OpSize Opcode = 0x82
+
+	OpEqual       Opcode = 0x87
+	OpEqualVerify Opcode = 0x88
+
+	OpAdd         Opcode = 0x93
+	OpSub         Opcode = 0x94
+	OpNumEqual    Opcode = 0x9c
+	OpLessThan    Opcode = 0x9f
+	OpGreaterThan Opcode = 0xa0
+
+	OpSha256         Opcode = 0xa8
+	OpCheckSig       Opcode = 0xac
+	OpCheckSigVerify Opcode = 0xad
+
+	// OpCheckLockHeightVerify fails unless the spending block is at least
+	// at the height on top of the stack; OpCheckLockTimeVerify does the
+	// same for the block timestamp. Both leave the stack unchanged.
+	OpCheckLockHeightVerify Opcode = 0xb1
+	OpCheckLockTimeVerify   Opcode = 0xb2
+)
+
+var opcodeNames = map[Opcode]string{
+	OpFalse:                 "OP_FALSE",
+	OpPushData1:             "OP_PUSHDATA1",
+	OpPushData2:             "OP_PUSHDATA2",
+	OpNop:                   "OP_NOP",
+	OpIf:                    "OP_IF",
+	OpNotIf:                 "OP_NOTIF",
+	OpElse:                  "OP_ELSE",
+	OpEndIf:                 "OP_ENDIF",
+	OpVerify:                "OP_VERIFY",
+	OpReturn:                "OP_RETURN",
+	OpDrop:                  "OP_DROP",
+	OpDup:                   "OP_DUP",
+	OpSwap:                  "OP_SWAP",
+	OpSize:                  "OP_SIZE",
+	OpEqual:                 "OP_EQUAL",
+	OpEqualVerify:           "OP_EQUALVERIFY",
+	OpAdd:                   "OP_ADD",
+	OpSub:                   "OP_SUB",
+	OpNumEqual:              "OP_NUMEQUAL",
+	OpLessThan:              "OP_LESSTHAN",
+	OpGreaterThan:           "OP_GREATERTHAN",
+	OpSha256:                "OP_SHA256",
+	OpCheckSig:              "OP_CHECKSIG",
+	OpCheckSigVerify:        "OP_CHECKSIGVERIFY",
+	OpCheckLockHeightVerify: "OP_CHECKLOCKHEIGHTVERIFY",
+	OpCheckLockTimeVerify:   "OP_CHECKLOCKTIMEVERIFY",
+}
+
+// opcodeGas is the gas charged for executing an opcode; opcodes not listed
+// cost one.
+var opcodeGas = map[Opcode]uint64{
+	OpSha256:         10,
+	OpCheckSig:       100,
+	OpCheckSigVerify: 100,
+}
+
+func (op Opcode) String() string {
+	if name, ok := opcodeNames[op]; ok {
+		return name
+	}
+	if op.isSmallInt() {
+		return fmt.Sprintf("OP_%d", op-OpTrue+1)
+	}
+	if op.isDirectPush() {
+		return fmt.Sprintf("OP_PUSH%d", op)
+	}
+	return fmt.Sprintf("OP_UNKNOWN%d", op)
+}
+
+func (op Opcode) isDirectPush() bool {
+	return op > OpFalse && op < OpPushData1
+}
+
+func (op Opcode) isSmallInt() bool {
+	return op >= OpTrue && op <= Op16
+}
+
+// isPush reports whether op only pushes data or a small number.
+func (op Opcode) isPush() bool {
+	return op <= OpPushData2 || op.isSmallInt()
+}
+
+func (op Opcode) gas() uint64 {
+	if gas, ok := opcodeGas[op]; ok {
+		return gas
+	}
+	return 1
+}
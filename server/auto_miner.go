@@ -0,0 +1,189 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/prot"
+	"github.com/tusharjoshi4531/block-chain.git/types"
+)
+
+const (
+	DefaultAutoMineTransactionsLimit = 10
+	autoMineRetryDelay               = time.Second
+)
+
+type AutoMineConfig struct {
+	MinerWalletId     string
+	TransactionsLimit uint32
+	// MinTransactions is how many pooled transactions to wait for before
+	// mining a block.
+	MinTransactions int
+	// Interval is the least time between the starts of two blocks.
+	Interval time.Duration
+}
+
+type AutoMineStatus struct {
+	Running     bool
+	Config      AutoMineConfig
+	BlocksMined uint64
+	LastBlock   types.Hash
+	LastError   error
+}
+
+type autoMiner struct {
+	mu     sync.RWMutex
+	status AutoMineStatus
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// StartAutoMine mines, connects and announces blocks in the background
+// until StopAutoMine is called. Mining restarts on the new tip whenever a
+// peer's block arrives first.
+func (server *DefaultBlockChainServer) StartAutoMine(config AutoMineConfig) error {
+	miner := &server.autoMiner
+	miner.mu.Lock()
+	defer miner.mu.Unlock()
+
+	if miner.status.Running {
+		return fmt.Errorf("auto mining is already running")
+	}
+	if config.TransactionsLimit == 0 {
+		config.TransactionsLimit = DefaultAutoMineTransactionsLimit
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	miner.status = AutoMineStatus{Running: true, Config: config}
+	miner.cancel = cancel
+	miner.done = make(chan struct{})
+
+	go server.autoMine(ctx, config, miner.done)
+	return nil
+}
+
+func (server *DefaultBlockChainServer) StopAutoMine() error {
+	miner := &server.autoMiner
+	miner.mu.Lock()
+	if !miner.status.Running {
+		miner.mu.Unlock()
+		return fmt.Errorf("auto mining is not running")
+	}
+	miner.cancel()
+	done := miner.done
+	miner.mu.Unlock()
+
+	<-done
+
+	miner.mu.Lock()
+	miner.status.Running = false
+	miner.mu.Unlock()
+	return nil
+}
+
+func (server *DefaultBlockChainServer) AutoMineStatus() AutoMineStatus {
+	miner := &server.autoMiner
+	miner.mu.RLock()
+	defer miner.mu.RUnlock()
+	return miner.status
+}
+
+func (server *DefaultBlockChainServer) autoMine(ctx context.Context, config AutoMineConfig, done chan struct{}) {
+	defer close(done)
+
+	poolEvents := server.transactionPool.Subscribe(256, core.EventTransactionAdded)
+	defer poolEvents.Unsubscribe()
+
+	for {
+		started := time.Now()
+		if !server.waitForTransactions(ctx, poolEvents, config.MinTransactions) {
+			return
+		}
+
+		block, err := server.MineAndAnnounceContext(ctx, config.TransactionsLimit, config.MinerWalletId)
+		if ctx.Err() != nil {
+			if err == nil {
+				server.recordAutoMine(block, err)
+			}
+			return
+		}
+		server.recordAutoMine(block, err)
+
+		delay := time.Duration(0)
+		switch {
+		case errors.Is(err, prot.ErrTipChanged):
+		case err != nil:
+			log.Printf("auto mine: %s", err)
+			delay = autoMineRetryDelay
+		default:
+			delay = config.Interval - time.Since(started)
+		}
+		if delay > 0 && !sleepContext(ctx, delay) {
+			return
+		}
+	}
+}
+
+func (server *DefaultBlockChainServer) waitForTransactions(ctx context.Context, poolEvents *core.Subscription, minTransactions int) bool {
+	for server.pendingTransactions() < minTransactions {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-poolEvents.Events():
+		}
+	}
+	return ctx.Err() == nil
+}
+
+// pendingTransactions counts pooled transactions that are not yet in the
+// main chain. The pool drops mined transactions asynchronously, so its
+// length alone would lag behind the chain.
+func (server *DefaultBlockChainServer) pendingTransactions() int {
+	server.chainMu.Lock()
+	defer server.chainMu.Unlock()
+
+	tipHash, err := server.blockChain.GetHeighestBlock().Hash()
+	if err != nil {
+		return 0
+	}
+
+	pending := 0
+	for _, tx := range server.transactionPool.Transactions() {
+		if server.blockChain.HasTransactionInChain(tx.Hash(), tipHash) != nil {
+			pending++
+		}
+	}
+	return pending
+}
+
+func (server *DefaultBlockChainServer) recordAutoMine(block *core.Block, err error) {
+	miner := &server.autoMiner
+	miner.mu.Lock()
+	defer miner.mu.Unlock()
+
+	miner.status.LastError = err
+	if err != nil {
+		return
+	}
+	if hash, hashErr := block.Hash(); hashErr == nil {
+		miner.status.BlocksMined++
+		miner.status.LastBlock = hash
+	}
+}
+
+func sleepContext(ctx context.Context, delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"crypto/ecdsa"
 	"fmt"
 	"sync"
@@ -32,7 +33,13 @@ type DefaultBlockChainServer struct {
 	transactionPool core.TransactionPool
 	running         bool
 	privKey         *ecdsa.PrivateKey
+	chainEvents     *core.Subscription
+	autoMiner       autoMiner
 	mu              sync.RWMutex
+	// chainMu serializes everything that reads or changes the chain, the
+	// ledger behind it and the pool: peer messages, mining, submitted
+	// blocks and callers such as the rpc server.
+	chainMu sync.Mutex
 }
 
 func NewDefaultBlockChainServer(
@@ -62,6 +69,12 @@ func (server *DefaultBlockChainServer) ConnectPeer(transport network.TransportIn
 
 func (server *DefaultBlockChainServer) Listen() {
 	server.running = true
+	server.chainEvents = server.blockChain.Subscribe(
+		256,
+		core.EventBlockConnected,
+		core.EventBlockDisconnected,
+	)
+	go server.syncPoolWithChain(server.chainEvents)
 
 	go func() {
 		for {
@@ -80,7 +93,9 @@ func (server *DefaultBlockChainServer) Listen() {
 				continue
 			}
 
+			server.chainMu.Lock()
 			err = server.ProcessMessage(recPayload, recMsg.From)
+			server.chainMu.Unlock()
 			if err != nil {
 				fmt.Println("Error: ", err.Error())
 			}
@@ -89,10 +104,122 @@ func (server *DefaultBlockChainServer) Listen() {
 }
 
 func (server *DefaultBlockChainServer) Kill() {
+	if server.AutoMineStatus().Running {
+		server.StopAutoMine()
+	}
+
 	server.mu.Lock()
 	defer server.mu.Unlock()
 
 	server.running = false
+	if server.chainEvents != nil {
+		server.chainEvents.Unsubscribe()
+		server.chainEvents = nil
+	}
+}
+
+// syncPoolWithChain drops transactions from the pool once they are in the
+// main chain and returns them to the pool when their block is disconnected.
+func (server *DefaultBlockChainServer) syncPoolWithChain(sub *core.Subscription) {
+	for event := range sub.Events() {
+		server.chainMu.Lock()
+		switch event.Type {
+		case core.EventBlockConnected:
+			for _, transaction := range event.Block.Transactions {
+				server.transactionPool.RemoveTransaction(transaction.Hash())
+			}
+		case core.EventBlockDisconnected:
+			for _, transaction := range event.Block.Transactions {
+				server.transactionPool.AddTransaction(transaction)
+			}
+		}
+		server.chainMu.Unlock()
+	}
+}
+
+// ChainLock returns the lock that must be held while reading or changing
+// the chain, the ledger or the pool from outside the server's own loop.
+// The server's own methods take it themselves.
+func (server *DefaultBlockChainServer) ChainLock() sync.Locker {
+	return &server.chainMu
+}
+
+// MineAndAnnounce mines a signed block on the current tip, connects it to the
+// local chain and announces the new chain to peers.
+func (server *DefaultBlockChainServer) MineAndAnnounce(transactionsLimit uint32, minerWalletId string) (*core.Block, error) {
+	return server.MineAndAnnounceContext(context.Background(), transactionsLimit, minerWalletId)
+}
+
+// MineAndAnnounceContext is MineAndAnnounce with a context that can cancel
+// miners implementing prot.ContextMiner. A prot.SealMiner searches for its
+// seal without holding the chain lock.
+func (server *DefaultBlockChainServer) MineAndAnnounceContext(ctx context.Context, transactionsLimit uint32, minerWalletId string) (*core.Block, error) {
+	if miner, ok := server.Miner.(prot.SealMiner); ok {
+		return server.mineSealed(ctx, miner, transactionsLimit, minerWalletId)
+	}
+
+	server.chainMu.Lock()
+	defer server.chainMu.Unlock()
+
+	var block *core.Block
+	var err error
+	if miner, ok := server.Miner.(prot.ContextMiner); ok {
+		block, err = miner.MineBlockContext(ctx, transactionsLimit, minerWalletId)
+	} else {
+		block, err = server.MineBlock(transactionsLimit, minerWalletId)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := server.connectAndAnnounce(block); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+func (server *DefaultBlockChainServer) mineSealed(ctx context.Context, miner prot.SealMiner, transactionsLimit uint32, minerWalletId string) (*core.Block, error) {
+	tipEvents := server.blockChain.Subscribe(16, core.EventTipChanged)
+	defer tipEvents.Unsubscribe()
+
+	server.chainMu.Lock()
+	block, err := miner.BlockTemplate(transactionsLimit, minerWalletId)
+	server.chainMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	sealCtx, cancel := prot.WatchTip(ctx, tipEvents, block.Header.PrevBlockHash)
+	defer cancel()
+	if err := miner.SealBlockContext(sealCtx, block); err != nil {
+		return nil, err
+	}
+
+	server.chainMu.Lock()
+	defer server.chainMu.Unlock()
+	if err := server.connectAndAnnounce(block); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// SubmitBlock validates a block produced outside this server, connects it
+// to the local chain and announces the new chain to peers.
+func (server *DefaultBlockChainServer) SubmitBlock(block *core.Block) error {
+	server.chainMu.Lock()
+	defer server.chainMu.Unlock()
+
+	if err := server.ValidateBlock(block); err != nil {
+		return err
+	}
+	return server.connectAndAnnounce(block)
+}
+
+func (server *DefaultBlockChainServer) connectAndAnnounce(block *core.Block) error {
+	if err := server.blockChain.AddBlock(block); err != nil {
+		return err
+	}
+	return server.BroadcastHashChain()
 }
 
 func (server *DefaultBlockChainServer) PrivKey() *ecdsa.PrivateKey {
@@ -29,7 +29,7 @@ func NewSimpleLocalBlockChainServer(address string) *LocalBlockChainServer {
 			transport,
 			func() prot.Miner { return prot.NewSimpleMiner(bc, txPool, privKey) },
 			func() prot.Comsumer { return prot.NewSimpleConsumer(bc, txPool, transport) },
-			func() prot.Validator { return prot.NewSimpleValidator(bc, privKey) },
+			func() prot.Validator { return prot.NewSimpleValidator(bc, prot.CoinbaseProducer{}) },
 		),
 		TransportInterface: transport,
 	}
@@ -206,6 +206,46 @@ func createLocalServer(t *testing.T, numTx, blockSz int, pref, addr string) *Loc
 		transport,
 		func() prot.Miner { return prot.NewSimpleMiner(bc, txPool, privKey) },
 		func() prot.Comsumer { return prot.NewSimpleConsumer(bc, txPool, transport) },
-		func() prot.Validator { return prot.NewSimpleValidator(bc, privKey) },
+		func() prot.Validator { return prot.NewSimpleValidator(bc, prot.CoinbaseProducer{}) },
 	)
 }
+
+func TestAutoMine(t *testing.T) {
+	server := NewSimpleLocalBlockChainServer("A")
+	server.Listen()
+	defer server.Kill()
+
+	assert.NotNil(t, server.StopAutoMine())
+	assert.Nil(t, server.StartAutoMine(AutoMineConfig{MinTransactions: 2}))
+	assert.NotNil(t, server.StartAutoMine(AutoMineConfig{}))
+
+	status := server.AutoMineStatus()
+	assert.True(t, status.Running)
+	assert.Equal(t, uint32(DefaultAutoMineTransactionsLimit), status.Config.TransactionsLimit)
+
+	// Nothing is mined until enough transactions are pooled.
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, uint32(0), server.blockChain.Height())
+
+	for i := 0; i < 2; i++ {
+		tx := core.NewTransaction([]byte(fmt.Sprintf("A: %d", i)))
+		assert.Nil(t, tx.Sign(server.privKey))
+		assert.Nil(t, server.AddTransaction(tx))
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for server.AutoMineStatus().BlocksMined == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assert.Nil(t, server.StopAutoMine())
+	status = server.AutoMineStatus()
+	assert.False(t, status.Running)
+	assert.Equal(t, uint64(1), status.BlocksMined)
+	assert.Nil(t, status.LastError)
+
+	tipHash, err := server.blockChain.GetHeighestBlock().Hash()
+	assert.Nil(t, err)
+	assert.Equal(t, tipHash, status.LastBlock)
+	assert.Equal(t, uint32(status.BlocksMined), server.blockChain.Height())
+}
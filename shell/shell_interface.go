@@ -2,12 +2,23 @@ package shell
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/tusharjoshi4531/block-chain.git/anchor"
+	"github.com/tusharjoshi4531/block-chain.git/core"
+	"github.com/tusharjoshi4531/block-chain.git/crypto"
 	"github.com/tusharjoshi4531/block-chain.git/currency"
+	"github.com/tusharjoshi4531/block-chain.git/server"
 	"github.com/tusharjoshi4531/block-chain.git/tcp"
 )
 
@@ -16,8 +27,12 @@ const (
 	WALLETS    = "wallets"
 	TRANSACT   = "transact"
 	MINE       = "mine"
+	AUTOMINE   = "automine"
 	BALANCE    = "balance"
 	RUN        = "run"
+	MULTISIG   = "multisig"
+	ASSET      = "asset"
+	ANCHOR     = "anchor"
 )
 
 type ShellInterface struct {
@@ -31,6 +46,10 @@ func NewShellInterface(server *tcp.TCPServer) *ShellInterface {
 func (sh *ShellInterface) Run() {
 	sh.server.Listen()
 
+	tipEvents := sh.server.BlockChain.Subscribe(16, core.EventTipChanged, core.EventReorgRejected)
+	defer tipEvents.Unsubscribe()
+	go sh.printChainEvents(tipEvents)
+
 	scanner := bufio.NewScanner(os.Stdin)
 	for {
 		fmt.Printf(">>> ")
@@ -49,6 +68,22 @@ func (sh *ShellInterface) Run() {
 	}
 }
 
+func (sh *ShellInterface) printChainEvents(sub *core.Subscription) {
+	for event := range sub.Events() {
+		hash, err := event.Block.Hash()
+		if err != nil {
+			continue
+		}
+
+		switch event.Type {
+		case core.EventTipChanged:
+			fmt.Printf("\n\tNew tip (%s) at height (%d)\n>>> ", hash.String(), event.Block.Header.Height)
+		case core.EventReorgRejected:
+			fmt.Printf("\n\tRejected block (%s); reorg depth (%d) too large\n>>> ", hash.String(), event.ReorgDepth)
+		}
+	}
+}
+
 func (sh *ShellInterface) processCommand(cmd string, args []string) (string, error) {
 	switch cmd {
 	case ADD_WALLET:
@@ -69,13 +104,33 @@ func (sh *ShellInterface) processCommand(cmd string, args []string) (string, err
 		if err != nil {
 			return "", fmt.Errorf("ERROR: %s\n", err.Error())
 		}
+		fee := float64(0)
+		if len(args) > 3 {
+			if fee, err = strconv.ParseFloat(args[3], 64); err != nil {
+				return "", fmt.Errorf("ERROR: %s\n", err.Error())
+			}
+		}
 
-		return sh.processTransact(from, to, amt)
+		return sh.processTransact(from, to, amt, fee)
 	case MINE:
 		if len(args) < 1 {
 			return "", fmt.Errorf("ERROR: incomplete args\n")
 		}
-		return sh.processMine(args[0])
+
+		timeout := time.Duration(0)
+		if len(args) > 1 {
+			seconds, err := strconv.ParseFloat(args[1], 64)
+			if err != nil {
+				return "", fmt.Errorf("ERROR: %s\n", err.Error())
+			}
+			timeout = time.Duration(seconds * float64(time.Second))
+		}
+		return sh.processMine(args[0], timeout)
+	case AUTOMINE:
+		if len(args) < 1 {
+			return "", fmt.Errorf("ERROR: incomplete args\n")
+		}
+		return sh.processAutoMine(args[0], args[1:])
 	case BALANCE:
 		if len(args) < 1 {
 			return "", fmt.Errorf("ERROR: incomplet arguments")
@@ -87,6 +142,21 @@ func (sh *ShellInterface) processCommand(cmd string, args []string) (string, err
 			return "", fmt.Errorf("ERROR: incomplete args\n")
 		}
 		return sh.processRunScript(args[0])
+	case MULTISIG:
+		if len(args) < 1 {
+			return "", fmt.Errorf("ERROR: incomplete args\n")
+		}
+		return sh.processMultisig(args[0], args[1:])
+	case ASSET:
+		if len(args) < 1 {
+			return "", fmt.Errorf("ERROR: incomplete args\n")
+		}
+		return sh.processAsset(args[0], args[1:])
+	case ANCHOR:
+		if len(args) < 1 {
+			return "", fmt.Errorf("ERROR: incomplete args\n")
+		}
+		return sh.processAnchor(args[0], strings.Join(args[1:], " "))
 	default:
 		return "", fmt.Errorf("ERROR: invalid command (%s)\n", cmd)
 	}
@@ -96,17 +166,22 @@ func (sh *ShellInterface) processAddWallet(walletId string) (string, error) {
 	if err := sh.server.AddWallet(walletId); err != nil {
 		return "", fmt.Errorf("ERROR: %s\n", err.Error())
 	} else {
-		return fmt.Sprintf("Added wallet (%s) to block chain\n", walletId), nil
+		return fmt.Sprintf("Registration of wallet (%s) added; it is created once mined\n", walletId), nil
 	}
 }
 
 func (sh *ShellInterface) processWallets() string {
+	lock := sh.server.ChainLock()
+	lock.Lock()
+	defer lock.Unlock()
+
 	wallets := sh.server.Ledger.GetWallets()
 	return fmt.Sprintf("Wallets: %v\n", wallets)
 }
 
-func (sh *ShellInterface) processTransact(from, to string, amt float64) (string, error) {
+func (sh *ShellInterface) processTransact(from, to string, amt, fee float64) (string, error) {
 	transaction := currency.NewTransaction(from, to, amt)
+	transaction.Fee = fee
 	tx, err := transaction.ToCoreTransaction()
 	if err != nil {
 		return "", fmt.Errorf("ERROR: %s\n", err.Error())
@@ -123,31 +198,328 @@ func (sh *ShellInterface) processTransact(from, to string, amt float64) (string,
 	return "Transaction Added\n", nil
 }
 
-func (sh *ShellInterface) processMine(minerWalletId string) (string, error) {
-	block, err := sh.server.MineBlock(10, minerWalletId)
+// processMine mines one block. A positive timeout gives up mining after
+// that long.
+func (sh *ShellInterface) processMine(minerWalletId string, timeout time.Duration) (string, error) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	if _, err := sh.server.MineAndAnnounceContext(ctx, 10, minerWalletId); err != nil {
+		return "", fmt.Errorf("ERROR: %s\n", err.Error())
+	}
+
+	if miner, ok := sh.server.Miner.(interface{ HashRate() float64 }); ok {
+		return fmt.Sprintf("New block created (%.0f H/s)\n", miner.HashRate()), nil
+	}
+	return "New block created\n", nil
+}
+
+// processAutoMine handles "automine start <wallet> [min transactions]
+// [interval seconds]", "automine stop" and "automine status".
+func (sh *ShellInterface) processAutoMine(action string, args []string) (string, error) {
+	switch action {
+	case "start":
+		if len(args) < 1 {
+			return "", fmt.Errorf("ERROR: incomplete args\n")
+		}
+
+		config := server.AutoMineConfig{MinerWalletId: args[0]}
+		if len(args) > 1 {
+			minTransactions, err := strconv.Atoi(args[1])
+			if err != nil {
+				return "", fmt.Errorf("ERROR: %s\n", err.Error())
+			}
+			config.MinTransactions = minTransactions
+		}
+		if len(args) > 2 {
+			seconds, err := strconv.ParseFloat(args[2], 64)
+			if err != nil {
+				return "", fmt.Errorf("ERROR: %s\n", err.Error())
+			}
+			config.Interval = time.Duration(seconds * float64(time.Second))
+		}
+
+		if err := sh.server.StartAutoMine(config); err != nil {
+			return "", fmt.Errorf("ERROR: %s\n", err.Error())
+		}
+		return "Auto mining started\n", nil
+	case "stop":
+		if err := sh.server.StopAutoMine(); err != nil {
+			return "", fmt.Errorf("ERROR: %s\n", err.Error())
+		}
+		return "Auto mining stopped\n", nil
+	case "status":
+		status := sh.server.AutoMineStatus()
+		if !status.Running {
+			return fmt.Sprintf("Auto mining stopped; blocks mined (%d)\n", status.BlocksMined), nil
+		}
+
+		msg := fmt.Sprintf(
+			"Auto mining for (%s); blocks mined (%d); last block (%s)",
+			status.Config.MinerWalletId,
+			status.BlocksMined,
+			status.LastBlock.String(),
+		)
+		if status.LastError != nil {
+			msg += fmt.Sprintf("; last error (%s)", status.LastError.Error())
+		}
+		return msg + "\n", nil
+	default:
+		return "", fmt.Errorf("ERROR: invalid automine action (%s)\n", action)
+	}
+}
+
+// processMultisig handles "multisig key", "multisig create <threshold>
+// <public key>...", "multisig propose <wallet> <to> <amount> [fee]",
+// "multisig sign <proposal>" and "multisig submit <proposal>". Wallets and
+// proposals are printed as hex so they can be passed between key holders
+// offline; each holder signs with the key of their own node.
+func (sh *ShellInterface) processMultisig(action string, args []string) (string, error) {
+	switch action {
+	case "key":
+		return fmt.Sprintf("Public key: %s\n", hex.EncodeToString(crypto.PublicKeyBytes(&sh.server.PrivKey.PublicKey))), nil
+	case "create":
+		if len(args) < 2 {
+			return "", fmt.Errorf("ERROR: incomplete args\n")
+		}
+		threshold, err := strconv.ParseUint(args[0], 10, 8)
+		if err != nil {
+			return "", fmt.Errorf("ERROR: %s\n", err.Error())
+		}
+
+		keys := make([]*ecdsa.PublicKey, 0, len(args)-1)
+		for _, keyHex := range args[1:] {
+			keyBytes, err := hex.DecodeString(keyHex)
+			if err != nil {
+				return "", fmt.Errorf("ERROR: %s\n", err.Error())
+			}
+			key, err := crypto.PublicKeyFromBytes(keyBytes)
+			if err != nil {
+				return "", fmt.Errorf("ERROR: %s\n", err.Error())
+			}
+			keys = append(keys, key)
+		}
+
+		wallet, err := currency.NewMultisigWallet(uint8(threshold), keys)
+		if err != nil {
+			return "", fmt.Errorf("ERROR: %s\n", err.Error())
+		}
+		if err := sh.server.AddWallet(wallet.Address()); err != nil {
+			return "", fmt.Errorf("ERROR: %s\n", err.Error())
+		}
+		return fmt.Sprintf(
+			"Registration of multisig wallet (%s) added\n\tWallet: %s\n",
+			wallet.Address(),
+			hex.EncodeToString(wallet.Bytes()),
+		), nil
+	case "propose":
+		if len(args) < 3 {
+			return "", fmt.Errorf("ERROR: incomplete args\n")
+		}
+		walletBytes, err := hex.DecodeString(args[0])
+		if err != nil {
+			return "", fmt.Errorf("ERROR: %s\n", err.Error())
+		}
+		wallet := &currency.MultisigWallet{}
+		if err := wallet.Decode(bytes.NewReader(walletBytes)); err != nil {
+			return "", fmt.Errorf("ERROR: %s\n", err.Error())
+		}
+		amt, err := strconv.ParseFloat(args[2], 64)
+		if err != nil {
+			return "", fmt.Errorf("ERROR: %s\n", err.Error())
+		}
+		fee := float64(0)
+		if len(args) > 3 {
+			if fee, err = strconv.ParseFloat(args[3], 64); err != nil {
+				return "", fmt.Errorf("ERROR: %s\n", err.Error())
+			}
+		}
+
+		proposal := currency.NewMultisigTransaction(wallet, args[1], amt, fee, uint64(time.Now().UnixNano()))
+		return sh.printMultisigProposal(proposal)
+	case "sign":
+		if len(args) < 1 {
+			return "", fmt.Errorf("ERROR: incomplete args\n")
+		}
+		proposal, err := decodeMultisigProposal(args[0])
+		if err != nil {
+			return "", err
+		}
+		if err := proposal.Approve(sh.server.PrivKey); err != nil {
+			return "", fmt.Errorf("ERROR: %s\n", err.Error())
+		}
+		return sh.printMultisigProposal(proposal)
+	case "submit":
+		if len(args) < 1 {
+			return "", fmt.Errorf("ERROR: incomplete args\n")
+		}
+		proposal, err := decodeMultisigProposal(args[0])
+		if err != nil {
+			return "", err
+		}
+		tx, err := proposal.ToCoreTransaction()
+		if err != nil {
+			return "", fmt.Errorf("ERROR: %s\n", err.Error())
+		}
+		if err := tx.Sign(sh.server.PrivKey); err != nil {
+			return "", fmt.Errorf("ERROR: %s\n", err.Error())
+		}
+		tx.SetFirstSeen(time.Now().UnixNano())
+		if err := sh.server.AddTransaction(tx); err != nil {
+			return "", fmt.Errorf("ERROR: %s\n", err.Error())
+		}
+		return "Multisig transaction Added\n", nil
+	default:
+		return "", fmt.Errorf("ERROR: invalid multisig action (%s)\n", action)
+	}
+}
+
+// processAsset handles "asset issue <wallet> <asset> <supply>", "asset
+// transfer <from> <to> <asset> <amount> [fee]" and "asset list [wallet]".
+func (sh *ShellInterface) processAsset(action string, args []string) (string, error) {
+	switch action {
+	case "issue":
+		if len(args) < 3 {
+			return "", fmt.Errorf("ERROR: incomplete args\n")
+		}
+		supply, err := strconv.ParseFloat(args[2], 64)
+		if err != nil {
+			return "", fmt.Errorf("ERROR: %s\n", err.Error())
+		}
+		if err := sh.addTransaction(currency.NewIssueAssetTransaction(args[0], args[1], supply)); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Issue of asset (%s) added; it is created once mined\n", args[1]), nil
+	case "transfer":
+		if len(args) < 4 {
+			return "", fmt.Errorf("ERROR: incomplete args\n")
+		}
+		amt, err := strconv.ParseFloat(args[3], 64)
+		if err != nil {
+			return "", fmt.Errorf("ERROR: %s\n", err.Error())
+		}
+		transaction := currency.NewAssetTransaction(args[0], args[1], args[2], amt)
+		if len(args) > 4 {
+			if transaction.Fee, err = strconv.ParseFloat(args[4], 64); err != nil {
+				return "", fmt.Errorf("ERROR: %s\n", err.Error())
+			}
+		}
+		if err := sh.addTransaction(transaction); err != nil {
+			return "", err
+		}
+		return "Transaction Added\n", nil
+	case "list":
+		lock := sh.server.ChainLock()
+		lock.Lock()
+		defer lock.Unlock()
+
+		if len(args) < 1 {
+			msg := "Assets:\n"
+			for _, asset := range sh.server.Ledger.GetAssets() {
+				msg += fmt.Sprintf("\t%s issued by (%s) : %f\n", asset.Id, asset.Issuer, asset.Supply)
+			}
+			return msg, nil
+		}
+		if !sh.server.Ledger.HasWallet(args[0]) {
+			return "", fmt.Errorf("ERROR: member with id (%s) is not present in the ledger\n", args[0])
+		}
+		balances := sh.server.Ledger.GetAssetBalances(args[0])
+		assets := make([]string, 0, len(balances))
+		for asset := range balances {
+			assets = append(assets, asset)
+		}
+		sort.Strings(assets)
+		msg := fmt.Sprintf("Assets of wallet (%s):\n", args[0])
+		for _, asset := range assets {
+			msg += fmt.Sprintf("\t%s : %f\n", asset, balances[asset])
+		}
+		return msg, nil
+	default:
+		return "", fmt.Errorf("ERROR: invalid asset action (%s)\n", action)
+	}
+}
+
+// addTransaction signs transaction with the node key and adds it to the
+// pool.
+func (sh *ShellInterface) addTransaction(transaction *currency.Transaction) error {
+	tx, err := transaction.ToCoreTransaction()
+	if err != nil {
+		return fmt.Errorf("ERROR: %s\n", err.Error())
+	}
+	if err := tx.Sign(sh.server.PrivKey); err != nil {
+		return fmt.Errorf("ERROR: %s\n", err.Error())
+	}
+	tx.SetFirstSeen(time.Now().UnixNano())
+	if err := sh.server.AddTransaction(tx); err != nil {
+		return fmt.Errorf("ERROR: %s\n", err.Error())
+	}
+	return nil
+}
+
+func (sh *ShellInterface) printMultisigProposal(proposal *currency.MultisigTransaction) (string, error) {
+	data, err := proposal.Bytes()
 	if err != nil {
 		return "", fmt.Errorf("ERROR: %s\n", err.Error())
 	}
+	return fmt.Sprintf(
+		"Proposal with (%d) of (%d) signatures\n\t%s\n",
+		len(proposal.Signatures),
+		proposal.Wallet.Threshold,
+		hex.EncodeToString(data),
+	), nil
+}
+
+func decodeMultisigProposal(proposalHex string) (*currency.MultisigTransaction, error) {
+	data, err := hex.DecodeString(proposalHex)
+	if err != nil {
+		return nil, fmt.Errorf("ERROR: %s\n", err.Error())
+	}
+	proposal, err := currency.DecodeMultisigTransaction(data)
+	if err != nil {
+		return nil, fmt.Errorf("ERROR: %s\n", err.Error())
+	}
+	return proposal, nil
+}
 
-	err = block.Sign(sh.server.PrivKey)
+// processAnchor hashes the file and submits an anchor of the hash signed by
+// the node key. The metadata defaults to the file name.
+func (sh *ShellInterface) processAnchor(filePath string, metadata string) (string, error) {
+	file, err := os.Open(filePath)
 	if err != nil {
 		return "", fmt.Errorf("ERROR: %s\n", err.Error())
 	}
+	defer file.Close()
 
-	err = sh.server.BlockChain.AddBlock(block)
+	hash, err := anchor.HashDocument(file)
 	if err != nil {
 		return "", fmt.Errorf("ERROR: %s\n", err.Error())
 	}
+	if metadata == "" {
+		metadata = filepath.Base(filePath)
+	}
 
-	err = sh.server.BroadcastHashChain()
+	tx, err := anchor.NewAnchorTransaction(hash, metadata, sh.server.PrivKey)
 	if err != nil {
 		return "", fmt.Errorf("ERROR: %s\n", err.Error())
 	}
+	tx.SetFirstSeen(time.Now().UnixNano())
+	if err := sh.server.AddTransaction(tx); err != nil {
+		return "", fmt.Errorf("ERROR: %s\n", err.Error())
+	}
 
-	return "New block created\n", nil
+	txHash := tx.Hash()
+	return fmt.Sprintf("Anchor of document (%s) added\n\tTransaction: %s\n", hash.String(), txHash.String()), nil
 }
 
 func (sh *ShellInterface) processBalance(walletId string) (string, error) {
+	lock := sh.server.ChainLock()
+	lock.Lock()
+	defer lock.Unlock()
+
 	balance, err := sh.server.Ledger.GetBalance(walletId)
 	if err != nil {
 		return "", fmt.Errorf("ERROR: %s\n", err.Error())
@@ -7,8 +7,10 @@ import (
 	"io"
 	"log"
 	"net"
+	"time"
 
 	bcnetwork "github.com/tusharjoshi4531/block-chain.git/bc_network"
+	"github.com/tusharjoshi4531/block-chain.git/chainspec"
 	"github.com/tusharjoshi4531/block-chain.git/core"
 	"github.com/tusharjoshi4531/block-chain.git/currency"
 	"github.com/tusharjoshi4531/block-chain.git/network"
@@ -32,6 +34,29 @@ func NewTcpServer(
 	privKey *ecdsa.PrivateKey,
 	bcTransport bcnetwork.BlockChainTransport,
 ) *TCPServer {
+	spec := chainspec.Default()
+	engine := pow.NewPowEngine(
+		spec.Consensus.Difficulty,
+		bc,
+		txPool,
+		privKey,
+		spec.NewRewarder(privKey),
+	)
+	return NewTcpServerWithEngine(ledger, bc, txPool, privKey, bcTransport, engine)
+}
+
+// NewTcpServerWithEngine builds a server that mines and validates blocks
+// with the given consensus engine.
+func NewTcpServerWithEngine(
+	ledger currency.LedgerState,
+	bc core.BlockChain,
+	txPool core.TransactionPool,
+	privKey *ecdsa.PrivateKey,
+	bcTransport bcnetwork.BlockChainTransport,
+	engine prot.Engine,
+) *TCPServer {
+	bcTransport.SetBlockVerifier(engine.ValidateBlock)
+
 	return &TCPServer{
 		TxPool:     txPool,
 		BlockChain: bc,
@@ -42,12 +67,7 @@ func NewTcpServer(
 			privKey,
 			bcTransport,
 			func() prot.Miner {
-				return pow.NewPowMiner(
-					1,
-					bc,
-					txPool, privKey,
-					currency.NewRewarder(privKey, bc, 100, 10),
-				)
+				return engine
 			},
 			func() prot.Comsumer {
 				return prot.NewSimpleConsumer(
@@ -57,16 +77,41 @@ func NewTcpServer(
 				)
 			},
 			func() prot.Validator {
-				return prot.NewSimpleValidator(
-					bc,
-					privKey,
-				)
+				return engine
 			},
 		),
 		Ledger: ledger,
 	}
 }
 
+// AddWallet pools and broadcasts a registration transaction for walletId
+// with the chain's initial balance, signed with the server key. The wallet
+// exists once the transaction is mined. It holds the chain lock, so callers
+// must not.
+func (server *TCPServer) AddWallet(walletId string) error {
+	lock := server.ChainLock()
+	lock.Lock()
+	defer lock.Unlock()
+
+	if server.Ledger.HasWallet(walletId) {
+		return fmt.Errorf("member with id (%s) is already present in ledger", walletId)
+	}
+
+	balance := float64(0)
+	if ledgerChain, ok := server.BlockChain.(*currency.BlockChain); ok {
+		balance = ledgerChain.InitialBalance()
+	}
+	tx, err := currency.NewRegistrationTransaction(walletId, balance).ToCoreTransaction()
+	if err != nil {
+		return err
+	}
+	if err := tx.Sign(server.PrivKey); err != nil {
+		return err
+	}
+	tx.SetFirstSeen(time.Now().UnixNano())
+	return server.AddTransaction(tx)
+}
+
 func (server *TCPServer) Listen() {
 	listener, err := net.Listen("tcp", server.Address())
 	if err != nil {
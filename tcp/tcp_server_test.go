@@ -2,6 +2,7 @@ package tcp
 
 import (
 	"bytes"
+	"crypto/ecdsa"
 	"fmt"
 	"strconv"
 	"testing"
@@ -12,13 +13,15 @@ import (
 	"github.com/tusharjoshi4531/block-chain.git/crypto"
 	"github.com/tusharjoshi4531/block-chain.git/currency"
 	"github.com/tusharjoshi4531/block-chain.git/network"
-	"github.com/tusharjoshi4531/block-chain.git/pow"
+	"github.com/tusharjoshi4531/block-chain.git/poa"
 	"github.com/tusharjoshi4531/block-chain.git/util"
 )
 
 func TestEncodeBlocksWithNonce(t *testing.T) {
 	block1 := core.NewBlock()
-	block1.SetNonce(pow.NewPowNonce(5))
+	seal := core.NewSeal(5)
+	seal.Extra[0] = 1
+	block1.SetSeal(seal)
 
 	block2 := core.NewBlock()
 
@@ -82,6 +85,44 @@ func TestEncodeBlockFromServer(t *testing.T) {
 	assert.Nil(t, server.SendBlocks("B", extBlocks))
 }
 
+func TestPoaServer(t *testing.T) {
+	ledger := currency.NewMemoryLedgerState()
+	bc := currency.NewBlockChain(ledger, 1000)
+	txPool := core.NewDefaultTransactionPool()
+	privKey := crypto.GeneratePrivateKey()
+	outsiderKey := crypto.GeneratePrivateKey()
+	authorities := []*ecdsa.PublicKey{&privKey.PublicKey}
+
+	server := NewTcpServerWithEngine(
+		ledger,
+		bc,
+		txPool,
+		privKey,
+		bcnetwork.NewDefaultBlockChainTransport(network.NewDefaultTransport("poa"), bc, txPool),
+		poa.NewPoaEngine(authorities, bc, txPool, privKey, currency.NewRewarder(privKey, 100, 10)),
+	)
+	assert.Nil(t, server.AddWallet("A"))
+	assert.False(t, ledger.HasWallet("A"))
+
+	// The first block registers A; later blocks pay A's rewards
+	_, err := server.MineAndAnnounce(3, "M")
+	assert.Nil(t, err)
+	for i := 0; i < 2; i++ {
+		_, err := server.MineAndAnnounce(3, "A")
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, uint32(3), bc.Height())
+
+	balance, err := ledger.GetBalance("A")
+	assert.Nil(t, err)
+	assert.Equal(t, 1200.0, balance)
+	assert.NotNil(t, server.AddWallet("A"))
+
+	outsider := poa.NewPoaEngine(authorities, bc, txPool, outsiderKey, currency.NewRewarder(outsiderKey, 100, 10))
+	_, err = outsider.MineBlock(3, "A")
+	assert.NotNil(t, err)
+}
+
 func extendBlockChain(t *testing.T, bc core.BlockChain, pref string, numTx, numBlocks int) {
 	privKey := crypto.GeneratePrivateKey()
 	blockSz := numTx / numBlocks
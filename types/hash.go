@@ -1,9 +1,25 @@
 package types
 
-import "encoding/hex"
+import (
+	"encoding/hex"
+	"fmt"
+)
 
 type Hash [32]byte
 
+func HashFromString(str string) (Hash, error) {
+	hash := Hash{}
+	bytes, err := hex.DecodeString(str)
+	if err != nil {
+		return hash, err
+	}
+	if len(bytes) != len(hash) {
+		return hash, fmt.Errorf("hash (%s) must be (%d) bytes long", str, len(hash))
+	}
+	copy(hash[:], bytes)
+	return hash, nil
+}
+
 func (h *Hash) IsZero() bool {
 	for i := 0; i < 32; i++ {
 		if h[i] != 0 {
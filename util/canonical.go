@@ -0,0 +1,173 @@
+package util
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// MaxCanonicalBytesLength bounds length prefixed fields read from the wire.
+const MaxCanonicalBytesLength = 1 << 24
+
+// CanonicalWriter writes big-endian fixed width integers and uint32 length
+// prefixed byte strings. The first error is kept and later writes are no-ops.
+type CanonicalWriter struct {
+	w   io.Writer
+	err error
+}
+
+func NewCanonicalWriter(w io.Writer) *CanonicalWriter {
+	return &CanonicalWriter{w: w}
+}
+
+func (cw *CanonicalWriter) Err() error {
+	return cw.err
+}
+
+func (cw *CanonicalWriter) WriteFixed(data []byte) {
+	if cw.err != nil {
+		return
+	}
+	_, cw.err = cw.w.Write(data)
+}
+
+func (cw *CanonicalWriter) WriteUint8(v uint8) {
+	cw.WriteFixed([]byte{v})
+}
+
+func (cw *CanonicalWriter) WriteBool(v bool) {
+	if v {
+		cw.WriteUint8(1)
+	} else {
+		cw.WriteUint8(0)
+	}
+}
+
+func (cw *CanonicalWriter) WriteUint16(v uint16) {
+	cw.WriteFixed(binary.BigEndian.AppendUint16(nil, v))
+}
+
+func (cw *CanonicalWriter) WriteUint32(v uint32) {
+	cw.WriteFixed(binary.BigEndian.AppendUint32(nil, v))
+}
+
+func (cw *CanonicalWriter) WriteUint64(v uint64) {
+	cw.WriteFixed(binary.BigEndian.AppendUint64(nil, v))
+}
+
+func (cw *CanonicalWriter) WriteInt64(v int64) {
+	cw.WriteUint64(uint64(v))
+}
+
+func (cw *CanonicalWriter) WriteFloat64(v float64) {
+	cw.WriteUint64(math.Float64bits(v))
+}
+
+func (cw *CanonicalWriter) WriteBytes(data []byte) {
+	cw.WriteUint32(uint32(len(data)))
+	cw.WriteFixed(data)
+}
+
+func (cw *CanonicalWriter) WriteString(s string) {
+	cw.WriteBytes([]byte(s))
+}
+
+type CanonicalReader struct {
+	r   io.Reader
+	err error
+}
+
+func NewCanonicalReader(r io.Reader) *CanonicalReader {
+	return &CanonicalReader{r: r}
+}
+
+func (cr *CanonicalReader) Err() error {
+	return cr.err
+}
+
+func (cr *CanonicalReader) ReadFixed(data []byte) {
+	if cr.err != nil {
+		return
+	}
+	_, cr.err = io.ReadFull(cr.r, data)
+}
+
+func (cr *CanonicalReader) ReadUint8() uint8 {
+	buf := make([]byte, 1)
+	cr.ReadFixed(buf)
+	return buf[0]
+}
+
+func (cr *CanonicalReader) ReadBool() bool {
+	v := cr.ReadUint8()
+	if v > 1 && cr.err == nil {
+		cr.err = fmt.Errorf("invalid boolean value (%d)", v)
+	}
+	return v == 1
+}
+
+func (cr *CanonicalReader) ReadUint16() uint16 {
+	buf := make([]byte, 2)
+	cr.ReadFixed(buf)
+	return binary.BigEndian.Uint16(buf)
+}
+
+func (cr *CanonicalReader) ReadUint32() uint32 {
+	buf := make([]byte, 4)
+	cr.ReadFixed(buf)
+	return binary.BigEndian.Uint32(buf)
+}
+
+func (cr *CanonicalReader) ReadUint64() uint64 {
+	buf := make([]byte, 8)
+	cr.ReadFixed(buf)
+	return binary.BigEndian.Uint64(buf)
+}
+
+func (cr *CanonicalReader) ReadInt64() int64 {
+	return int64(cr.ReadUint64())
+}
+
+func (cr *CanonicalReader) ReadFloat64() float64 {
+	return math.Float64frombits(cr.ReadUint64())
+}
+
+func (cr *CanonicalReader) ReadBytes() []byte {
+	length := cr.ReadUint32()
+	if cr.err != nil {
+		return nil
+	}
+	if length > MaxCanonicalBytesLength {
+		cr.err = fmt.Errorf("length (%d) exceeds maximum (%d)", length, MaxCanonicalBytesLength)
+		return nil
+	}
+
+	data := make([]byte, length)
+	cr.ReadFixed(data)
+	return data
+}
+
+func (cr *CanonicalReader) ReadString() string {
+	return string(cr.ReadBytes())
+}
+
+// ReadEnd fails unless the reader is exhausted, so that an encoding followed
+// by extra bytes is rejected and every value has a single encoding.
+func (cr *CanonicalReader) ReadEnd() {
+	if cr.err != nil {
+		return
+	}
+	buf := make([]byte, 1)
+	if _, err := io.ReadFull(cr.r, buf); err != io.EOF {
+		cr.err = fmt.Errorf("unexpected bytes after the end of the encoding")
+	}
+}
+
+// ReadVersion reads a version byte and fails unless it matches expected.
+func (cr *CanonicalReader) ReadVersion(expected uint8) {
+	version := cr.ReadUint8()
+	if cr.err == nil && version != expected {
+		cr.err = fmt.Errorf("unsupported encoding version (%d); expected (%d)", version, expected)
+	}
+}
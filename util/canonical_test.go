@@ -0,0 +1,61 @@
+package util
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalGoldenVector(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cw := NewCanonicalWriter(buf)
+	cw.WriteUint8(1)
+	cw.WriteBool(true)
+	cw.WriteUint16(0x0203)
+	cw.WriteUint32(0x04050607)
+	cw.WriteUint64(0x08090a0b0c0d0e0f)
+	cw.WriteInt64(-1)
+	cw.WriteFloat64(1.5)
+	cw.WriteString("abc")
+	assert.Nil(t, cw.Err())
+
+	expected := "01" + "01" + "0203" + "04050607" + "08090a0b0c0d0e0f" +
+		"ffffffffffffffff" + "3ff8000000000000" + "00000003616263"
+	assert.Equal(t, expected, hex.EncodeToString(buf.Bytes()))
+
+	cr := NewCanonicalReader(buf)
+	cr.ReadVersion(1)
+	assert.True(t, cr.ReadBool())
+	assert.Equal(t, uint16(0x0203), cr.ReadUint16())
+	assert.Equal(t, uint32(0x04050607), cr.ReadUint32())
+	assert.Equal(t, uint64(0x08090a0b0c0d0e0f), cr.ReadUint64())
+	assert.Equal(t, int64(-1), cr.ReadInt64())
+	assert.Equal(t, 1.5, cr.ReadFloat64())
+	assert.Equal(t, "abc", cr.ReadString())
+	assert.Nil(t, cr.Err())
+}
+
+func TestCanonicalReaderErrors(t *testing.T) {
+	cr := NewCanonicalReader(bytes.NewBuffer([]byte{2}))
+	cr.ReadVersion(1)
+	assert.NotNil(t, cr.Err())
+
+	cr = NewCanonicalReader(bytes.NewBuffer([]byte{0xff, 0xff, 0xff, 0xff}))
+	assert.Nil(t, cr.ReadBytes())
+	assert.NotNil(t, cr.Err())
+
+	cr = NewCanonicalReader(bytes.NewBuffer([]byte{0, 0, 0, 4, 1}))
+	cr.ReadBytes()
+	assert.NotNil(t, cr.Err())
+
+	cr = NewCanonicalReader(bytes.NewBuffer([]byte{1, 2}))
+	cr.ReadUint8()
+	cr.ReadEnd()
+	assert.NotNil(t, cr.Err())
+	cr = NewCanonicalReader(bytes.NewBuffer([]byte{1}))
+	cr.ReadUint8()
+	cr.ReadEnd()
+	assert.Nil(t, cr.Err())
+}
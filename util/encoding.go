@@ -54,10 +54,22 @@ func EncodeToBytes(item Encoder) ([]byte, error) {
 	return EncodeToBytesUsingEncoder(item.Encode)
 }
 
+// DecodeFromBytes decodes item from data and fails if any bytes are left
+// over.
+func DecodeFromBytes(data []byte, item Decoder) error {
+	r := bytes.NewReader(data)
+	if err := item.Decode(r); err != nil {
+		return err
+	}
+	cr := NewCanonicalReader(r)
+	cr.ReadEnd()
+	return cr.Err()
+}
+
 func EncodeSlice(w io.Writer, items []Encoder) error {
-	len := len(items)
-	enc := gob.NewEncoder(w)
-	if err := enc.Encode(int(len)); err != nil {
+	cw := NewCanonicalWriter(w)
+	cw.WriteUint32(uint32(len(items)))
+	if err := cw.Err(); err != nil {
 		return err
 	}
 
@@ -79,13 +91,14 @@ func EncodeSliceToBytes(items []Encoder) ([]byte, error) {
 }
 
 func DecodeSlice[T Decoder](r io.Reader, factory func() T) ([]T, error) {
-	var length int
-	if err := gob.NewDecoder(r).Decode(&length); err != nil {
+	cr := NewCanonicalReader(r)
+	length := cr.ReadUint32()
+	if err := cr.Err(); err != nil {
 		return nil, err
 	}
 
-	items := make([]T, 0, length)
-	for i := 0; i < length; i++ {
+	items := make([]T, 0, min(length, 1024))
+	for i := uint32(0); i < length; i++ {
 		item := factory()
 		if err := item.Decode(r); err != nil {
 			return nil, err